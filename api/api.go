@@ -0,0 +1,12 @@
+// Package api embeds the contracts of the HTTP API: the OpenAPI spec, so that
+// requests can be checked against the same document the types are generated
+// from, and the GraphQL schema the resolvers are bound to.
+package api
+
+import _ "embed"
+
+//go:embed openapi.yaml
+var Spec []byte
+
+//go:embed schema.graphql
+var GraphQLSchema string
@@ -0,0 +1,17 @@
+// Package schemas embeds the published contracts of the Kafka event payloads.
+// Every change must stay backward compatible; the compatibility checks in
+// internal/infra/schemaregistry fail the build otherwise.
+package schemas
+
+import "embed"
+
+//go:embed avro/*.avsc proto/*.proto published/avro/*.avsc published/proto/*.proto
+var FS embed.FS
+
+// ProtoFile is the path of the protobuf schema inside FS.
+const ProtoFile = "proto/company_events.proto"
+
+// PublishedDir holds a copy of every schema as last registered for
+// production, at the same path below it. The current schemas are checked
+// against these; copy them over once a new version is registered.
+const PublishedDir = "published"
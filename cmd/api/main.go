@@ -5,20 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/dubininme/xm-assessment/api"
 	"github.com/dubininme/xm-assessment/internal/config"
+	"github.com/dubininme/xm-assessment/internal/delivery/graphql"
 	deliveryHttp "github.com/dubininme/xm-assessment/internal/delivery/http"
 	"github.com/dubininme/xm-assessment/internal/delivery/http/handler"
 	"github.com/dubininme/xm-assessment/internal/delivery/http/middleware"
 	"github.com/dubininme/xm-assessment/internal/domain/company"
-	"github.com/dubininme/xm-assessment/internal/domain/events"
+	"github.com/dubininme/xm-assessment/internal/domain/webhook"
 	"github.com/dubininme/xm-assessment/internal/infra/auth"
-	"github.com/dubininme/xm-assessment/internal/infra/kafka"
-	"github.com/dubininme/xm-assessment/internal/infra/outbox"
-	"github.com/dubininme/xm-assessment/internal/infra/postgres"
 	"github.com/dubininme/xm-assessment/pkg/logger"
 )
 
@@ -38,43 +38,65 @@ func main() {
 	// Add logger to context
 	ctx = logger.WithLogger(ctx, log)
 
-	db, err := postgres.Connect(ctx, cfg.Db)
-	if err != nil {
-		log.Error("failed to connect to database", "error", err)
-		panic(err)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(ctx, cfg.Db, os.Args[2:]); err != nil {
+			log.Error("migrate failed", "error", err)
+			os.Exit(1)
+		}
+		return
 	}
-	defer func() { _ = db.Close() }()
 
-	companyRepo := postgres.NewCompanyRepo(db)
-	outboxRepo := postgres.NewOutboxRepo(db)
-	txManager := postgres.NewTxManager(db)
-	dbChecker := postgres.NewDBHealthChecker(db)
+	if len(os.Args) > 1 && os.Args[1] == "outbox" {
+		if err := runOutbox(ctx, cfg, os.Args[2:]); err != nil {
+			log.Error("outbox failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	kafkaProducer := kafka.NewProducer(cfg.Kafka.BrokersList(), cfg.Kafka.Topic)
-	defer func() { _ = kafkaProducer.Close() }()
+	if len(os.Args) > 1 && os.Args[1] == "schemas" {
+		if err := runSchemas(ctx, cfg.Kafka, os.Args[2:]); err != nil {
+			log.Error("schemas failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Reload *_FILE secrets on change so they can be rotated without a restart
+	go cfg.Db.DBPasswordSource.Watch(ctx, cfg.SecretsReloadInterval)
+	go cfg.JWTSecretSource.Watch(ctx, cfg.SecretsReloadInterval)
 
-	outboxProcessor := outbox.NewProcessor(
-		outboxRepo,
-		kafkaProducer,
-		txManager,
-		cfg.Outbox.BatchSize,
-		cfg.Outbox.Interval,
-		cfg.Outbox.PublishTimeout,
-	)
+	st, err := initStorage(ctx, cfg)
+	if err != nil {
+		log.Error("failed to initialize storage", "error", err)
+		panic(err)
+	}
+	defer st.Close()
 
 	processorErrCh := make(chan error, 1)
 	processorCtx, cancelProcessor := context.WithCancel(ctx)
 	defer cancelProcessor()
 
 	go func() {
+		defer close(processorErrCh)
+
+		if st.worker == nil {
+			<-processorCtx.Done()
+			return
+		}
+
 		log.Info("starting outbox processor")
-		if err := outboxProcessor.Start(processorCtx); err != nil && !errors.Is(err, context.Canceled) {
+		if err := st.worker(processorCtx); err != nil && !errors.Is(err, context.Canceled) {
 			processorErrCh <- fmt.Errorf("outbox processor failed: %w", err)
 		}
-		close(processorErrCh)
 	}()
 
-	httpHandler := initRouter(cfg, companyRepo, outboxRepo, txManager, dbChecker)
+	httpHandler, err := initRouter(ctx, cfg, st)
+	if err != nil {
+		log.Error("failed to initialize router", "error", err)
+		cancelProcessor()
+		panic(err)
+	}
 	srv := &http.Server{
 		Addr:              ":" + cfg.Port,
 		Handler:           httpHandler,
@@ -133,16 +155,47 @@ func main() {
 	}
 }
 
-func initRouter(cfg *config.AppConfig, companyRepo company.CompanyRepository, publisher events.EventsPublisher, txManager company.TxManager, dbChecker handler.HealthChecker) http.Handler {
+func initRouter(ctx context.Context, cfg *config.AppConfig, st *storage) (http.Handler, error) {
 
-	cService := company.NewCompanyService(companyRepo, publisher, txManager)
-	cHandler := handler.NewCompanyHandler(cService)
-	healthHandler := handler.NewHealthHandler(dbChecker)
+	cService := company.NewCompanyService(st.companyRepo, st.publisher, st.txManager)
+	cHandler := handler.NewCompanyHandler(cService, cfg.Cache.Control)
+	healthHandler := handler.NewHealthHandler(st.checkers...)
 
-	jwtService := auth.NewJWTService(cfg.JWTSecret)
+	var adminHandler *handler.AdminHandler
+	if st.outboxAdmin != nil {
+		adminHandler = handler.NewAdminHandler(st.outboxAdmin)
+	}
+
+	var webhookHandler *handler.WebhookHandler
+	if st.webhookRepo != nil {
+		webhookHandler = handler.NewWebhookHandler(webhook.NewService(st.webhookRepo, st.txManager, deliveryTargets(cfg.Sinks)))
+	}
+
+	var streamHandler *handler.StreamHandler
+	if st.eventStream != nil {
+		streamHandler = handler.NewStreamHandler(st.eventStream, cfg.Stream.Heartbeat, cfg.Stream.WriteTimeout)
+	}
+
+	transferHandler := handler.NewTransferHandler(ctx, cService, cfg.Import.MaxBytes, cfg.Import.SyncMaxBytes, cfg.Import.Timeout)
+
+	jwtService := auth.NewJWTService(cfg.JWTSecretSource.Value())
+	cfg.JWTSecretSource.OnChange(jwtService.SetSecret)
 	authHandler := handler.NewAuthHandler(jwtService)
 	authMiddleware := middleware.NewAuthMiddleware(jwtService)
 
-	router := deliveryHttp.NewRouter(cHandler, healthHandler, authHandler, authMiddleware)
-	return router
+	graphQLHandler, err := graphql.NewHandler(api.GraphQLSchema, cService, cfg.Validation.MaxBodyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	validator, err := middleware.NewValidator(api.Spec, middleware.ValidatorOptions{
+		MaxBodyBytes:      cfg.Validation.MaxBodyBytes,
+		ValidateResponses: cfg.Validation.Responses,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	router := deliveryHttp.NewRouter(cHandler, healthHandler, authHandler, adminHandler, webhookHandler, streamHandler, transferHandler, graphQLHandler, authMiddleware, validator)
+	return router, nil
 }
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/dubininme/xm-assessment/internal/config"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/pkg/logger"
+)
+
+const migrateUsage = "usage: migrate up | down [N|-all] | version | force VERSION"
+
+// runMigrate implements the `migrate` subcommand.
+func runMigrate(ctx context.Context, cfg config.DbConfig, args []string) (err error) {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	log := logger.FromContext(ctx)
+
+	migrator, err := postgres.NewMigrator(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, migrator.Close()) }()
+
+	switch args[0] {
+	case "up":
+		if err := migrator.Up(); err != nil {
+			return fmt.Errorf("migrate up: %w", err)
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if args[1] == "-all" {
+				steps = 0
+			} else if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return errors.New(migrateUsage)
+			}
+		}
+
+		if err := migrator.Down(steps); err != nil {
+			return fmt.Errorf("migrate down: %w", err)
+		}
+	case "force":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return errors.New(migrateUsage)
+		}
+
+		if err := migrator.Force(version); err != nil {
+			return fmt.Errorf("migrate force: %w", err)
+		}
+	case "version":
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		return fmt.Errorf("migrate version: %w", err)
+	}
+
+	log.Info("schema version", "version", version, "dirty", dirty)
+	return nil
+}
+
+// migrateOnStart applies pending migrations before the app starts serving.
+func migrateOnStart(ctx context.Context, cfg config.DbConfig) error {
+	migrator, err := postgres.NewMigrator(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = migrator.Close() }()
+
+	if err := migrator.Up(); err != nil {
+		return fmt.Errorf("migrate up: %w", err)
+	}
+
+	version, _, err := migrator.Version()
+	if err != nil {
+		return fmt.Errorf("migrate version: %w", err)
+	}
+
+	logger.FromContext(ctx).Info("database schema is up to date", "version", version)
+	return nil
+}
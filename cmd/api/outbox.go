@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/config"
+	"github.com/dubininme/xm-assessment/internal/infra/outbox"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/pkg/logger"
+)
+
+const outboxUsage = `usage: outbox replay [-aggregate-id ID] [-event-type TYPE] [-from RFC3339] [-to RFC3339]
+                     [-from-id N] [-to-id N] [-topic TOPIC] [-rate N] [-batch N]
+       outbox snapshot [-topic TOPIC] [-rate N] [-batch N]
+
+-from-id and -to-id are inclusive.`
+
+// runOutbox implements the `outbox` subcommand. It runs in the foreground and
+// logs progress after every batch.
+func runOutbox(ctx context.Context, cfg *config.AppConfig, args []string) error {
+	if len(args) == 0 || (args[0] != "replay" && args[0] != "snapshot") {
+		return errors.New(outboxUsage)
+	}
+
+	fs := flag.NewFlagSet("outbox "+args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var (
+		aggregateID = fs.String("aggregate-id", "", "company ID")
+		eventType   = fs.String("event-type", "", "event type, e.g. CompanyUpdated")
+		from        = fs.String("from", "", "earliest event creation time, RFC3339")
+		to          = fs.String("to", "", "latest event creation time, RFC3339")
+		fromID      = fs.Int64("from-id", 0, "lowest outbox ID, inclusive")
+		toID        = fs.Int64("to-id", 0, "highest outbox ID, inclusive")
+		topic       = fs.String("topic", "", "publish to this topic instead of KAFKA_TOPIC")
+		rate        = fs.Float64("rate", 0, "maximum messages per second, 0 for no limit")
+		batch       = fs.Int("batch", cfg.Outbox.BatchSize, "messages per batch")
+	)
+	if err := fs.Parse(args[1:]); err != nil || fs.NArg() > 0 || *rate < 0 || *batch < 1 {
+		return errors.New(outboxUsage)
+	}
+
+	filter := postgres.OutboxFilter{AggregateID: *aggregateID, EventType: *eventType, FromID: *fromID, ToID: *toID}
+	for _, bound := range []struct {
+		value string
+		dst   *int64
+	}{{*from, &filter.CreatedFrom}, {*to, &filter.CreatedTo}} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, bound.value)
+		if err != nil {
+			return fmt.Errorf("invalid time %q: %w", bound.value, err)
+		}
+		*bound.dst = t.Unix()
+	}
+
+	db, err := postgres.Connect(ctx, cfg.Db)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	replayer := outbox.NewReplayer(
+		postgres.NewOutboxRepo(db),
+		postgres.NewCompanyRepo(db),
+		newPublisherFactory(cfg.Kafka),
+		cfg.Kafka.Topic,
+		cfg.Outbox.BatchSize,
+	)
+
+	log := logger.FromContext(ctx)
+	onProgress := func(p outbox.Progress) {
+		log.Info("progress", "published", p.Published, "total", p.Total, "last_id", p.LastID)
+	}
+
+	var progress outbox.Progress
+	if args[0] == "replay" {
+		progress, err = replayer.Replay(ctx, outbox.ReplayOptions{
+			Filter:        filter,
+			Topic:         *topic,
+			RatePerSecond: *rate,
+			BatchSize:     *batch,
+		}, onProgress)
+	} else {
+		progress, err = replayer.Snapshot(ctx, outbox.SnapshotOptions{
+			Topic:         *topic,
+			RatePerSecond: *rate,
+			BatchSize:     *batch,
+		}, onProgress)
+	}
+	if err != nil {
+		return fmt.Errorf("%s stopped after %d of %d (last id %q%s): %w", args[0], progress.Published, progress.Total, progress.LastID, resumeHint(args[0], progress), err)
+	}
+	return nil
+}
+
+// resumeHint tells the operator where a stopped replay resumes. -from-id is
+// inclusive, so it is the ID after the last one published.
+func resumeHint(mode string, progress outbox.Progress) string {
+	if mode != "replay" {
+		return ""
+	}
+	lastID, err := strconv.ParseInt(progress.LastID, 10, 64)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf(", resume with -from-id %d", lastID+1)
+}
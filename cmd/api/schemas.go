@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/dubininme/xm-assessment/internal/config"
+	"github.com/dubininme/xm-assessment/internal/infra/schemaregistry"
+	"github.com/dubininme/xm-assessment/pkg/logger"
+)
+
+const schemasUsage = "usage: schemas check | register (uses KAFKA_VALUE_FORMAT and SCHEMA_REGISTRY_URL)"
+
+// newSerializer builds the Kafka value serializer for KAFKA_VALUE_FORMAT.
+func newSerializer(ctx context.Context, cfg config.KafkaConfig) (*schemaregistry.Serializer, error) {
+	format := schemaregistry.Format(cfg.ValueFormat)
+
+	var registry schemaregistry.Registry
+	if cfg.SchemaRegistryURL != "" {
+		registry = schemaregistry.NewClient(cfg.SchemaRegistryURL, nil)
+	}
+
+	return schemaregistry.NewSerializer(ctx, format, registry, cfg.Topic)
+}
+
+// runSchemas implements the `schemas` subcommand. check exits non-zero when a
+// schema in api/schemas is incompatible with the latest registered version,
+// which makes it usable as a CI gate; register publishes the schemas ahead
+// of a deploy.
+func runSchemas(ctx context.Context, cfg config.KafkaConfig, args []string) error {
+	if len(args) != 1 || (args[0] != "check" && args[0] != "register") {
+		return errors.New(schemasUsage)
+	}
+	if cfg.SchemaRegistryURL == "" {
+		return errors.New("SCHEMA_REGISTRY_URL is not set")
+	}
+
+	log := logger.FromContext(ctx)
+
+	serializer, err := newSerializer(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	subjects, err := serializer.Schemas()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(subjects))
+	for subject := range subjects {
+		names = append(names, subject)
+	}
+	sort.Strings(names)
+
+	registry := schemaregistry.NewClient(cfg.SchemaRegistryURL, nil)
+
+	var incompatible []string
+	for _, subject := range names {
+		if args[0] == "register" {
+			id, err := registry.Register(ctx, subject, subjects[subject])
+			if err != nil {
+				return err
+			}
+			log.Info("schema registered", "subject", subject, "id", id)
+			continue
+		}
+
+		ok, err := registry.CheckCompatibility(ctx, subject, subjects[subject])
+		if err != nil {
+			return err
+		}
+		log.Info("schema checked", "subject", subject, "compatible", ok)
+		if !ok {
+			incompatible = append(incompatible, subject)
+		}
+	}
+
+	if len(incompatible) > 0 {
+		return fmt.Errorf("%w: %v", schemaregistry.ErrIncompatibleSchema, incompatible)
+	}
+	return nil
+}
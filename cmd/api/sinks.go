@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dubininme/xm-assessment/internal/config"
+	"github.com/dubininme/xm-assessment/internal/domain/webhook"
+	"github.com/dubininme/xm-assessment/internal/infra/kafka"
+	"github.com/dubininme/xm-assessment/internal/infra/outbox"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/internal/infra/sink"
+)
+
+// newSinks opens the outbox sinks listed in OUTBOX_SINKS. The processor owns
+// them and closes them when it stops.
+func newSinks(cfg *config.AppConfig, serializer outbox.ValueSerializer, outboxRepo *postgres.OutboxRepo, webhookRepo *postgres.WebhookRepo) ([]outbox.Sink, error) {
+	var sinks []outbox.Sink
+	for _, name := range cfg.Sinks.SinksList() {
+		s, err := newSink(cfg, name, serializer, outboxRepo, webhookRepo)
+		if err != nil {
+			for _, opened := range sinks {
+				err = errors.Join(err, opened.Close())
+			}
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+func newSink(cfg *config.AppConfig, name string, serializer outbox.ValueSerializer, outboxRepo *postgres.OutboxRepo, webhookRepo *postgres.WebhookRepo) (outbox.Sink, error) {
+	switch name {
+	case config.SinkKafka:
+		producer := kafka.NewProducer(cfg.Kafka.BrokersList(), cfg.Kafka.Topic)
+		return outbox.NewKafkaSink(producer, serializer, cfg.Outbox.PublishTimeout), nil
+	case config.SinkWebhook:
+		return sink.NewWebhook(sink.WebhookConfig{
+			URL:         cfg.Sinks.WebhookURL,
+			Secret:      cfg.Sinks.WebhookSecret,
+			Timeout:     cfg.Sinks.WebhookTimeout,
+			MaxAttempts: cfg.Sinks.WebhookMaxAttempts,
+			Backoff:     cfg.Sinks.WebhookBackoff,
+			MaxBackoff:  cfg.Sinks.WebhookMaxBackoff,
+		}, &http.Client{}, outboxRepo), nil
+	case config.SinkNATS:
+		return sink.DialNATS(cfg.Sinks.NATSURL, cfg.Sinks.NATSSubjectPrefix)
+	case config.SinkFile:
+		return sink.NewFile(cfg.Sinks.FilePath)
+	case config.SinkSubscriptions:
+		return sink.NewSubscriptions(webhookRepo), nil
+	default:
+		return nil, errors.New("unknown sink " + name)
+	}
+}
+
+// newDispatcher sends the deliveries queued by the subscriptions sink.
+func newDispatcher(cfg config.SinkConfig, webhookRepo *postgres.WebhookRepo, txManager *postgres.TxManager, targets webhook.TargetPolicy) *sink.Dispatcher {
+	return sink.NewDispatcher(webhookRepo, txManager, sink.NewDeliveryClient(targets), sink.DispatcherConfig{
+		BatchSize:   cfg.DeliveryBatchSize,
+		Interval:    cfg.DeliveryInterval,
+		Timeout:     cfg.WebhookTimeout,
+		MaxAttempts: cfg.DeliveryMaxAttempts,
+		Backoff:     cfg.DeliveryBackoff,
+		MaxBackoff:  cfg.DeliveryMaxBackoff,
+	})
+}
+
+// deliveryTargets is the policy of subscription URLs; InitConfig has already
+// checked WEBHOOK_DELIVERY_ALLOWED_NETWORKS.
+func deliveryTargets(cfg config.SinkConfig) webhook.TargetPolicy {
+	allowed, _ := cfg.AllowedNetworks()
+	return webhook.TargetPolicy{Allowed: allowed}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dubininme/xm-assessment/internal/config"
+	"github.com/dubininme/xm-assessment/internal/delivery/http/handler"
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/domain/events"
+	"github.com/dubininme/xm-assessment/internal/domain/webhook"
+	"github.com/dubininme/xm-assessment/internal/infra/cache"
+	"github.com/dubininme/xm-assessment/internal/infra/kafka"
+	"github.com/dubininme/xm-assessment/internal/infra/memory"
+	"github.com/dubininme/xm-assessment/internal/infra/outbox"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/pkg/logger"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/errgroup"
+)
+
+// storage bundles the domain ports of the selected STORAGE backend.
+type storage struct {
+	companyRepo company.CompanyRepository
+	publisher   events.EventsPublisher
+	txManager   company.TxManager
+	checkers    []handler.HealthChecker
+	outboxAdmin handler.OutboxAdmin
+	// webhookRepo is set when the subscriptions sink is enabled
+	webhookRepo webhook.Repository
+	eventStream handler.CompanyEventStream
+
+	// worker runs background processing until ctx is done; nil if there is none
+	worker  func(ctx context.Context) error
+	closers []func()
+}
+
+func (s *storage) Close() {
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		s.closers[i]()
+	}
+}
+
+func initStorage(ctx context.Context, cfg *config.AppConfig) (*storage, error) {
+	switch cfg.Storage {
+	case config.StoragePostgres:
+		return initPostgresStorage(ctx, cfg)
+	case config.StorageMemory:
+		return initMemoryStorage(ctx), nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE %q", cfg.Storage)
+	}
+}
+
+func initPostgresStorage(ctx context.Context, cfg *config.AppConfig) (*storage, error) {
+	if cfg.MigrateOnStart {
+		if err := migrateOnStart(ctx, cfg.Db); err != nil {
+			return nil, fmt.Errorf("failed to apply migrations: %w", err)
+		}
+	}
+
+	db, err := postgres.Connect(ctx, cfg.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	outboxRepo := postgres.NewOutboxRepo(db)
+	txManager := postgres.NewTxManager(db, cfg.Db.DBTxMaxRetries)
+
+	serializer, err := newSerializer(ctx, cfg.Kafka)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to init kafka value serializer: %w", err)
+	}
+
+	companyRepo := postgres.NewCompanyRepo(db)
+
+	webhookRepo := postgres.NewWebhookRepo(db)
+	sinks, err := newSinks(cfg, serializer, outboxRepo, webhookRepo)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to init outbox sinks: %w", err)
+	}
+
+	outboxProcessor := outbox.NewProcessor(
+		outboxRepo,
+		sinks,
+		txManager,
+		cfg.Outbox.BatchSize,
+		cfg.Outbox.Interval,
+		outbox.Partitioning{
+			Partitions: cfg.Outbox.Partitions,
+			MaxOwned:   cfg.Outbox.MaxOwnedPartitions,
+			NewLocker: func(ctx context.Context) (outbox.Locker, error) {
+				return postgres.NewLockSession(ctx, db, postgres.OutboxLockClassID, cfg.Outbox.LockTimeout)
+			},
+		},
+	)
+
+	eventStream := outbox.NewStream(outboxRepo, func(ctx context.Context, onListen func(ctx context.Context) error, onID func(id int64)) error {
+		return postgres.ListenOutbox(ctx, db, onListen, onID)
+	}, cfg.Stream.ClientBuffer, cfg.Stream.RetryDelay)
+
+	replayer := outbox.NewReplayer(outboxRepo, companyRepo, newPublisherFactory(cfg.Kafka), cfg.Kafka.Topic, cfg.Outbox.BatchSize)
+
+	var cachedRepo company.CompanyRepository = companyRepo
+	var companyCache *cache.CompanyRepo
+	closers := []func(){db.Close}
+	if cfg.Cache.CompanySize > 0 {
+		var closeCache func()
+		companyCache, closeCache, err = newCompanyCache(ctx, cfg.Cache, companyRepo, txManager)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to init company cache: %w", err)
+		}
+		cachedRepo = companyCache
+		closers = append(closers, closeCache)
+	}
+
+	st := &storage{
+		companyRepo: cachedRepo,
+		publisher:   outboxRepo,
+		txManager:   txManager,
+		checkers:    []handler.HealthChecker{postgres.NewDBHealthChecker(db), outboxProcessor},
+		outboxAdmin: outbox.NewAdmin(ctx, replayer),
+		eventStream: eventStream,
+		closers:     closers,
+	}
+
+	workers := []func(ctx context.Context) error{outboxProcessor.Start, eventStream.Start}
+	if companyCache != nil {
+		workers = append(workers, func(ctx context.Context) error {
+			return companyCache.Listen(ctx, func(ctx context.Context, onListen func(ctx context.Context) error, onID func(id string)) error {
+				return postgres.ListenCompanies(ctx, db, onListen, onID)
+			})
+		})
+	}
+	if cfg.Sinks.Has(config.SinkSubscriptions) {
+		st.webhookRepo = webhookRepo
+		workers = append(workers, newDispatcher(cfg.Sinks, webhookRepo, txManager, deliveryTargets(cfg.Sinks)).Start)
+	}
+	st.worker = runAll(workers...)
+
+	return st, nil
+}
+
+// newCompanyCache puts the companies read by ID in memory and, with
+// REDIS_URL, in Redis. A Redis that cannot be reached at startup is an error;
+// later failures only fall back to Postgres. The returned func closes the
+// Redis connections.
+func newCompanyCache(ctx context.Context, cfg config.CacheConfig, repo *postgres.CompanyRepo, txManager *postgres.TxManager) (*cache.CompanyRepo, func(), error) {
+	var shared cache.Cache
+	closeCache := func() {}
+	if cfg.RedisURL != "" {
+		opts, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+		}
+		client := redis.NewClient(opts)
+		if err := client.Ping(ctx).Err(); err != nil {
+			_ = client.Close()
+			return nil, nil, fmt.Errorf("failed to reach redis: %w", err)
+		}
+		shared = cache.NewRedis(client)
+		closeCache = func() { _ = client.Close() }
+	}
+
+	return cache.NewCompanyRepo(repo, txManager, cache.NewLRU(cfg.CompanySize), shared, cfg.CompanyTTL, cfg.NegativeTTL), closeCache, nil
+}
+
+// runAll runs workers side by side until ctx is done; the first error stops
+// the others.
+func runAll(workers ...func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		g, ctx := errgroup.WithContext(ctx)
+		for _, worker := range workers {
+			g.Go(func() error { return worker(ctx) })
+		}
+		return g.Wait()
+	}
+}
+
+// initMemoryStorage keeps everything in process memory; events are only
+// logged since there is no outbox to relay them from.
+func initMemoryStorage(ctx context.Context) *storage {
+	logger.FromContext(ctx).Warn("using in-memory storage, data is lost on restart")
+
+	store := memory.NewStore()
+	return &storage{
+		companyRepo: memory.NewCompanyRepo(store),
+		publisher:   memory.NewEventsPublisher(store),
+		txManager:   memory.NewTxManager(store),
+	}
+}
+
+// newPublisherFactory opens a Kafka producer and serializer per topic for
+// replay and snapshot runs.
+func newPublisherFactory(cfg config.KafkaConfig) outbox.PublisherFactory {
+	return func(ctx context.Context, topic string) (outbox.MessageProducer, outbox.ValueSerializer, error) {
+		topicCfg := cfg
+		topicCfg.Topic = topic
+
+		serializer, err := newSerializer(ctx, topicCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return kafka.NewProducer(cfg.BrokersList(), topic), serializer, nil
+	}
+}
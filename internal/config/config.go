@@ -1,35 +1,97 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
 )
 
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
 type AppConfig struct {
 	Port            string `envconfig:"PORT" default:"8080"`
+	Storage         string `envconfig:"STORAGE" default:"postgres"`
 	Db              DbConfig
 	Kafka           KafkaConfig
 	Outbox          OutboxConfig
+	Sinks           SinkConfig
+	Stream          StreamConfig
+	Import          ImportConfig
+	Validation      ValidationConfig
+	Cache           CacheConfig
 	ShutdownTimeout int    `envconfig:"SHUTDOWN_TIMEOUT" default:"5"`
 	JWTSecret       string `envconfig:"JWT_SECRET"`
+	JWTSecretFile   string `envconfig:"JWT_SECRET_FILE"`
+
+	// MigrateOnStart applies embedded migrations before serving traffic
+	MigrateOnStart bool `envconfig:"MIGRATE_ON_START" default:"false"`
+
+	// SecretsReloadInterval controls how often *_FILE secrets are re-read
+	SecretsReloadInterval time.Duration `envconfig:"SECRETS_RELOAD_INTERVAL" default:"30s"`
+
+	// JWTSecretSource is resolved from JWT_SECRET or JWT_SECRET_FILE
+	JWTSecretSource *Secret `ignored:"true"`
 }
 
 type DbConfig struct {
+	// DatabaseURL is a full postgres:// URL or keyword/value DSN. When set it
+	// takes precedence over the individual DB_* connection settings below.
+	DatabaseURL string `envconfig:"DATABASE_URL"`
+
 	DBHost            string `envconfig:"DB_HOST"`
 	DBPort            string `envconfig:"DB_PORT"`
 	DBUser            string `envconfig:"DB_USER"`
 	DBPassword        string `envconfig:"DB_PASSWORD"`
+	DBPasswordFile    string `envconfig:"DB_PASSWORD_FILE"`
 	DBName            string `envconfig:"DB_NAME"`
 	DBMaxOpenConns    int    `envconfig:"DB_MAX_OPEN_CONNS" default:"10"`
-	DBMaxIdleConns    int    `envconfig:"DB_MAX_IDLE_CONNS" default:"5"`
+	DBMinConns        int    `envconfig:"DB_MIN_CONNS" default:"0"`
 	DBConnMaxLifetime int    `envconfig:"DB_CONN_MAX_LIFETIME" default:"300"`
+
+	DBSSLMode          string        `envconfig:"DB_SSLMODE" default:"disable"`
+	DBSSLRootCert      string        `envconfig:"DB_SSLROOTCERT"`
+	DBSSLCert          string        `envconfig:"DB_SSLCERT"`
+	DBSSLKey           string        `envconfig:"DB_SSLKEY"`
+	DBApplicationName  string        `envconfig:"DB_APPLICATION_NAME" default:"xm-companies"`
+	DBConnectTimeout   time.Duration `envconfig:"DB_CONNECT_TIMEOUT" default:"5s"`
+	DBStatementTimeout time.Duration `envconfig:"DB_STATEMENT_TIMEOUT" default:"0"`
+
+	// Startup retries before giving up on the first ping
+	DBConnectRetries    int           `envconfig:"DB_CONNECT_RETRIES" default:"5"`
+	DBConnectMaxBackoff time.Duration `envconfig:"DB_CONNECT_MAX_BACKOFF" default:"10s"`
+
+	// Retries of transactions failing with a serialization failure or deadlock
+	DBTxMaxRetries int `envconfig:"DB_TX_MAX_RETRIES" default:"3"`
+
+	// DBPasswordSource is resolved from DB_PASSWORD or DB_PASSWORD_FILE
+	DBPasswordSource *Secret `ignored:"true"`
+}
+
+// Password returns the password source, falling back to the static DBPassword
+// for configs built by hand (e.g. in tests).
+func (c *DbConfig) Password() *Secret {
+	if c.DBPasswordSource != nil {
+		return c.DBPasswordSource
+	}
+	return &Secret{value: c.DBPassword}
 }
 
 type KafkaConfig struct {
 	Brokers string `envconfig:"KAFKA_BROKERS" default:"localhost:9092"`
 	Topic   string `envconfig:"KAFKA_TOPIC" default:"company-events"`
+
+	// ValueFormat is json, avro or protobuf; the binary formats use the
+	// Confluent wire format and need SchemaRegistryURL
+	ValueFormat       string `envconfig:"KAFKA_VALUE_FORMAT" default:"json"`
+	SchemaRegistryURL string `envconfig:"SCHEMA_REGISTRY_URL"`
 }
 
 func (k *KafkaConfig) BrokersList() []string {
@@ -40,6 +102,170 @@ type OutboxConfig struct {
 	BatchSize      int           `envconfig:"OUTBOX_BATCH_SIZE" default:"100"`
 	Interval       time.Duration `envconfig:"OUTBOX_INTERVAL" default:"5s"`
 	PublishTimeout time.Duration `envconfig:"OUTBOX_PUBLISH_TIMEOUT" default:"500ms"`
+
+	// Partitions splits the outbox between replicas by aggregate; 1 elects a
+	// single leader that publishes everything
+	Partitions int `envconfig:"OUTBOX_PARTITIONS" default:"1"`
+	// MaxOwnedPartitions caps the partitions one replica takes; 0 means no cap
+	MaxOwnedPartitions int `envconfig:"OUTBOX_MAX_OWNED_PARTITIONS" default:"0"`
+	// LockTimeout is how long the partition locks of an unresponsive replica
+	// survive; it must be longer than Interval
+	LockTimeout time.Duration `envconfig:"OUTBOX_LOCK_TIMEOUT" default:"15s"`
+}
+
+// StreamConfig tunes the Server-Sent Events stream of company events.
+type StreamConfig struct {
+	// ClientBuffer is how many events a client may lag behind before it is
+	// disconnected to resume from the outbox
+	ClientBuffer int `envconfig:"STREAM_CLIENT_BUFFER" default:"256"`
+	// Heartbeat is the keep-alive interval of an idle stream
+	Heartbeat time.Duration `envconfig:"STREAM_HEARTBEAT" default:"15s"`
+	// WriteTimeout disconnects a client that stops reading
+	WriteTimeout time.Duration `envconfig:"STREAM_WRITE_TIMEOUT" default:"10s"`
+	// RetryDelay is the pause before listening again after the database
+	// connection failed
+	RetryDelay time.Duration `envconfig:"STREAM_RETRY_DELAY" default:"1s"`
+}
+
+// ImportConfig limits CSV and NDJSON uploads of companies.
+type ImportConfig struct {
+	// MaxBytes is the largest upload accepted
+	MaxBytes int64 `envconfig:"IMPORT_MAX_BYTES" default:"67108864"`
+	// SyncMaxBytes is the largest upload imported within the request; larger
+	// ones are imported as a background job
+	SyncMaxBytes int64 `envconfig:"IMPORT_SYNC_MAX_BYTES" default:"262144"`
+	// Timeout bounds an upload, a synchronous import and each write of an
+	// export, in place of the server's 10s request timeouts
+	Timeout time.Duration `envconfig:"IMPORT_TIMEOUT" default:"5m"`
+}
+
+// ValidationConfig controls the checks of requests against the OpenAPI spec.
+type ValidationConfig struct {
+	// MaxBodyBytes is the largest JSON request body accepted
+	MaxBodyBytes int64 `envconfig:"REQUEST_MAX_BODY_BYTES" default:"4194304"`
+	// Responses checks JSON responses against the spec too, answering 500
+	// when one does not match; it buffers responses, so it is meant for tests
+	Responses bool `envconfig:"VALIDATE_RESPONSES" default:"false"`
+}
+
+// CacheConfig sets the caching of company reads.
+type CacheConfig struct {
+	// Control is sent as Cache-Control with company reads; set it empty to
+	// send none. The default lets caches keep responses but revalidate them
+	// with the ETag or Last-Modified each time.
+	Control string `envconfig:"HTTP_CACHE_CONTROL" default:"no-cache"`
+
+	// CompanySize caps the companies each replica keeps in memory; 0 turns
+	// the company cache off
+	CompanySize int `envconfig:"COMPANY_CACHE_SIZE" default:"10000"`
+	// CompanyTTL bounds how long a company is served from the cache, and so
+	// how stale it can be when an invalidation is lost
+	CompanyTTL time.Duration `envconfig:"COMPANY_CACHE_TTL" default:"1m"`
+	// NegativeTTL is how long that no company has an ID is remembered
+	NegativeTTL time.Duration `envconfig:"COMPANY_CACHE_NEGATIVE_TTL" default:"5s"`
+	// RedisURL, when set, adds Redis as a cache shared by every replica
+	// behind the memory of each one, e.g. redis://localhost:6379/0
+	RedisURL string `envconfig:"REDIS_URL"`
+}
+
+// Outbox sink names accepted in OUTBOX_SINKS.
+const (
+	SinkKafka   = "kafka"
+	SinkWebhook = "webhook"
+	SinkNATS    = "nats"
+	SinkFile    = "file"
+	// SinkSubscriptions queues deliveries for the webhook subscriptions API
+	SinkSubscriptions = "subscriptions"
+)
+
+type SinkConfig struct {
+	// Sinks is a comma-separated list of kafka, webhook, nats, file and
+	// subscriptions; every event is delivered to each of them
+	Sinks string `envconfig:"OUTBOX_SINKS" default:"kafka"`
+
+	WebhookURL         string        `envconfig:"WEBHOOK_URL"`
+	WebhookSecret      string        `envconfig:"WEBHOOK_SECRET"`
+	WebhookTimeout     time.Duration `envconfig:"WEBHOOK_TIMEOUT" default:"5s"`
+	WebhookMaxAttempts int           `envconfig:"WEBHOOK_MAX_ATTEMPTS" default:"10"`
+	WebhookBackoff     time.Duration `envconfig:"WEBHOOK_BACKOFF" default:"1s"`
+	WebhookMaxBackoff  time.Duration `envconfig:"WEBHOOK_MAX_BACKOFF" default:"5m"`
+
+	NATSURL           string `envconfig:"NATS_URL" default:"nats://localhost:4222"`
+	NATSSubjectPrefix string `envconfig:"NATS_SUBJECT_PREFIX" default:"companies.events"`
+
+	// FilePath is the JSON lines file of the file sink; "-" is stdout
+	FilePath string `envconfig:"SINK_FILE_PATH" default:"-"`
+
+	// Delivery of the subscriptions sink; attempts time out after WebhookTimeout
+	DeliveryInterval    time.Duration `envconfig:"WEBHOOK_DELIVERY_INTERVAL" default:"1s"`
+	DeliveryBatchSize   int           `envconfig:"WEBHOOK_DELIVERY_BATCH_SIZE" default:"50"`
+	DeliveryMaxAttempts int           `envconfig:"WEBHOOK_DELIVERY_MAX_ATTEMPTS" default:"8"`
+	DeliveryBackoff     time.Duration `envconfig:"WEBHOOK_DELIVERY_BACKOFF" default:"30s"`
+	DeliveryMaxBackoff  time.Duration `envconfig:"WEBHOOK_DELIVERY_MAX_BACKOFF" default:"1h"`
+	// DeliveryAllowedNetworks is a comma-separated list of CIDRs that
+	// subscriptions may target even though they are loopback, private or
+	// link-local, e.g. 10.1.0.0/16 for partners reached over a VPN
+	DeliveryAllowedNetworks string `envconfig:"WEBHOOK_DELIVERY_ALLOWED_NETWORKS"`
+}
+
+// Has reports whether name is listed in OUTBOX_SINKS.
+func (s *SinkConfig) Has(name string) bool {
+	return slices.Contains(s.SinksList(), name)
+}
+
+func (s *SinkConfig) SinksList() []string {
+	var names []string
+	for _, name := range strings.Split(s.Sinks, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// AllowedNetworks parses WEBHOOK_DELIVERY_ALLOWED_NETWORKS.
+func (s *SinkConfig) AllowedNetworks() ([]netip.Prefix, error) {
+	var networks []netip.Prefix
+	for _, cidr := range strings.Split(s.DeliveryAllowedNetworks, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		network, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("WEBHOOK_DELIVERY_ALLOWED_NETWORKS: %w", err)
+		}
+		networks = append(networks, network.Masked())
+	}
+	return networks, nil
+}
+
+func (s *SinkConfig) validate() error {
+	if _, err := s.AllowedNetworks(); err != nil {
+		return err
+	}
+
+	names := s.SinksList()
+	if len(names) == 0 {
+		return errors.New("OUTBOX_SINKS must list at least one sink")
+	}
+
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		switch name {
+		case SinkKafka, SinkNATS, SinkFile, SinkSubscriptions:
+		case SinkWebhook:
+			if s.WebhookURL == "" {
+				return errors.New("WEBHOOK_URL is required by the webhook sink")
+			}
+		default:
+			return fmt.Errorf("unknown sink %q in OUTBOX_SINKS", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("sink %q is listed twice in OUTBOX_SINKS", name)
+		}
+		seen[name] = true
+	}
+	return nil
 }
 
 func InitConfig() (*AppConfig, error) {
@@ -48,5 +274,41 @@ func InitConfig() (*AppConfig, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	cfg.Db.DBPasswordSource, err = NewSecret(cfg.Db.DBPassword, cfg.Db.DBPasswordFile)
+	if err != nil {
+		return nil, fmt.Errorf("DB_PASSWORD_FILE: %w", err)
+	}
+
+	cfg.JWTSecretSource, err = NewSecret(cfg.JWTSecret, cfg.JWTSecretFile)
+	if err != nil {
+		return nil, fmt.Errorf("JWT_SECRET_FILE: %w", err)
+	}
+
+	if cfg.SecretsReloadInterval <= 0 {
+		return nil, errors.New("SECRETS_RELOAD_INTERVAL must be positive")
+	}
+	if cfg.Outbox.Partitions < 1 {
+		return nil, errors.New("OUTBOX_PARTITIONS must be positive")
+	}
+	if cfg.Outbox.LockTimeout > 0 && cfg.Outbox.LockTimeout <= cfg.Outbox.Interval {
+		return nil, errors.New("OUTBOX_LOCK_TIMEOUT must be longer than OUTBOX_INTERVAL")
+	}
+	if err := cfg.Sinks.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Import.SyncMaxBytes < 0 || cfg.Import.SyncMaxBytes > cfg.Import.MaxBytes {
+		return nil, errors.New("IMPORT_SYNC_MAX_BYTES must be between 0 and IMPORT_MAX_BYTES")
+	}
+	if cfg.Validation.MaxBodyBytes <= 0 {
+		return nil, errors.New("REQUEST_MAX_BODY_BYTES must be positive")
+	}
+	if cfg.Cache.CompanySize < 0 {
+		return nil, errors.New("COMPANY_CACHE_SIZE must not be negative")
+	}
+	if cfg.Cache.CompanySize > 0 && (cfg.Cache.CompanyTTL <= 0 || cfg.Cache.NegativeTTL < 0) {
+		return nil, errors.New("COMPANY_CACHE_TTL must be positive and COMPANY_CACHE_NEGATIVE_TTL not negative")
+	}
+
 	return &cfg, nil
 }
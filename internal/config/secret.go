@@ -0,0 +1,120 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dubininme/xm-assessment/pkg/logger"
+)
+
+// Secret holds a sensitive setting that is either passed directly or read from
+// a file (Docker/Kubernetes secret mounts). File-backed secrets can be watched
+// and reloaded without restarting the process.
+type Secret struct {
+	path string
+
+	mu        sync.RWMutex
+	value     string
+	modTime   time.Time
+	listeners []func(string)
+}
+
+// NewSecret returns a secret backed by path when it is set, otherwise a static
+// secret holding value.
+func NewSecret(value, path string) (*Secret, error) {
+	s := &Secret{path: path, value: value}
+	if path == "" {
+		return s, nil
+	}
+
+	if _, err := s.reload(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Secret) Value() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.value
+}
+
+// OnChange registers fn to be called with the new value after each reload.
+func (s *Secret) OnChange(fn func(string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// Watch polls the backing file until ctx is done. Polling is used instead of
+// inotify because Kubernetes swaps secret mounts through symlinks.
+func (s *Secret) Watch(ctx context.Context, interval time.Duration) {
+	if s.path == "" {
+		return
+	}
+
+	log := logger.FromContext(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := s.reload()
+			if err != nil {
+				log.Error("failed to reload secret", "path", s.path, "error", err)
+				continue
+			}
+
+			if changed {
+				log.Info("secret reloaded", "path", s.path)
+			}
+		}
+	}
+}
+
+func (s *Secret) reload() (bool, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return false, fmt.Errorf("stat secret file: %w", err)
+	}
+
+	s.mu.RLock()
+	unchanged := info.ModTime().Equal(s.modTime)
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return false, fmt.Errorf("read secret file: %w", err)
+	}
+
+	value := strings.TrimRight(string(data), "\r\n")
+	if value == "" {
+		return false, fmt.Errorf("secret file %s is empty", s.path)
+	}
+
+	s.mu.Lock()
+	changed := value != s.value
+	s.value = value
+	s.modTime = info.ModTime()
+	listeners := append([]func(string){}, s.listeners...)
+	s.mu.Unlock()
+
+	if changed {
+		for _, fn := range listeners {
+			fn(value)
+		}
+	}
+
+	return changed, nil
+}
@@ -0,0 +1,61 @@
+//go:build unit
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSecret_Static(t *testing.T) {
+	s, err := NewSecret("plain-value", "")
+
+	require.NoError(t, err)
+	assert.Equal(t, "plain-value", s.Value())
+}
+
+func TestNewSecret_FromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db_password")
+	require.NoError(t, os.WriteFile(path, []byte("from-file\n"), 0o600))
+
+	s, err := NewSecret("ignored-env-value", path)
+
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", s.Value())
+}
+
+func TestNewSecret_MissingFile(t *testing.T) {
+	s, err := NewSecret("", filepath.Join(t.TempDir(), "missing"))
+
+	require.Error(t, err)
+	assert.Nil(t, s)
+}
+
+func TestSecret_ReloadNotifiesListeners(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwt_secret")
+	require.NoError(t, os.WriteFile(path, []byte("first"), 0o600))
+
+	s, err := NewSecret("", path)
+	require.NoError(t, err)
+
+	var got string
+	s.OnChange(func(v string) { got = v })
+
+	require.NoError(t, os.WriteFile(path, []byte("second"), 0o600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+
+	changed, err := s.reload()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "second", s.Value())
+	assert.Equal(t, "second", got)
+
+	changed, err = s.reload()
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
@@ -0,0 +1,33 @@
+package graphql
+
+import (
+	"github.com/dubininme/xm-assessment/internal/delivery/http/handler"
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+)
+
+// apiError is an error of a resolver. Its code is the one the REST API
+// answers with, and is sent in the extensions of the GraphQL error.
+type apiError struct {
+	code    oapi.ErrorCode
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func (e *apiError) Extensions() map[string]any {
+	return map[string]any{"code": e.code}
+}
+
+func badRequest(message string) error {
+	return &apiError{code: oapi.ErrorCodeBadRequest, message: message}
+}
+
+var errUnauthorized = &apiError{code: oapi.ErrorCodeUnauthorized, message: "missing or invalid authorization header"}
+
+// companyError maps an error of the company service like the REST API does.
+func companyError(err error) error {
+	_, body := handler.CompanyError(err)
+	return &apiError{code: body.Code, message: body.Message}
+}
@@ -0,0 +1,87 @@
+// Package graphql serves the company API over GraphQL at /graphql, with the
+// schema in api/schema.graphql. Resolvers delegate to the company service
+// like the REST handlers do, and answer with the same error codes.
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	gql "github.com/graph-gophers/graphql-go"
+)
+
+const (
+	// maxDepth and maxQueryLength bound the work a single query can ask for
+	maxDepth       = 8
+	maxQueryLength = 16 << 10
+)
+
+type Handler struct {
+	schema       *gql.Schema
+	service      *company.CompanyService
+	maxBodyBytes int64
+}
+
+// NewHandler binds schema to the resolvers; requests with a body larger than
+// maxBodyBytes are rejected.
+func NewHandler(schema string, service *company.CompanyService, maxBodyBytes int64) (*Handler, error) {
+	s, err := gql.ParseSchema(schema, &resolver{service: service},
+		gql.UseStringDescriptions(),
+		gql.MaxDepth(maxDepth),
+		gql.MaxQueryLength(maxQueryLength),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing GraphQL schema: %w", err)
+	}
+
+	return &Handler{schema: s, service: service, maxBodyBytes: maxBodyBytes}, nil
+}
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// ServeHTTP executes a query posted as JSON. Errors of the query are in the
+// errors of the response, which is a 200; only a request that is not a
+// GraphQL request gets an error status.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, h.maxBodyBytes)).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, oapi.ErrorCodePayloadTooLarge,
+				fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+		writeError(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, "invalid request body")
+		return
+	}
+	if req.Query == "" {
+		writeError(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, "query is required")
+		return
+	}
+
+	ctx := withLoader(r.Context(), newCompanyLoader(h.service))
+	resp := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("failed to encode JSON response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code oapi.ErrorCode, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(oapi.Error{Code: code, Message: message}); err != nil {
+		slog.Error("failed to encode JSON response", "error", err)
+	}
+}
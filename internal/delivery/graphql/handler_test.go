@@ -0,0 +1,293 @@
+//go:build unit
+
+package graphql_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dubininme/xm-assessment/api"
+	"github.com/dubininme/xm-assessment/internal/delivery/graphql"
+	deliveryHttp "github.com/dubininme/xm-assessment/internal/delivery/http"
+	"github.com/dubininme/xm-assessment/internal/delivery/http/handler"
+	"github.com/dubininme/xm-assessment/internal/delivery/http/middleware"
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/infra/auth"
+	"github.com/dubininme/xm-assessment/internal/infra/memory"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingRepo counts the reads of List, which serve multi-ID lookups.
+type countingRepo struct {
+	company.CompanyRepository
+	lists atomic.Int32
+}
+
+func (r *countingRepo) List(ctx context.Context, filter company.ListFilter, afterID string, limit int) ([]*company.Company, error) {
+	r.lists.Add(1)
+	return r.CompanyRepository.List(ctx, filter, afterID, limit)
+}
+
+type fixture struct {
+	router *mux.Router
+	repo   *countingRepo
+	token  string
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	store := memory.NewStore()
+	repo := &countingRepo{CompanyRepository: memory.NewCompanyRepo(store)}
+	service := company.NewCompanyService(repo, memory.NewEventsPublisher(store), memory.NewTxManager(store))
+	jwtService := auth.NewJWTService("test-secret")
+
+	graphQLHandler, err := graphql.NewHandler(api.GraphQLSchema, service, 1<<10)
+	require.NoError(t, err)
+
+	router := deliveryHttp.NewRouter(handler.NewCompanyHandler(service, ""), handler.NewHealthHandler(), handler.NewAuthHandler(jwtService),
+		nil, nil, nil, handler.NewTransferHandler(t.Context(), service, 1<<20, 64<<10, time.Minute),
+		graphQLHandler, middleware.NewAuthMiddleware(jwtService), nil)
+
+	token, err := jwtService.GenerateToken("tester", time.Hour)
+	require.NoError(t, err)
+	return &fixture{router: router, repo: repo, token: token}
+}
+
+type gqlError struct {
+	Message    string         `json:"message"`
+	Extensions map[string]any `json:"extensions"`
+}
+
+type gqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []gqlError      `json:"errors"`
+}
+
+func (f *fixture) exec(t *testing.T, token, query string, variables map[string]any) gqlResponse {
+	t.Helper()
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp gqlResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	return resp
+}
+
+// code is the error code of the only error of resp.
+func code(t *testing.T, resp gqlResponse) string {
+	t.Helper()
+	require.Len(t, resp.Errors, 1)
+	c, _ := resp.Errors[0].Extensions["code"].(string)
+	return c
+}
+
+const createMutation = `mutation($input: CreateCompanyInput!) {
+	createCompany(input: $input) { id name description employeesCount registered type }
+}`
+
+func (f *fixture) create(t *testing.T, name, companyType string, registered bool) string {
+	t.Helper()
+	resp := f.exec(t, f.token, createMutation, map[string]any{"input": map[string]any{
+		"name": name, "employeesCount": 10, "registered": registered, "type": companyType,
+	}})
+	require.Empty(t, resp.Errors)
+
+	var data struct {
+		CreateCompany struct{ ID string } `json:"createCompany"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Data, &data))
+	return data.CreateCompany.ID
+}
+
+func TestGraphQL_Mutations(t *testing.T) {
+	f := newFixture(t)
+
+	resp := f.exec(t, f.token, createMutation, map[string]any{"input": map[string]any{
+		"name": "Acme", "description": "Anvils", "employeesCount": 10, "registered": true, "type": "SOLE_PROPRIETORSHIP",
+	}})
+	require.Empty(t, resp.Errors)
+	var created struct {
+		CreateCompany map[string]any `json:"createCompany"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Data, &created))
+	id := created.CreateCompany["id"].(string)
+	assert.Equal(t, map[string]any{
+		"id": id, "name": "Acme", "description": "Anvils", "employeesCount": float64(10), "registered": true, "type": "SOLE_PROPRIETORSHIP",
+	}, created.CreateCompany)
+
+	resp = f.exec(t, f.token, `mutation($id: ID!) {
+		updateCompany(id: $id, input: {employeesCount: 42, type: NON_PROFIT}) { employeesCount type name }
+	}`, map[string]any{"id": id})
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"updateCompany": {"employeesCount": 42, "type": "NON_PROFIT", "name": "Acme"}}`, string(resp.Data))
+
+	resp = f.exec(t, f.token, `mutation($id: ID!) { deleteCompany(id: $id) }`, map[string]any{"id": id})
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, fmt.Sprintf(`{"deleteCompany": %q}`, id), string(resp.Data))
+
+	resp = f.exec(t, "", `query($id: ID!) { company(id: $id) { id } }`, map[string]any{"id": id})
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"company": null}`, string(resp.Data), "a missing company is null")
+}
+
+func TestGraphQL_Errors(t *testing.T) {
+	f := newFixture(t)
+	f.create(t, "Acme", "CORPORATIONS", true)
+
+	tests := []struct {
+		name      string
+		token     string
+		query     string
+		variables map[string]any
+		code      string
+	}{
+		{"mutation without token", "", createMutation,
+			map[string]any{"input": map[string]any{"name": "Other", "employeesCount": 1, "registered": true, "type": "COOPERATIVE"}}, "unauthorized"},
+		{"mutation with invalid token", "not-a-token", `mutation { deleteCompany(id: "6f1c9e44-3f4a-4f0e-9a55-2b1f2d0c7a11") }`, nil, "unauthorized"},
+		{"duplicate name", f.token, createMutation,
+			map[string]any{"input": map[string]any{"name": "Acme", "employeesCount": 1, "registered": true, "type": "COOPERATIVE"}}, "conflict"},
+		{"invalid field", f.token, createMutation,
+			map[string]any{"input": map[string]any{"name": "A name that is far too long", "employeesCount": 1, "registered": true, "type": "COOPERATIVE"}}, "bad_request"},
+		{"update missing", f.token, `mutation { updateCompany(id: "6f1c9e44-3f4a-4f0e-9a55-2b1f2d0c7a11", input: {registered: false}) { id } }`, nil, "not_found"},
+		{"delete missing", f.token, `mutation { deleteCompany(id: "6f1c9e44-3f4a-4f0e-9a55-2b1f2d0c7a11") }`, nil, "not_found"},
+		{"invalid id", "", `{ company(id: "nope") { id } }`, nil, "bad_request"},
+		{"page too large", "", `{ companies(first: 1000) { nodes { id } } }`, nil, "bad_request"},
+		{"invalid cursor", "", `{ companies(after: "???") { nodes { id } } }`, nil, "bad_request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.code, code(t, f.exec(t, tt.token, tt.query, tt.variables)))
+		})
+	}
+}
+
+func TestGraphQL_Companies(t *testing.T) {
+	f := newFixture(t)
+	acme := f.create(t, "Acme", "CORPORATIONS", true)
+	acmeCoop := f.create(t, "Acme Coop", "COOPERATIVE", false)
+	f.create(t, "Globex", "CORPORATIONS", false)
+
+	type page struct {
+		Companies struct {
+			Edges []struct {
+				Cursor string `json:"cursor"`
+				Node   struct {
+					ID string `json:"id"`
+				} `json:"node"`
+			} `json:"edges"`
+			Nodes []struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"nodes"`
+			PageInfo struct {
+				EndCursor   *string `json:"endCursor"`
+				HasNextPage bool    `json:"hasNextPage"`
+			} `json:"pageInfo"`
+		} `json:"companies"`
+	}
+	list := func(t *testing.T, filter map[string]any, first int, after *string) page {
+		t.Helper()
+		resp := f.exec(t, "", `query($filter: CompanyFilter, $first: Int, $after: String) {
+			companies(filter: $filter, first: $first, after: $after) {
+				edges { cursor node { id } }
+				nodes { id name }
+				pageInfo { endCursor hasNextPage }
+			}
+		}`, map[string]any{"filter": filter, "first": first, "after": after})
+		require.Empty(t, resp.Errors)
+
+		var p page
+		require.NoError(t, json.Unmarshal(resp.Data, &p))
+		return p
+	}
+	names := func(p page) []string {
+		var names []string
+		for _, n := range p.Companies.Nodes {
+			names = append(names, n.Name)
+		}
+		return names
+	}
+
+	t.Run("pages", func(t *testing.T) {
+		var seen []string
+		var after *string
+		for {
+			p := list(t, nil, 2, after)
+			require.LessOrEqual(t, len(p.Companies.Nodes), 2)
+			for i, edge := range p.Companies.Edges {
+				assert.Equal(t, p.Companies.Nodes[i].ID, edge.Node.ID)
+			}
+			seen = append(seen, names(p)...)
+			if !p.Companies.PageInfo.HasNextPage {
+				break
+			}
+			after = p.Companies.PageInfo.EndCursor
+		}
+		assert.ElementsMatch(t, []string{"Acme", "Acme Coop", "Globex"}, seen)
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{"Acme", "Acme Coop"}, names(list(t, map[string]any{"nameContains": "acme"}, 10, nil)))
+		assert.ElementsMatch(t, []string{"Acme", "Globex"}, names(list(t, map[string]any{"type": "CORPORATIONS"}, 10, nil)))
+		assert.ElementsMatch(t, []string{"Acme"}, names(list(t, map[string]any{"type": "CORPORATIONS", "registered": true}, 10, nil)))
+		assert.ElementsMatch(t, []string{"Acme", "Acme Coop"}, names(list(t, map[string]any{"ids": []string{acme, acmeCoop}}, 10, nil)))
+		assert.Empty(t, names(list(t, map[string]any{"ids": []string{}}, 10, nil)), "no IDs match nothing")
+	})
+}
+
+func TestGraphQL_BatchesLookups(t *testing.T) {
+	f := newFixture(t)
+	ids := []string{f.create(t, "Acme", "CORPORATIONS", true), f.create(t, "Globex", "COOPERATIVE", false), uuid.NewString()}
+
+	var query strings.Builder
+	query.WriteString("{")
+	for i, id := range ids {
+		fmt.Fprintf(&query, "c%d: company(id: %q) { name } ", i, id)
+	}
+	// The same company twice is read once
+	fmt.Fprintf(&query, "again: company(id: %q) { name } }", ids[0])
+
+	f.repo.lists.Store(0)
+	resp := f.exec(t, "", query.String(), nil)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"c0": {"name": "Acme"}, "c1": {"name": "Globex"}, "c2": null, "again": {"name": "Acme"}}`, string(resp.Data))
+	assert.EqualValues(t, 1, f.repo.lists.Load(), "the lookups are batched into one read")
+}
+
+func TestGraphQL_MalformedRequests(t *testing.T) {
+	f := newFixture(t)
+
+	for name, body := range map[string]string{
+		"not JSON":  "{",
+		"no query":  `{"variables": {}}`,
+		"too large": `{"query": "` + strings.Repeat(" ", 2<<10) + `{ companies { nodes { id } } }"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			f.router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+			assert.Contains(t, []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge}, w.Code)
+			assert.Contains(t, w.Body.String(), `"code"`)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package graphql
+
+import (
+	"context"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+const (
+	// loaderWait is how long a lookup waits for others to batch with; the
+	// fields of a query are resolved concurrently, so they arrive together
+	loaderWait = 2 * time.Millisecond
+	// loaderBatch caps the IDs read at once
+	loaderBatch = 100
+)
+
+type loaderKey struct{}
+
+type companyLoader = dataloader.Loader[string, *company.Company]
+
+// newCompanyLoader batches the lookups of a request by ID into one read. It
+// also caches them, so it lives for a single request.
+func newCompanyLoader(service *company.CompanyService) *companyLoader {
+	return dataloader.NewBatchedLoader(func(ctx context.Context, ids []string) []*dataloader.Result[*company.Company] {
+		results := make([]*dataloader.Result[*company.Company], len(ids))
+		companies, err := service.GetByIDs(ctx, ids)
+		if err != nil {
+			for i := range results {
+				results[i] = &dataloader.Result[*company.Company]{Error: err}
+			}
+			return results
+		}
+
+		byID := make(map[string]*company.Company, len(companies))
+		for _, c := range companies {
+			byID[c.ID().String()] = c
+		}
+		// A company that does not exist resolves to nil, not an error
+		for i, id := range ids {
+			results[i] = &dataloader.Result[*company.Company]{Data: byID[id]}
+		}
+		return results
+	}, dataloader.WithWait[string, *company.Company](loaderWait), dataloader.WithBatchCapacity[string, *company.Company](loaderBatch))
+}
+
+func withLoader(ctx context.Context, l *companyLoader) context.Context {
+	return context.WithValue(ctx, loaderKey{}, l)
+}
+
+func loaderFrom(ctx context.Context) *companyLoader {
+	return ctx.Value(loaderKey{}).(*companyLoader)
+}
@@ -0,0 +1,352 @@
+package graphql
+
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/dubininme/xm-assessment/internal/delivery/http/middleware"
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/google/uuid"
+	gql "github.com/graph-gophers/graphql-go"
+)
+
+// maxPageSize caps first; its default is in the schema.
+const maxPageSize = 100
+
+// companyTypes maps the CompanyType enum of the schema to the domain, whose
+// names are not valid GraphQL enum values.
+var companyTypes = map[string]company.CompanyType{
+	"CORPORATIONS":        company.CorporationsType,
+	"NON_PROFIT":          company.NonProfitType,
+	"COOPERATIVE":         company.CooperativeType,
+	"SOLE_PROPRIETORSHIP": company.SoleProprietorshipType,
+}
+
+func companyTypeFromEnum(s string) (company.CompanyType, error) {
+	t, ok := companyTypes[s]
+	if !ok {
+		return "", badRequest("invalid company type")
+	}
+	return t, nil
+}
+
+// resolver is the root of the schema.
+type resolver struct {
+	service *company.CompanyService
+}
+
+func (r *resolver) Company(ctx context.Context, args struct{ ID gql.ID }) (*companyResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := loaderFrom(ctx).Load(ctx, id)()
+	if err != nil {
+		return nil, companyError(err)
+	}
+	if c == nil {
+		return nil, nil
+	}
+	return &companyResolver{c: c}, nil
+}
+
+type companyFilterInput struct {
+	IDs          *[]gql.ID
+	NameContains *string
+	Type         *string
+	Registered   *bool
+}
+
+func (r *resolver) Companies(ctx context.Context, args struct {
+	Filter *companyFilterInput
+	First  int32
+	After  *string
+}) (*connectionResolver, error) {
+	first := args.First
+	if first < 1 || first > maxPageSize {
+		return nil, badRequest("first must be between 1 and 100")
+	}
+
+	var afterID string
+	if args.After != nil {
+		var err error
+		if afterID, err = decodeCursor(*args.After); err != nil {
+			return nil, err
+		}
+	}
+
+	filter, err := listFilter(args.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if args.Filter != nil && args.Filter.IDs != nil && len(*args.Filter.IDs) == 0 {
+		// No IDs match no company, where an empty ListFilter.IDs matches all
+		return &connectionResolver{}, nil
+	}
+
+	// One more than asked tells whether there is a next page
+	companies, err := r.service.ListCompanies(ctx, filter, afterID, int(first)+1)
+	if err != nil {
+		return nil, companyError(err)
+	}
+
+	conn := &connectionResolver{hasNext: len(companies) > int(first)}
+	for _, c := range companies[:min(len(companies), int(first))] {
+		// Later lookups of these companies in the same query need no read
+		loaderFrom(ctx).Prime(ctx, c.ID().String(), c)
+		conn.companies = append(conn.companies, &companyResolver{c: c})
+	}
+	return conn, nil
+}
+
+func listFilter(in *companyFilterInput) (company.ListFilter, error) {
+	var filter company.ListFilter
+	if in == nil {
+		return filter, nil
+	}
+
+	if in.IDs != nil {
+		for _, raw := range *in.IDs {
+			id, err := parseID(raw)
+			if err != nil {
+				return filter, err
+			}
+			filter.IDs = append(filter.IDs, id)
+		}
+	}
+	if in.NameContains != nil {
+		filter.NameContains = *in.NameContains
+	}
+	if in.Type != nil {
+		t, err := companyTypeFromEnum(*in.Type)
+		if err != nil {
+			return filter, err
+		}
+		filter.Type = &t
+	}
+	filter.Registered = in.Registered
+	return filter, nil
+}
+
+type createCompanyInput struct {
+	Name           string
+	Description    *string
+	EmployeesCount int32
+	Registered     bool
+	Type           string
+}
+
+func (r *resolver) CreateCompany(ctx context.Context, args struct{ Input createCompanyInput }) (*companyResolver, error) {
+	if err := authenticated(ctx); err != nil {
+		return nil, err
+	}
+
+	t, err := companyTypeFromEnum(args.Input.Type)
+	if err != nil {
+		return nil, err
+	}
+	params := company.CreateParams{
+		Name:           args.Input.Name,
+		EmployeesCount: int(args.Input.EmployeesCount),
+		Registered:     args.Input.Registered,
+		Type:           t.String(),
+	}
+	if args.Input.Description != nil {
+		params.Description = *args.Input.Description
+	}
+
+	c, err := r.service.CreateCompany(ctx, params)
+	if err != nil {
+		return nil, companyError(err)
+	}
+	return &companyResolver{c: c}, nil
+}
+
+type updateCompanyInput struct {
+	Name           *string
+	Description    *string
+	EmployeesCount *int32
+	Registered     *bool
+	Type           *string
+}
+
+func (r *resolver) UpdateCompany(ctx context.Context, args struct {
+	ID    gql.ID
+	Input updateCompanyInput
+}) (*companyResolver, error) {
+	if err := authenticated(ctx); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := company.UpdateParams{
+		Name:        args.Input.Name,
+		Description: args.Input.Description,
+		Registered:  args.Input.Registered,
+	}
+	if args.Input.EmployeesCount != nil {
+		count := int(*args.Input.EmployeesCount)
+		params.EmployeesCount = &count
+	}
+	if args.Input.Type != nil {
+		t, err := companyTypeFromEnum(*args.Input.Type)
+		if err != nil {
+			return nil, err
+		}
+		name := t.String()
+		params.Type = &name
+	}
+
+	c, err := r.service.UpdateCompany(ctx, id, params)
+	if err != nil {
+		return nil, companyError(err)
+	}
+	loaderFrom(ctx).Clear(ctx, id).Prime(ctx, id, c)
+	return &companyResolver{c: c}, nil
+}
+
+func (r *resolver) DeleteCompany(ctx context.Context, args struct{ ID gql.ID }) (gql.ID, error) {
+	if err := authenticated(ctx); err != nil {
+		return "", err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := r.service.DeleteCompany(ctx, id); err != nil {
+		return "", companyError(err)
+	}
+	loaderFrom(ctx).Clear(ctx, id)
+	return args.ID, nil
+}
+
+// authenticated checks that the request carried a valid token; mutations
+// need one, like the writes of the REST API.
+func authenticated(ctx context.Context) error {
+	if userID, _ := ctx.Value(middleware.UserIDKey).(string); userID == "" {
+		return errUnauthorized
+	}
+	return nil
+}
+
+func parseID(id gql.ID) (string, error) {
+	parsed, err := uuid.Parse(string(id))
+	if err != nil {
+		return "", badRequest("invalid id")
+	}
+	return parsed.String(), nil
+}
+
+// Cursors are opaque to clients; they hold the ID of the last company of a
+// page.
+func encodeCursor(id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(id))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", badRequest("invalid cursor")
+	}
+	id, err := uuid.ParseBytes(raw)
+	if err != nil {
+		return "", badRequest("invalid cursor")
+	}
+	return id.String(), nil
+}
+
+type companyResolver struct {
+	c *company.Company
+}
+
+func (r *companyResolver) ID() gql.ID {
+	return gql.ID(r.c.ID().String())
+}
+
+func (r *companyResolver) Name() string {
+	return r.c.Name().String()
+}
+
+func (r *companyResolver) Description() *string {
+	desc := r.c.Description().String()
+	if desc == "" {
+		return nil
+	}
+	return &desc
+}
+
+func (r *companyResolver) EmployeesCount() int32 {
+	return int32(r.c.EmployeesCount().Int()) // #nosec G115 -- employee counts fit in an int32
+}
+
+func (r *companyResolver) Registered() bool {
+	return r.c.IsRegistered()
+}
+
+func (r *companyResolver) Type() string {
+	for name, t := range companyTypes {
+		if t == r.c.CompanyType() {
+			return name
+		}
+	}
+	return ""
+}
+
+type connectionResolver struct {
+	companies []*companyResolver
+	hasNext   bool
+}
+
+func (r *connectionResolver) Edges() []*edgeResolver {
+	edges := make([]*edgeResolver, len(r.companies))
+	for i, c := range r.companies {
+		edges[i] = &edgeResolver{node: c}
+	}
+	return edges
+}
+
+func (r *connectionResolver) Nodes() []*companyResolver {
+	if r.companies == nil {
+		return []*companyResolver{}
+	}
+	return r.companies
+}
+
+func (r *connectionResolver) PageInfo() *pageInfoResolver {
+	info := &pageInfoResolver{hasNext: r.hasNext}
+	if len(r.companies) > 0 {
+		cursor := encodeCursor(string(r.companies[len(r.companies)-1].ID()))
+		info.endCursor = &cursor
+	}
+	return info
+}
+
+type edgeResolver struct {
+	node *companyResolver
+}
+
+func (r *edgeResolver) Cursor() string {
+	return encodeCursor(string(r.node.ID()))
+}
+
+func (r *edgeResolver) Node() *companyResolver {
+	return r.node
+}
+
+type pageInfoResolver struct {
+	endCursor *string
+	hasNext   bool
+}
+
+func (r *pageInfoResolver) EndCursor() *string {
+	return r.endCursor
+}
+
+func (r *pageInfoResolver) HasNextPage() bool {
+	return r.hasNext
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	"github.com/google/uuid"
+)
+
+// OutboxAdmin starts and tracks outbox replay and snapshot jobs.
+type OutboxAdmin interface {
+	StartReplay(params ReplayParams) OutboxJob
+	StartSnapshot(params SnapshotParams) OutboxJob
+	Job(id string) (OutboxJob, bool)
+}
+
+// ReplayParams selects the outbox events to re-publish; zero values leave a
+// filter open.
+type ReplayParams struct {
+	AggregateID   string
+	EventType     string
+	From          time.Time
+	To            time.Time
+	FromID        int64
+	ToID          int64
+	Topic         string
+	RatePerSecond float64
+	BatchSize     int
+}
+
+type SnapshotParams struct {
+	Topic         string
+	RatePerSecond float64
+	BatchSize     int
+}
+
+type OutboxJob struct {
+	ID         string
+	Kind       string
+	Status     string
+	Total      int64
+	Published  int64
+	LastID     string
+	Error      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// AdminHandler exposes the outbox replay and snapshot tooling.
+type AdminHandler struct {
+	outbox OutboxAdmin
+}
+
+func NewAdminHandler(outbox OutboxAdmin) *AdminHandler {
+	return &AdminHandler{outbox: outbox}
+}
+
+func (h *AdminHandler) ReplayOutbox(w http.ResponseWriter, r *http.Request) {
+	var req oapi.OutboxReplayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, "invalid request body")
+		return
+	}
+
+	params, err := replayRequestToParams(req)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, jobToResponse(h.outbox.StartReplay(params)))
+}
+
+func (h *AdminHandler) SnapshotCompanies(w http.ResponseWriter, r *http.Request) {
+	var req oapi.OutboxSnapshotRequest
+	// The body is optional
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, "invalid request body")
+		return
+	}
+
+	params := SnapshotParams{Topic: deref(req.Topic)}
+	if err := setRateAndBatch(req.Rate, req.BatchSize, &params.RatePerSecond, &params.BatchSize); err != nil {
+		writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, jobToResponse(h.outbox.StartSnapshot(params)))
+}
+
+func (h *AdminHandler) GetOutboxJob(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	job, ok := h.outbox.Job(id.String())
+	if !ok {
+		writeErr(w, http.StatusNotFound, oapi.ErrorCodeNotFound, "job not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, jobToResponse(job))
+}
+
+func replayRequestToParams(req oapi.OutboxReplayRequest) (ReplayParams, error) {
+	params := ReplayParams{
+		EventType: deref(req.EventType),
+		From:      deref(req.From),
+		To:        deref(req.To),
+		FromID:    deref(req.FromId),
+		ToID:      deref(req.ToId),
+		Topic:     deref(req.Topic),
+	}
+
+	if req.AggregateId != nil {
+		params.AggregateID = req.AggregateId.String()
+	}
+
+	if params.FromID < 0 || params.ToID < 0 {
+		return params, errors.New("from_id and to_id must be positive")
+	}
+	if params.ToID != 0 && params.FromID > params.ToID {
+		return params, errors.New("from_id must not be greater than to_id")
+	}
+	if !params.To.IsZero() && params.From.After(params.To) {
+		return params, errors.New("from must not be after to")
+	}
+
+	err := setRateAndBatch(req.Rate, req.BatchSize, &params.RatePerSecond, &params.BatchSize)
+	return params, err
+}
+
+func setRateAndBatch(rate *float32, batchSize *int, ratePerSecond *float64, batch *int) error {
+	if rate != nil {
+		if *rate < 0 {
+			return errors.New("rate must not be negative")
+		}
+		*ratePerSecond = float64(*rate)
+	}
+	if batchSize != nil {
+		if *batchSize < 1 {
+			return errors.New("batch_size must be positive")
+		}
+		*batch = *batchSize
+	}
+	return nil
+}
+
+func jobToResponse(job OutboxJob) oapi.OutboxJob {
+	resp := oapi.OutboxJob{
+		Id:        uuid.MustParse(job.ID),
+		Kind:      oapi.OutboxJobKind(job.Kind),
+		Status:    oapi.OutboxJobStatus(job.Status),
+		Total:     job.Total,
+		Published: job.Published,
+		StartedAt: job.StartedAt,
+	}
+
+	if job.LastID != "" {
+		resp.LastId = &job.LastID
+	}
+	if job.Error != "" {
+		resp.Error = &job.Error
+	}
+	if !job.FinishedAt.IsZero() {
+		resp.FinishedAt = &job.FinishedAt
+	}
+	return resp
+}
+
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
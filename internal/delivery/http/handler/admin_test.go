@@ -0,0 +1,129 @@
+//go:build unit
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubOutboxAdmin struct {
+	replay   *ReplayParams
+	snapshot *SnapshotParams
+	jobs     map[string]OutboxJob
+}
+
+func (a *stubOutboxAdmin) StartReplay(params ReplayParams) OutboxJob {
+	a.replay = &params
+	return OutboxJob{ID: uuid.NewString(), Kind: "replay", Status: "running", Total: 10, StartedAt: time.Now()}
+}
+
+func (a *stubOutboxAdmin) StartSnapshot(params SnapshotParams) OutboxJob {
+	a.snapshot = &params
+	return OutboxJob{ID: uuid.NewString(), Kind: "snapshot", Status: "running", StartedAt: time.Now()}
+}
+
+func (a *stubOutboxAdmin) Job(id string) (OutboxJob, bool) {
+	job, ok := a.jobs[id]
+	return job, ok
+}
+
+func TestReplayOutbox_StartsJob(t *testing.T) {
+	admin := &stubOutboxAdmin{}
+	h := NewAdminHandler(admin)
+	aggregateID := uuid.NewString()
+
+	body := `{"aggregate_id":"` + aggregateID + `","event_type":"CompanyUpdated","from":"2024-01-01T00:00:00Z",` +
+		`"from_id":5,"to_id":9,"topic":"replays","rate":50,"batch_size":20}`
+	w := httptest.NewRecorder()
+	h.ReplayOutbox(w, httptest.NewRequest(http.MethodPost, "/api/v1/admin/outbox/replay", strings.NewReader(body)))
+
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var resp oapi.OutboxJob
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, oapi.OutboxJobKindReplay, resp.Kind)
+	assert.Equal(t, oapi.OutboxJobStatusRunning, resp.Status)
+	assert.EqualValues(t, 10, resp.Total)
+	assert.Nil(t, resp.FinishedAt)
+
+	require.NotNil(t, admin.replay)
+	assert.Equal(t, ReplayParams{
+		AggregateID:   aggregateID,
+		EventType:     "CompanyUpdated",
+		From:          time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		FromID:        5,
+		ToID:          9,
+		Topic:         "replays",
+		RatePerSecond: 50,
+		BatchSize:     20,
+	}, *admin.replay)
+}
+
+func TestReplayOutbox_InvalidRequest(t *testing.T) {
+	tests := map[string]string{
+		"malformed body":  `{`,
+		"negative rate":   `{"rate":-1}`,
+		"zero batch size": `{"batch_size":0}`,
+		"inverted ids":    `{"from_id":9,"to_id":5}`,
+		"inverted times":  `{"from":"2024-02-01T00:00:00Z","to":"2024-01-01T00:00:00Z"}`,
+		"invalid uuid":    `{"aggregate_id":"nope"}`,
+	}
+
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			admin := &stubOutboxAdmin{}
+			w := httptest.NewRecorder()
+			NewAdminHandler(admin).ReplayOutbox(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Nil(t, admin.replay)
+		})
+	}
+}
+
+func TestSnapshotCompanies_EmptyBody(t *testing.T) {
+	admin := &stubOutboxAdmin{}
+	w := httptest.NewRecorder()
+	NewAdminHandler(admin).SnapshotCompanies(w, httptest.NewRequest(http.MethodPost, "/", http.NoBody))
+
+	require.Equal(t, http.StatusAccepted, w.Code)
+	require.NotNil(t, admin.snapshot)
+	assert.Equal(t, SnapshotParams{}, *admin.snapshot)
+}
+
+func TestGetOutboxJob(t *testing.T) {
+	id := uuid.NewString()
+	admin := &stubOutboxAdmin{jobs: map[string]OutboxJob{
+		id: {ID: id, Kind: "snapshot", Status: "failed", Total: 4, Published: 2, LastID: "x", Error: "kafka down",
+			StartedAt: time.Now(), FinishedAt: time.Now()},
+	}}
+
+	get := func(jobID uuid.UUID) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		NewAdminHandler(admin).GetOutboxJob(w, httptest.NewRequest(http.MethodGet, "/", nil), jobID)
+		return w
+	}
+
+	w := get(uuid.MustParse(id))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp oapi.OutboxJob
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, oapi.OutboxJobStatusFailed, resp.Status)
+	assert.EqualValues(t, 2, resp.Published)
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, "kafka down", *resp.Error)
+	assert.NotNil(t, resp.FinishedAt)
+
+	assert.Equal(t, http.StatusNotFound, get(uuid.New()).Code)
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	"github.com/google/uuid"
+)
+
+// maxBatchBodyBytes leaves room for MaxBatchOperations creates with long
+// descriptions
+const maxBatchBodyBytes = 4 << 20
+
+func (h *CompanyHandler) BatchCompanies(w http.ResponseWriter, r *http.Request) {
+	var req oapi.CompanyBatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodyBytes)).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, "invalid request body")
+		return
+	}
+
+	mode := company.BatchAllOrNothing
+	if req.Mode != nil {
+		mode = company.BatchMode(*req.Mode)
+	}
+
+	ops, err := batchRequestToOperations(req.Operations)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, err.Error())
+		return
+	}
+
+	results, err := h.service.Batch(r.Context(), mode, ops)
+	if err != nil {
+		if errors.Is(err, company.ErrEmptyBatch) ||
+			errors.Is(err, company.ErrBatchTooLarge) ||
+			errors.Is(err, company.ErrInvalidBatchMode) {
+			writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, err.Error())
+			return
+		}
+
+		writeErr(w, http.StatusInternalServerError, oapi.ErrorCodeInternalError, "internal error")
+		return
+	}
+
+	resp := oapi.CompanyBatchResponse{
+		Mode:    oapi.CompanyBatchMode(mode),
+		Results: make([]oapi.CompanyBatchResult, len(results)),
+	}
+	for i, res := range results {
+		resp.Results[i] = batchResultToResponse(i, res)
+		if res.Status == company.BatchSucceeded {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// batchRequestToOperations rejects malformed operations up front, so a
+// mistake in the request fails it as a whole rather than one item.
+func batchRequestToOperations(reqOps []oapi.CompanyBatchOperation) ([]company.BatchOperation, error) {
+	ops := make([]company.BatchOperation, len(reqOps))
+	for i, reqOp := range reqOps {
+		op := company.BatchOperation{Action: company.BatchAction(reqOp.Action)}
+		if reqOp.Id != nil {
+			op.CompanyID = reqOp.Id.String()
+		}
+
+		switch op.Action {
+		case company.BatchCreate:
+			if reqOp.Create == nil {
+				return nil, fmt.Errorf("operations[%d]: create requires the create field", i)
+			}
+			op.Create = CreateRequestToParams(*reqOp.Create)
+		case company.BatchUpdate:
+			if reqOp.Id == nil || reqOp.Update == nil {
+				return nil, fmt.Errorf("operations[%d]: update requires the id and update fields", i)
+			}
+			op.Update = UpdateRequestToParams(*reqOp.Update)
+		case company.BatchDelete:
+			if reqOp.Id == nil {
+				return nil, fmt.Errorf("operations[%d]: delete requires the id field", i)
+			}
+		default:
+			return nil, fmt.Errorf("operations[%d]: %w %q", i, company.ErrInvalidBatchAction, reqOp.Action)
+		}
+		ops[i] = op
+	}
+	return ops, nil
+}
+
+func batchResultToResponse(index int, res company.BatchResult) oapi.CompanyBatchResult {
+	item := oapi.CompanyBatchResult{
+		Index:  index,
+		Action: oapi.CompanyBatchAction(res.Action),
+		Status: oapi.CompanyBatchStatus(res.Status),
+	}
+
+	if id, err := uuid.Parse(res.CompanyID); err == nil {
+		item.Id = &id
+	}
+	if res.Company != nil {
+		c := CompanyToResponse(res.Company)
+		item.Company = &c
+	}
+	if res.Err != nil {
+		_, body := CompanyError(res.Err)
+		item.Error = &body
+	}
+	return item
+}
@@ -0,0 +1,175 @@
+//go:build integration
+
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	deliveryHttp "github.com/dubininme/xm-assessment/internal/delivery/http"
+	"github.com/dubininme/xm-assessment/internal/delivery/http/handler"
+	"github.com/dubininme/xm-assessment/internal/delivery/http/middleware"
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/infra/auth"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres/pgtest"
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type batchFixture struct {
+	router http.Handler
+	token  string
+	outbox *postgres.OutboxRepo
+}
+
+func setupBatch(t *testing.T) batchFixture {
+	t.Helper()
+	ctx := context.Background()
+
+	db, err := postgres.Connect(ctx, testDbConfig)
+	require.NoError(t, err)
+	t.Cleanup(db.Close)
+	require.NoError(t, pgtest.Reset(ctx, db))
+
+	outboxRepo := postgres.NewOutboxRepo(db)
+	service := company.NewCompanyService(postgres.NewCompanyRepo(db), outboxRepo,
+		postgres.NewTxManager(db, testDbConfig.DBTxMaxRetries))
+
+	jwtService := auth.NewJWTService("test-secret-key-for-integration-tests")
+	router := deliveryHttp.NewRouter(handler.NewCompanyHandler(service, ""), handler.NewHealthHandler(),
+		handler.NewAuthHandler(jwtService), nil, nil, nil, handler.NewTransferHandler(ctx, service, 1<<20, 64<<10, time.Minute),
+		nil, middleware.NewAuthMiddleware(jwtService), newValidator(t))
+
+	return batchFixture{router: router, token: getAuthToken(t, router), outbox: outboxRepo}
+}
+
+func (f batchFixture) batch(t *testing.T, req oapi.CompanyBatchRequest) (int, oapi.CompanyBatchResponse) {
+	t.Helper()
+	body, err := json.Marshal(req)
+	require.NoError(t, err)
+
+	w := makeRequest(t, f.router, http.MethodPost, "/api/v1/companies:batch", f.token, body)
+	var resp oapi.CompanyBatchResponse
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	}
+	return w.Code, resp
+}
+
+func (f batchFixture) outboxEvents(t *testing.T) []string {
+	t.Helper()
+	events, err := f.outbox.List(context.Background(), postgres.OutboxFilter{}, 0, 100)
+	require.NoError(t, err)
+
+	names := make([]string, len(events))
+	for i, e := range events {
+		names[i] = e.EventType
+	}
+	return names
+}
+
+func createOp(name string) oapi.CompanyBatchOperation {
+	return oapi.CompanyBatchOperation{
+		Action: oapi.CompanyBatchActionCreate,
+		Create: &oapi.CreateCompanyRequest{Name: name, EmployeesCount: 10, Type: oapi.Corporations},
+	}
+}
+
+func TestBatch_AllOrNothingRollsBack(t *testing.T) {
+	f := setupBatch(t)
+	existing := createCompany(t, f.router, f.token, *createOp("existing").Create)
+
+	code, resp := f.batch(t, oapi.CompanyBatchRequest{Operations: []oapi.CompanyBatchOperation{
+		createOp("first"),
+		{Action: oapi.CompanyBatchActionUpdate, Id: ptr(uuid.MustParse(existing)), Update: &oapi.UpdateCompanyRequest{EmployeesCount: ptr(20)}},
+		createOp("existing"),
+		{Action: oapi.CompanyBatchActionDelete, Id: ptr(uuid.MustParse(existing))},
+	}})
+
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, oapi.CompanyBatchModeAllOrNothing, resp.Mode)
+	assert.Equal(t, 0, resp.Succeeded)
+	assert.Equal(t, 4, resp.Failed)
+
+	statuses := make([]oapi.CompanyBatchStatus, len(resp.Results))
+	for i, res := range resp.Results {
+		statuses[i] = res.Status
+	}
+	assert.Equal(t, []oapi.CompanyBatchStatus{
+		oapi.CompanyBatchStatusAborted, oapi.CompanyBatchStatusAborted, oapi.CompanyBatchStatusFailed, oapi.CompanyBatchStatusAborted,
+	}, statuses)
+	require.NotNil(t, resp.Results[2].Error)
+	assert.Equal(t, oapi.ErrorCodeConflict, resp.Results[2].Error.Code)
+
+	assert.Equal(t, 10, getCompany(t, f.router, existing).EmployeesCount, "the update was rolled back")
+	assert.Equal(t, []string{company.EventCompanyCreated}, f.outboxEvents(t), "only the setup event")
+}
+
+func TestBatch_AllOrNothingCommits(t *testing.T) {
+	f := setupBatch(t)
+	existing := createCompany(t, f.router, f.token, *createOp("existing").Create)
+
+	code, resp := f.batch(t, oapi.CompanyBatchRequest{Operations: []oapi.CompanyBatchOperation{
+		createOp("first"),
+		{Action: oapi.CompanyBatchActionDelete, Id: ptr(uuid.MustParse(existing))},
+	}})
+
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, resp.Succeeded)
+	require.NotNil(t, resp.Results[0].Company)
+	assert.Equal(t, "first", resp.Results[0].Company.Name)
+	assert.Equal(t, resp.Results[0].Company.Id, *resp.Results[0].Id)
+
+	getCompany(t, f.router, resp.Results[0].Id.String())
+	assert.Equal(t, http.StatusNotFound, makeRequest(t, f.router, http.MethodGet, "/api/v1/companies/"+existing, "", nil).Code)
+	assert.Equal(t, []string{company.EventCompanyCreated, company.EventCompanyCreated, company.EventCompanyDeleted}, f.outboxEvents(t))
+}
+
+func TestBatch_BestEffortKeepsSuccesses(t *testing.T) {
+	f := setupBatch(t)
+	mode := oapi.CompanyBatchModeBestEffort
+
+	code, resp := f.batch(t, oapi.CompanyBatchRequest{Mode: &mode, Operations: []oapi.CompanyBatchOperation{
+		createOp("first"),
+		createOp("first"),
+		{Action: oapi.CompanyBatchActionDelete, Id: ptr(uuid.New())},
+		createOp("second"),
+	}})
+
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, resp.Succeeded)
+	assert.Equal(t, 2, resp.Failed)
+	assert.Equal(t, oapi.CompanyBatchStatusSucceeded, resp.Results[0].Status)
+	assert.Equal(t, oapi.ErrorCodeConflict, resp.Results[1].Error.Code)
+	assert.Equal(t, oapi.ErrorCodeNotFound, resp.Results[2].Error.Code)
+	assert.Equal(t, oapi.CompanyBatchStatusSucceeded, resp.Results[3].Status)
+
+	assert.Equal(t, []string{company.EventCompanyCreated, company.EventCompanyCreated}, f.outboxEvents(t))
+}
+
+func TestBatch_RejectsMalformedRequests(t *testing.T) {
+	f := setupBatch(t)
+	tooMany := make([]oapi.CompanyBatchOperation, company.MaxBatchOperations+1)
+	for i := range tooMany {
+		tooMany[i] = createOp("x")
+	}
+
+	for name, req := range map[string]oapi.CompanyBatchRequest{
+		"empty":          {},
+		"too large":      {Operations: tooMany},
+		"missing id":     {Operations: []oapi.CompanyBatchOperation{{Action: oapi.CompanyBatchActionDelete}}},
+		"unknown action": {Operations: []oapi.CompanyBatchOperation{{Action: "upsert"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			code, _ := f.batch(t, req)
+			assert.Equal(t, http.StatusBadRequest, code)
+		})
+	}
+	assert.Empty(t, f.outboxEvents(t))
+}
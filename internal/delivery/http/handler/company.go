@@ -3,32 +3,37 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"time"
 
 	"github.com/dubininme/xm-assessment/internal/domain/company"
 	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
 	"github.com/google/uuid"
-	"github.com/gorilla/mux"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
 )
 
 type CompanyHandler struct {
 	service *company.CompanyService
-}
 
-func NewCompanyHandler(service *company.CompanyService) *CompanyHandler {
-	return &CompanyHandler{service: service}
+	// cacheControl is the Cache-Control of reads; empty sends none
+	cacheControl string
 }
 
-func (h *CompanyHandler) GetCompany(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-
-	if _, err := uuid.Parse(id); err != nil {
-		writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, "invalid company id")
-		return
-	}
+func NewCompanyHandler(service *company.CompanyService, cacheControl string) *CompanyHandler {
+	return &CompanyHandler{service: service, cacheControl: cacheControl}
+}
 
-	c, err := h.service.GetByID(r.Context(), id)
+// GetCompany answers with the company, or with 304 when If-None-Match or
+// If-Modified-Since shows the client has it already.
+func (h *CompanyHandler) GetCompany(w http.ResponseWriter, r *http.Request, id uuid.UUID, params oapi.GetCompanyParams) {
+	c, err := h.service.GetByID(r.Context(), id.String())
 	if err != nil {
 		if errors.Is(err, company.ErrCompanyNotFound) {
 			writeErr(w, http.StatusNotFound, oapi.ErrorCodeNotFound, "company not found")
@@ -39,7 +44,58 @@ func (h *CompanyHandler) GetCompany(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	writeJSON(w, http.StatusOK, CompanyToResponse(c))
+	h.writeCacheable(w, params.IfNoneMatch, params.IfModifiedSince, companyETag(c), lastModified(c), CompanyToResponse(c))
+}
+
+// ListCompanies answers with a page of companies in ID order. The page has
+// an ETag but no Last-Modified: a company deleted from it leaves no time
+// behind, so only the content can tell whether it changed.
+func (h *CompanyHandler) ListCompanies(w http.ResponseWriter, r *http.Request, params oapi.ListCompaniesParams) {
+	limit := defaultListLimit
+	if params.Limit != nil {
+		if *params.Limit < 1 || *params.Limit > maxListLimit {
+			writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, "limit must be between 1 and 100")
+			return
+		}
+		limit = *params.Limit
+	}
+
+	var filter company.ListFilter
+	if params.Name != nil {
+		filter.NameContains = *params.Name
+	}
+	if params.Type != nil {
+		t, err := company.NewCompanyType(string(*params.Type))
+		if err != nil {
+			writeCompanyErr(w, err)
+			return
+		}
+		filter.Type = t
+	}
+	filter.Registered = params.Registered
+
+	var afterID string
+	if params.After != nil {
+		afterID = params.After.String()
+	}
+
+	// One more than asked tells whether there is a next page
+	companies, err := h.service.ListCompanies(r.Context(), filter, afterID, limit+1)
+	if err != nil {
+		writeCompanyErr(w, err)
+		return
+	}
+
+	resp := oapi.CompanyList{Items: make([]oapi.Company, 0, min(len(companies), limit))}
+	for _, c := range companies[:min(len(companies), limit)] {
+		resp.Items = append(resp.Items, CompanyToResponse(c))
+	}
+	if len(companies) > limit {
+		next := companies[limit-1].ID()
+		resp.NextCursor = &next
+	}
+
+	h.writeCacheable(w, params.IfNoneMatch, nil, bodyETag(resp), time.Time{}, resp)
 }
 
 func (h *CompanyHandler) CreateCompany(w http.ResponseWriter, r *http.Request) {
@@ -53,92 +109,152 @@ func (h *CompanyHandler) CreateCompany(w http.ResponseWriter, r *http.Request) {
 	params := CreateRequestToParams(req)
 	c, err := h.service.CreateCompany(r.Context(), params)
 	if err != nil {
-		if errors.Is(err, company.ErrCompanyNameAlreadyExists) {
-			writeErr(w, http.StatusConflict, oapi.ErrorCodeConflict, "company name already exists")
-			return
+		writeCompanyErr(w, err)
+		return
+	}
+
+	writeCompany(w, http.StatusCreated, c)
+}
+
+func (h *CompanyHandler) UpdateCompany(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	mediaType := mediaTypeJSON
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(contentType); err != nil {
+			mediaType = ""
 		}
+	}
 
-		if errors.Is(err, company.ErrInvalidCompanyNameLength) ||
-			errors.Is(err, company.ErrInvalidCompanyDescriptionLength) ||
-			errors.Is(err, company.ErrInvalidEmployeesCount) ||
-			errors.Is(err, company.ErrInvalidCompanyType) {
-			writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, err.Error())
+	var c *company.Company
+	var err error
+	switch mediaType {
+	case mediaTypeJSON:
+		var req oapi.UpdateCompanyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, "invalid request body")
 			return
 		}
 
-		// All other errors are internal (database, kafka, etc.)
-		writeErr(w, http.StatusInternalServerError, oapi.ErrorCodeInternalError, "internal error")
+		c, err = h.service.UpdateCompany(r.Context(), id.String(), UpdateRequestToParams(req))
+	case mediaTypeMergePatch, mediaTypeJSONPatch:
+		c, err = h.patchCompany(r, id.String(), mediaType)
+	default:
+		w.Header().Set("Accept-Patch", acceptPatch)
+		writeErr(w, http.StatusUnsupportedMediaType, oapi.ErrorCodeUnsupportedMediaType, "PATCH takes "+acceptPatch)
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, CompanyToResponse(c))
-}
+	if err != nil {
+		writeUpdateErr(w, err)
+		return
+	}
 
-func (h *CompanyHandler) UpdateCompany(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
+	writeCompany(w, http.StatusOK, c)
+}
 
-	if _, err := uuid.Parse(id); err != nil {
-		writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, "invalid company id")
+// ReplaceCompany creates the company with the ID in the path, or replaces
+// all of its fields. If-Match and If-None-Match are checked against the
+// current state in the same transaction as the write.
+func (h *CompanyHandler) ReplaceCompany(w http.ResponseWriter, r *http.Request, id uuid.UUID, params oapi.ReplaceCompanyParams) {
+	var req oapi.CreateCompanyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, "invalid request body")
 		return
 	}
 
-	var req oapi.UpdateCompanyRequest
-	err := json.NewDecoder(r.Body).Decode(&req)
+	c, result, err := h.service.ReplaceCompany(r.Context(), id, CreateRequestToParams(req), func(current *company.Company) error {
+		return checkPreconditions(params.IfMatch, params.IfNoneMatch, current)
+	})
 	if err != nil {
-		writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, "invalid request body")
+		writeUpdateErr(w, err)
 		return
 	}
 
-	params := UpdateRequestToParams(req)
-	c, err := h.service.UpdateCompany(r.Context(), id, params)
+	if result == company.UpsertCreated {
+		w.Header().Set("Location", "/api/v1/companies/"+id.String())
+		writeCompany(w, http.StatusCreated, c)
+		return
+	}
+	writeCompany(w, http.StatusOK, c)
+}
+
+// patchCompany applies a merge patch or a JSON patch. Both go through
+// PatchCompany so that an empty patch, or one made only of tests, returns the
+// company unchanged instead of failing for lack of fields.
+func (h *CompanyHandler) patchCompany(r *http.Request, id, mediaType string) (*company.Company, error) {
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		if errors.Is(err, company.ErrCompanyNotFound) {
-			writeErr(w, http.StatusNotFound, oapi.ErrorCodeNotFound, "company not found")
-			return
-		}
+		return nil, fmt.Errorf("%w: failed to read request body", errInvalidPatch)
+	}
 
-		if errors.Is(err, company.ErrCompanyNameAlreadyExists) {
-			writeErr(w, http.StatusConflict, oapi.ErrorCodeConflict, "company name already exists")
-			return
+	if mediaType == mediaTypeMergePatch {
+		params, err := mergePatchToParams(body)
+		if err != nil {
+			return nil, err
 		}
+		return h.service.PatchCompany(r.Context(), id, func(*company.Company) (company.UpdateParams, error) {
+			return params, nil
+		})
+	}
 
-		if errors.Is(err, company.ErrInvalidCompanyNameLength) ||
-			errors.Is(err, company.ErrInvalidCompanyDescriptionLength) ||
-			errors.Is(err, company.ErrInvalidEmployeesCount) ||
-			errors.Is(err, company.ErrInvalidCompanyType) ||
-			errors.Is(err, company.ErrNoFieldsToUpdate) {
-			writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, err.Error())
-			return
-		}
+	ops, err := decodeJSONPatch(body)
+	if err != nil {
+		return nil, err
+	}
+	return h.service.PatchCompany(r.Context(), id, func(current *company.Company) (company.UpdateParams, error) {
+		return applyJSONPatch(current, ops)
+	})
+}
 
-		// All other errors are internal (database, kafka, etc.)
-		writeErr(w, http.StatusInternalServerError, oapi.ErrorCodeInternalError, "internal error")
+func (h *CompanyHandler) DeleteCompany(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	err := h.service.DeleteCompany(r.Context(), id.String())
+	if err != nil {
+		writeCompanyErr(w, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, CompanyToResponse(c))
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *CompanyHandler) DeleteCompany(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-
-	if _, err := uuid.Parse(id); err != nil {
-		writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, "invalid company id")
-		return
+// CompanyError maps an error of the company service to a status and body;
+// the GraphQL resolvers answer with the same codes.
+func CompanyError(err error) (int, oapi.Error) {
+	switch {
+	case errors.Is(err, company.ErrCompanyNotFound):
+		return http.StatusNotFound, oapi.Error{Code: oapi.ErrorCodeNotFound, Message: "company not found"}
+	case errors.Is(err, company.ErrCompanyNameAlreadyExists):
+		return http.StatusConflict, oapi.Error{Code: oapi.ErrorCodeConflict, Message: "company name already exists"}
+	case errors.Is(err, company.ErrCompanyAlreadyExists):
+		return http.StatusConflict, oapi.Error{Code: oapi.ErrorCodeConflict, Message: "company already exists"}
+	case errors.Is(err, company.ErrInvalidCompanyNameLength),
+		errors.Is(err, company.ErrInvalidCompanyDescriptionLength),
+		errors.Is(err, company.ErrInvalidEmployeesCount),
+		errors.Is(err, company.ErrInvalidCompanyType),
+		errors.Is(err, company.ErrNoFieldsToUpdate),
+		errors.Is(err, company.ErrInvalidImportRow):
+		return http.StatusBadRequest, oapi.Error{Code: oapi.ErrorCodeBadRequest, Message: err.Error()}
+	default:
+		// All other errors are internal (database, kafka, etc.)
+		return http.StatusInternalServerError, oapi.Error{Code: oapi.ErrorCodeInternalError, Message: "internal error"}
 	}
+}
 
-	err := h.service.DeleteCompany(r.Context(), id)
-	if err != nil {
-		if errors.Is(err, company.ErrCompanyNotFound) {
-			writeErr(w, http.StatusNotFound, oapi.ErrorCodeNotFound, "company not found")
-			return
-		}
-
-		writeErr(w, http.StatusInternalServerError, oapi.ErrorCodeInternalError, "internal error")
-		return
+// writeUpdateErr adds the errors of patches and preconditions to those of
+// the company service.
+func writeUpdateErr(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errPreconditionFailed):
+		writeErr(w, http.StatusPreconditionFailed, oapi.ErrorCodePreconditionFailed, "the company does not match If-Match or If-None-Match")
+	case errors.Is(err, errPatchTestFailed):
+		writeErr(w, http.StatusConflict, oapi.ErrorCodeConflict, err.Error())
+	case errors.Is(err, errInvalidPatch):
+		writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, err.Error())
+	default:
+		writeCompanyErr(w, err)
 	}
+}
 
-	w.WriteHeader(http.StatusNoContent)
+func writeCompanyErr(w http.ResponseWriter, err error) {
+	status, body := CompanyError(err)
+	writeJSON(w, status, body)
 }
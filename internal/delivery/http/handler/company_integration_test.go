@@ -9,23 +9,68 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/dubininme/xm-assessment/api"
 	"github.com/dubininme/xm-assessment/internal/config"
 	deliveryHttp "github.com/dubininme/xm-assessment/internal/delivery/http"
 	"github.com/dubininme/xm-assessment/internal/delivery/http/handler"
 	"github.com/dubininme/xm-assessment/internal/delivery/http/middleware"
 	"github.com/dubininme/xm-assessment/internal/domain/company"
 	"github.com/dubininme/xm-assessment/internal/infra/auth"
+	"github.com/dubininme/xm-assessment/internal/infra/outbox"
 	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres/pgtest"
+	"github.com/dubininme/xm-assessment/internal/infra/schemaregistry"
 	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
 	"github.com/dubininme/xm-assessment/pkg/logger"
+	kafkago "github.com/segmentio/kafka-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-// Integration test that requires a running PostgreSQL database
-// Run with: docker-compose up -d postgres && go test -v ./internal/delivery/http/handler/...
+var testDbConfig config.DbConfig
+
+// TestMain starts a throwaway Postgres (see pgtest) shared by the integration tests.
+func TestMain(m *testing.M) {
+	cfg, stop, err := pgtest.Start(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to start test database:", err)
+		os.Exit(1)
+	}
+	testDbConfig = cfg
+
+	code := m.Run()
+	if err := stop(); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to stop test database:", err)
+	}
+	os.Exit(code)
+}
+
+// fakeProducer stands in for Kafka and records every published message.
+type fakeProducer struct {
+	mu       sync.Mutex
+	messages []kafkago.Message
+}
+
+func (p *fakeProducer) PublishBatch(_ context.Context, messages []kafkago.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, messages...)
+	return nil
+}
+
+func (p *fakeProducer) Close() error { return nil }
+
+func (p *fakeProducer) Messages() []kafkago.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]kafkago.Message(nil), p.messages...)
+}
+
 func TestCompanyLifecycle_Integration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test in short mode")
@@ -34,38 +79,33 @@ func TestCompanyLifecycle_Integration(t *testing.T) {
 	ctx := context.Background()
 	ctx = logger.WithLogger(ctx, logger.NewTestLogger())
 
-	// Setup database connection (using docker-compose postgres)
-	cfg := &config.DbConfig{
-		DBHost:            "localhost",
-		DBPort:            "5432",
-		DBUser:            "xm_user",
-		DBPassword:        "xm_password",
-		DBName:            "xm_db",
-		DBMaxOpenConns:    10,
-		DBMaxIdleConns:    5,
-		DBConnMaxLifetime: 300,
-	}
-
-	db, err := postgres.Connect(ctx, *cfg)
-	require.NoError(t, err, "Failed to connect to test database. Make sure docker-compose is running.")
-	defer func() { _ = db.Close() }()
+	db, err := postgres.Connect(ctx, testDbConfig)
+	require.NoError(t, err)
+	defer db.Close()
 
-	_, _ = db.ExecContext(ctx, "DELETE FROM companies WHERE name LIKE 'IntegrationTest%'")
+	require.NoError(t, pgtest.Reset(ctx, db))
 
 	companyRepo := postgres.NewCompanyRepo(db)
 	outboxRepo := postgres.NewOutboxRepo(db)
-	txManager := postgres.NewTxManager(db)
+	txManager := postgres.NewTxManager(db, testDbConfig.DBTxMaxRetries)
 	dbChecker := postgres.NewDBHealthChecker(db)
 
+	producer := &fakeProducer{}
+	serializer, err := schemaregistry.NewSerializer(ctx, schemaregistry.FormatJSON, nil, "company-events")
+	require.NoError(t, err)
+	processor := outbox.NewProcessor(outboxRepo, []outbox.Sink{outbox.NewKafkaSink(producer, serializer, time.Second)}, txManager, 100, time.Second, outbox.Partitioning{})
+
 	companyService := company.NewCompanyService(companyRepo, outboxRepo, txManager)
-	companyHandler := handler.NewCompanyHandler(companyService)
+	companyHandler := handler.NewCompanyHandler(companyService, "")
 	healthHandler := handler.NewHealthHandler(dbChecker)
 
 	jwtService := auth.NewJWTService("test-secret-key-for-integration-tests")
 	authHandler := handler.NewAuthHandler(jwtService)
 	authMiddleware := middleware.NewAuthMiddleware(jwtService)
 
-	router := deliveryHttp.NewRouter(companyHandler, healthHandler, authHandler, authMiddleware)
+	transferHandler := handler.NewTransferHandler(ctx, companyService, 1<<20, 64<<10, time.Minute)
+
+	router := deliveryHttp.NewRouter(companyHandler, healthHandler, authHandler, nil, nil, nil, transferHandler, nil, authMiddleware, newValidator(t))
 
 	token := getAuthToken(t, router)
 
@@ -91,6 +131,19 @@ func TestCompanyLifecycle_Integration(t *testing.T) {
 	assert.Equal(t, "Test company for integration testing", *fetchedCompany.Description)
 	t.Log("Company fetched successfully")
 
+	t.Log("Test 2a: Revalidating company...")
+	resp := makeRequest(t, router, "GET", fmt.Sprintf("/api/v1/companies/%s", companyID), "", nil)
+	lastModified := resp.Header().Get("Last-Modified")
+	_, err = http.ParseTime(lastModified)
+	require.NoError(t, err, "Last-Modified comes from updated_at")
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/companies/%s", companyID), nil)
+	req.Header.Set("If-Modified-Since", lastModified)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	t.Log("Unchanged company answered with 304")
+
 	t.Log("Test 3: Updating company...")
 	updateReq := oapi.UpdateCompanyRequest{
 		EmployeesCount: ptr(250),
@@ -108,7 +161,7 @@ func TestCompanyLifecycle_Integration(t *testing.T) {
 	deleteCompany(t, router, token, companyID)
 	t.Log("Company deleted successfully")
 
-	resp := makeRequest(t, router, "GET", fmt.Sprintf("/api/v1/companies/%s", companyID), "", nil)
+	resp = makeRequest(t, router, "GET", fmt.Sprintf("/api/v1/companies/%s", companyID), "", nil)
 	assert.Equal(t, http.StatusNotFound, resp.Code)
 	t.Log("Verified company is deleted (404)")
 
@@ -116,6 +169,69 @@ func TestCompanyLifecycle_Integration(t *testing.T) {
 	resp = makeRequest(t, router, "DELETE", fmt.Sprintf("/api/v1/companies/%s", companyID), token, nil)
 	assert.Equal(t, http.StatusNotFound, resp.Code)
 	t.Log("Double delete correctly returns 404")
+
+	t.Log("Test 6: Checking outbox rows...")
+	rows, err := db.Query(ctx, `SELECT event_type, is_processed FROM outbox WHERE aggregate_id = $1 ORDER BY id`, companyID)
+	require.NoError(t, err)
+	var outboxTypes []string
+	for rows.Next() {
+		var eventType string
+		var processed bool
+		require.NoError(t, rows.Scan(&eventType, &processed))
+		assert.False(t, processed)
+		outboxTypes = append(outboxTypes, eventType)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"CompanyCreated", "CompanyUpdated", "CompanyDeleted"}, outboxTypes)
+
+	t.Log("Test 7: Running outbox processor...")
+	require.NoError(t, processor.ProcessBatch(ctx))
+
+	messages := producer.Messages()
+	require.Len(t, messages, 3)
+	for i, msg := range messages {
+		assert.Equal(t, companyID, string(msg.Key), "messages are keyed by company ID")
+		assert.Equal(t, outboxTypes[i], header(msg, "event_name"))
+		assert.NotEmpty(t, header(msg, "outbox_id"))
+		assert.Equal(t, "application/json", header(msg, "content_type"))
+	}
+
+	var created struct {
+		CompanyID string `json:"company_id"`
+		Name      string `json:"name"`
+	}
+	require.NoError(t, json.Unmarshal(messages[0].Value, &created))
+	assert.Equal(t, companyID, created.CompanyID)
+	assert.Equal(t, "IntegrationTest", created.Name)
+
+	var unprocessed int
+	require.NoError(t, db.QueryRow(ctx, `SELECT count(*) FROM outbox WHERE is_processed = false`).Scan(&unprocessed))
+	assert.Zero(t, unprocessed)
+
+	require.NoError(t, processor.ProcessBatch(ctx))
+	assert.Len(t, producer.Messages(), 3, "processed events are not published twice")
+	t.Log("Events delivered with correct key and headers")
+}
+
+func header(msg kafkago.Message, key string) string {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// newValidator checks responses against the spec too, so that the
+// integration tests catch handlers drifting from it.
+func newValidator(t *testing.T) *middleware.Validator {
+	t.Helper()
+	validator, err := middleware.NewValidator(api.Spec, middleware.ValidatorOptions{
+		MaxBodyBytes:      4 << 20,
+		ValidateResponses: true,
+	})
+	require.NoError(t, err)
+	return validator
 }
 
 func getAuthToken(t *testing.T, router http.Handler) string {
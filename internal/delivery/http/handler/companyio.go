@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+)
+
+const (
+	mediaTypeCSV    = "text/csv"
+	mediaTypeNDJSON = "application/x-ndjson"
+
+	// maxNDJSONLine fits a company with the longest description, escaped
+	maxNDJSONLine = 64 << 10
+)
+
+// csvColumns is the header of an export; imports need every column but id
+// and description.
+var csvColumns = []string{"id", "name", "description", "employees_count", "registered", "type"}
+
+// exportMediaType picks the first media type of an Accept header that an
+// export can be written as; CSV when the client takes anything.
+func exportMediaType(accept string) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return mediaTypeCSV, true
+	}
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+			continue
+		}
+
+		switch mediaType {
+		case mediaTypeCSV, "text/*", "*/*":
+			return mediaTypeCSV, true
+		case mediaTypeNDJSON, "application/ndjson", "application/*":
+			return mediaTypeNDJSON, true
+		}
+	}
+	return "", false
+}
+
+// importMediaType reads the format of an upload from its Content-Type.
+func importMediaType(contentType string) (string, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", false
+	}
+
+	switch mediaType {
+	case mediaTypeCSV:
+		return mediaTypeCSV, true
+	case mediaTypeNDJSON, "application/ndjson":
+		return mediaTypeNDJSON, true
+	default:
+		return "", false
+	}
+}
+
+// companyWriter encodes companies of an export; Flush writes out what is
+// buffered.
+type companyWriter interface {
+	Write(c *company.Company) error
+	Flush() error
+}
+
+func newCompanyWriter(mediaType string, w io.Writer) (companyWriter, error) {
+	if mediaType == mediaTypeNDJSON {
+		bw := bufio.NewWriter(w)
+		return &ndjsonCompanyWriter{w: bw, enc: json.NewEncoder(bw)}, nil
+	}
+
+	cw := &csvCompanyWriter{w: csv.NewWriter(w)}
+	if err := cw.w.Write(csvColumns); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+type csvCompanyWriter struct {
+	w *csv.Writer
+}
+
+func (cw *csvCompanyWriter) Write(c *company.Company) error {
+	return cw.w.Write([]string{
+		c.ID().String(),
+		c.Name().String(),
+		c.Description().String(),
+		strconv.Itoa(c.EmployeesCount().Int()),
+		strconv.FormatBool(c.IsRegistered()),
+		c.CompanyType().String(),
+	})
+}
+
+func (cw *csvCompanyWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+type ndjsonCompanyWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (nw *ndjsonCompanyWriter) Write(c *company.Company) error {
+	return nw.enc.Encode(CompanyToResponse(c))
+}
+
+func (nw *ndjsonCompanyWriter) Flush() error {
+	return nw.w.Flush()
+}
+
+// newImportSource reads an upload; a CSV header that lacks a required column
+// is rejected here, before any row is imported.
+func newImportSource(mediaType string, r io.Reader) (company.ImportSource, error) {
+	if mediaType == mediaTypeNDJSON {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 4096), maxNDJSONLine)
+		return &ndjsonImportSource{scanner: scanner}, nil
+	}
+
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("csv header row is missing")
+		}
+		return nil, fmt.Errorf("invalid csv header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			// Spreadsheets tend to save CSV with a byte order mark
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"name", "employees_count", "registered", "type"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("csv header is missing column %q", name)
+		}
+	}
+
+	return &csvImportSource{r: cr, columns: columns}, nil
+}
+
+type csvImportSource struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func (s *csvImportSource) Next() (int, company.CreateParams, error) {
+	record, err := s.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return parseErr.StartLine, company.CreateParams{}, fmt.Errorf("%w: %v", company.ErrInvalidImportRow, parseErr.Err)
+		}
+		return 0, company.CreateParams{}, err
+	}
+	line, _ := s.r.FieldPos(0)
+
+	field := func(name string) string {
+		if i, ok := s.columns[name]; ok {
+			return record[i]
+		}
+		return ""
+	}
+
+	params := company.CreateParams{
+		Name:        field("name"),
+		Description: field("description"),
+		Type:        field("type"),
+	}
+
+	params.EmployeesCount, err = strconv.Atoi(strings.TrimSpace(field("employees_count")))
+	if err != nil {
+		return line, params, fmt.Errorf("%w: employees_count must be an integer", company.ErrInvalidImportRow)
+	}
+	params.Registered, err = strconv.ParseBool(strings.TrimSpace(field("registered")))
+	if err != nil {
+		return line, params, fmt.Errorf("%w: registered must be true or false", company.ErrInvalidImportRow)
+	}
+	return line, params, nil
+}
+
+type ndjsonImportSource struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (s *ndjsonImportSource) Next() (int, company.CreateParams, error) {
+	for s.scanner.Scan() {
+		s.line++
+		raw := s.scanner.Bytes()
+		if len(strings.TrimSpace(string(raw))) == 0 {
+			continue
+		}
+
+		var req oapi.CreateCompanyRequest
+		if err := json.Unmarshal(raw, &req); err != nil {
+			return s.line, company.CreateParams{}, fmt.Errorf("%w: invalid JSON", company.ErrInvalidImportRow)
+		}
+		return s.line, CreateRequestToParams(req), nil
+	}
+
+	if err := s.scanner.Err(); err != nil {
+		return s.line + 1, company.CreateParams{}, err
+	}
+	return s.line, company.CreateParams{}, io.EOF
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+	"path"
+
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// DocsAssets are the Swagger UI files the docs page loads from under /docs/.
+// They are embedded from the swagger-ui-dist release pinned in go.sum, so no
+// script on the page comes from another origin.
+var DocsAssets = []string{"swagger-ui.css", "swagger-ui-bundle.js"}
+
+// docsPolicy only lets the page run its own scripts; Swagger UI needs inline
+// styles and data: images.
+const docsPolicy = "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; img-src 'self' data:"
+
+// docsPage renders /openapi.yaml with Swagger UI.
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>xm-assessment API</title>
+  <link rel="stylesheet" href="/docs/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="/docs/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: "/openapi.yaml", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
+
+// DocsHandler serves the OpenAPI spec and a page to browse it.
+type DocsHandler struct {
+	spec []byte
+}
+
+func NewDocsHandler(spec []byte) *DocsHandler {
+	return &DocsHandler{spec: spec}
+}
+
+func (h *DocsHandler) Spec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	_, _ = w.Write(h.spec)
+}
+
+func (h *DocsHandler) UI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", docsPolicy)
+	_, _ = w.Write([]byte(docsPage))
+}
+
+// Asset serves one of DocsAssets, named by the last element of the path.
+func (h *DocsHandler) Asset(w http.ResponseWriter, r *http.Request) {
+	http.ServeFileFS(w, r, swaggerFiles.FS, path.Base(r.URL.Path))
+}
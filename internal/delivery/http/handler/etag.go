@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+)
+
+// errPreconditionFailed is an If-Match or If-None-Match that does not hold
+// for the current state.
+var errPreconditionFailed = errors.New("precondition failed")
+
+// companyETag is a strong validator of a company as served: it changes
+// whenever any of its fields does.
+func companyETag(c *company.Company) string {
+	return bodyETag(CompanyToResponse(c))
+}
+
+// bodyETag is a strong validator of any JSON response body.
+func bodyETag(body any) string {
+	raw, _ := json.Marshal(body)
+	sum := sha256.Sum256(raw)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// lastModified is the Last-Modified of c, zero when it was never stamped.
+// HTTP dates have whole seconds, so the time is truncated to compare it with
+// If-Modified-Since.
+func lastModified(c *company.Company) time.Time {
+	return c.UpdatedAt().Truncate(time.Second)
+}
+
+// notModified reports whether a GET can be answered with 304. As RFC 9110
+// has it, If-None-Match decides when it is sent and If-Modified-Since is
+// only looked at otherwise; an unparsable date is ignored.
+func notModified(ifNoneMatch, ifModifiedSince *string, etag string, modified time.Time) bool {
+	if ifNoneMatch != nil && *ifNoneMatch != "" {
+		return etagListMatches(*ifNoneMatch, etag, true)
+	}
+	if ifModifiedSince == nil || modified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(*ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	return !modified.After(since)
+}
+
+// checkPreconditions evaluates If-Match and If-None-Match of a write against
+// the current state, nil when the company does not exist.
+func checkPreconditions(ifMatch, ifNoneMatch *string, current *company.Company) error {
+	etag := ""
+	if current != nil {
+		etag = companyETag(current)
+	}
+
+	if ifMatch != nil && *ifMatch != "" {
+		if current == nil || !etagListMatches(*ifMatch, etag, false) {
+			return errPreconditionFailed
+		}
+	}
+
+	if ifNoneMatch != nil && *ifNoneMatch != "" {
+		if current != nil && etagListMatches(*ifNoneMatch, etag, true) {
+			return errPreconditionFailed
+		}
+	}
+	return nil
+}
+
+// etagListMatches reports whether a comma-separated list of entity tags, or
+// "*", matches etag. If-Match compares strongly, so weak tags never match
+// it; If-None-Match compares weakly.
+func etagListMatches(list, etag string, weak bool) bool {
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCacheable answers a read with body and its validators, or with 304
+// and the validators alone when the client's copy is current. modified is
+// zero for responses without a Last-Modified.
+func (h *CompanyHandler) writeCacheable(w http.ResponseWriter, ifNoneMatch, ifModifiedSince *string, etag string, modified time.Time, body any) {
+	w.Header().Set("ETag", etag)
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if h.cacheControl != "" {
+		w.Header().Set("Cache-Control", h.cacheControl)
+	}
+
+	if notModified(ifNoneMatch, ifModifiedSince, etag, modified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// writeCompany writes c with its ETag.
+func writeCompany(w http.ResponseWriter, status int, c *company.Company) {
+	w.Header().Set("ETag", companyETag(c))
+	writeJSON(w, status, CompanyToResponse(c))
+}
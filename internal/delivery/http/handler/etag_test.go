@@ -0,0 +1,217 @@
+//go:build unit
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/infra/memory"
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCacheControl = "public, no-cache"
+
+func newCacheFixture(t *testing.T, names ...string) (*CompanyHandler, *company.CompanyService, []string) {
+	t.Helper()
+	store := memory.NewStore()
+	service := company.NewCompanyService(memory.NewCompanyRepo(store), memory.NewEventsPublisher(store), memory.NewTxManager(store))
+
+	var ids []string
+	for i, name := range names {
+		c, err := service.CreateCompany(context.Background(), company.CreateParams{
+			Name: name, EmployeesCount: i + 1, Registered: i%2 == 0, Type: "Corporations",
+		})
+		require.NoError(t, err)
+		ids = append(ids, c.ID().String())
+	}
+	return NewCompanyHandler(service, testCacheControl), service, ids
+}
+
+func getCompany(h *CompanyHandler, id string, params oapi.GetCompanyParams) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.GetCompany(w, httptest.NewRequest(http.MethodGet, "/api/v1/companies/"+id, nil), uuid.MustParse(id), params)
+	return w
+}
+
+func TestGetCompany_CachingHeaders(t *testing.T) {
+	h, _, ids := newCacheFixture(t, "Acme")
+
+	w := getCompany(h, ids[0], oapi.GetCompanyParams{})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+	assert.Equal(t, testCacheControl, w.Header().Get("Cache-Control"))
+
+	modified, err := http.ParseTime(w.Header().Get("Last-Modified"))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), modified, time.Minute)
+
+	h.cacheControl = ""
+	w = getCompany(h, ids[0], oapi.GetCompanyParams{})
+	assert.Empty(t, w.Header().Values("Cache-Control"), "an empty setting sends none")
+}
+
+func TestGetCompany_Conditional(t *testing.T) {
+	h, _, ids := newCacheFixture(t, "Acme")
+
+	first := getCompany(h, ids[0], oapi.GetCompanyParams{})
+	require.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	lastModified := first.Header().Get("Last-Modified")
+	modified, err := http.ParseTime(lastModified)
+	require.NoError(t, err)
+
+	str := func(s string) *string { return &s }
+	earlier := modified.Add(-time.Second).Format(http.TimeFormat)
+
+	tests := []struct {
+		name   string
+		params oapi.GetCompanyParams
+		status int
+	}{
+		{"matching etag", oapi.GetCompanyParams{IfNoneMatch: str(etag)}, http.StatusNotModified},
+		{"weak etag", oapi.GetCompanyParams{IfNoneMatch: str("W/" + etag)}, http.StatusNotModified},
+		{"etag in a list", oapi.GetCompanyParams{IfNoneMatch: str(`"other", ` + etag)}, http.StatusNotModified},
+		{"any", oapi.GetCompanyParams{IfNoneMatch: str("*")}, http.StatusNotModified},
+		{"stale etag", oapi.GetCompanyParams{IfNoneMatch: str(`"stale"`)}, http.StatusOK},
+		{"not modified since", oapi.GetCompanyParams{IfModifiedSince: str(lastModified)}, http.StatusNotModified},
+		{"modified since", oapi.GetCompanyParams{IfModifiedSince: str(earlier)}, http.StatusOK},
+		{"invalid date", oapi.GetCompanyParams{IfModifiedSince: str("yesterday")}, http.StatusOK},
+		{"etag takes precedence", oapi.GetCompanyParams{IfNoneMatch: str(`"stale"`), IfModifiedSince: str(lastModified)}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := getCompany(h, ids[0], tt.params)
+			require.Equal(t, tt.status, w.Code)
+			assert.Equal(t, etag, w.Header().Get("ETag"))
+			assert.Equal(t, lastModified, w.Header().Get("Last-Modified"))
+			assert.Equal(t, testCacheControl, w.Header().Get("Cache-Control"))
+			if tt.status == http.StatusNotModified {
+				assert.Empty(t, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetCompany_ConditionalAfterUpdate(t *testing.T) {
+	h, service, ids := newCacheFixture(t, "Acme")
+	etag := getCompany(h, ids[0], oapi.GetCompanyParams{}).Header().Get("ETag")
+
+	count := 99
+	_, err := service.UpdateCompany(context.Background(), ids[0], company.UpdateParams{EmployeesCount: &count})
+	require.NoError(t, err)
+
+	w := getCompany(h, ids[0], oapi.GetCompanyParams{IfNoneMatch: &etag})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	assert.Equal(t, 99, decodeCompany(t, w).EmployeesCount)
+}
+
+func listCompanies(t *testing.T, h *CompanyHandler, params oapi.ListCompaniesParams) (*httptest.ResponseRecorder, oapi.CompanyList) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ListCompanies(w, httptest.NewRequest(http.MethodGet, "/api/v1/companies", nil), params)
+
+	var list oapi.CompanyList
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+	}
+	return w, list
+}
+
+func TestListCompanies_Pages(t *testing.T) {
+	var names []string
+	for i := range 5 {
+		names = append(names, fmt.Sprintf("Co %d", i))
+	}
+	h, _, _ := newCacheFixture(t, names...)
+
+	limit := 2
+	params := oapi.ListCompaniesParams{Limit: &limit}
+	var seen []uuid.UUID
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 3)
+		w, list := listCompanies(t, h, params)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.LessOrEqual(t, len(list.Items), limit)
+		for _, c := range list.Items {
+			seen = append(seen, c.Id)
+		}
+		if list.NextCursor == nil {
+			break
+		}
+		params.After = list.NextCursor
+	}
+	assert.Len(t, seen, 5)
+	assert.IsIncreasing(t, func() []string {
+		ids := make([]string, len(seen))
+		for i, id := range seen {
+			ids[i] = id.String()
+		}
+		return ids
+	}())
+}
+
+func TestListCompanies_Filters(t *testing.T) {
+	h, _, _ := newCacheFixture(t, "Acme", "Globex", "Acme Labs")
+
+	name := "acme"
+	registered := true
+	w, list := listCompanies(t, h, oapi.ListCompaniesParams{Name: &name, Registered: &registered})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, list.Items, 2)
+	for _, c := range list.Items {
+		assert.Contains(t, []string{"Acme", "Acme Labs"}, c.Name)
+	}
+
+	nonProfit := oapi.CompanyType(company.NonProfitType)
+	w, list = listCompanies(t, h, oapi.ListCompaniesParams{Type: &nonProfit})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, list.Items)
+	assert.Nil(t, list.NextCursor)
+}
+
+func TestListCompanies_Invalid(t *testing.T) {
+	h, _, _ := newCacheFixture(t)
+
+	limit := 101
+	w, _ := listCompanies(t, h, oapi.ListCompaniesParams{Limit: &limit})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	guild := oapi.CompanyType("Guild")
+	w, _ = listCompanies(t, h, oapi.ListCompaniesParams{Type: &guild})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestListCompanies_Conditional(t *testing.T) {
+	h, service, ids := newCacheFixture(t, "Acme", "Globex")
+
+	w, _ := listCompanies(t, h, oapi.ListCompaniesParams{})
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Equal(t, testCacheControl, w.Header().Get("Cache-Control"))
+	assert.Empty(t, w.Header().Get("Last-Modified"), "deletions leave no time to serve")
+
+	w, _ = listCompanies(t, h, oapi.ListCompaniesParams{IfNoneMatch: &etag})
+	require.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+	assert.Empty(t, w.Body.String())
+
+	require.NoError(t, service.DeleteCompany(context.Background(), ids[1]))
+
+	w, list := listCompanies(t, h, oapi.ListCompaniesParams{IfNoneMatch: &etag})
+	require.Equal(t, http.StatusOK, w.Code, "a deletion changes the page")
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	assert.Len(t, list.Items, 1)
+}
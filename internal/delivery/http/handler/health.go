@@ -4,6 +4,8 @@ import (
 	"context"
 	"net/http"
 	"time"
+
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
 )
 
 type HealthHandler struct {
@@ -14,17 +16,37 @@ func NewHealthHandler(checkers ...HealthChecker) *HealthHandler {
 	return &HealthHandler{checkers: checkers}
 }
 
-func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
+func (h *HealthHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
 	defer cancel()
 
+	resp := oapi.HealthResponse{
+		Status: oapi.HealthStatusOk,
+		Checks: make(map[string]oapi.HealthCheck, len(h.checkers)),
+	}
+
 	for _, checker := range h.checkers {
-		err := checker.Check(ctx)
-		if err != nil {
-			w.WriteHeader(http.StatusServiceUnavailable)
-			return
+		check := oapi.HealthCheck{Status: oapi.HealthStatusOk}
+
+		if err := checker.Check(ctx); err != nil {
+			msg := err.Error()
+			check.Status = oapi.HealthStatusUnavailable
+			check.Error = &msg
+			resp.Status = oapi.HealthStatusUnavailable
 		}
+
+		if reporter, ok := checker.(StatsReporter); ok {
+			stats := reporter.Stats()
+			check.Stats = &stats
+		}
+
+		resp.Checks[checker.Name()] = check
+	}
+
+	status := http.StatusOK
+	if resp.Status != oapi.HealthStatusOk {
+		status = http.StatusServiceUnavailable
 	}
 
-	w.WriteHeader(http.StatusOK)
+	writeJSON(w, status, resp)
 }
@@ -0,0 +1,62 @@
+//go:build unit
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubChecker struct {
+	name  string
+	err   error
+	stats map[string]any
+}
+
+func (c stubChecker) Check(context.Context) error { return c.err }
+func (c stubChecker) Name() string                { return c.name }
+
+type stubStatsChecker struct{ stubChecker }
+
+func (c stubStatsChecker) Stats() map[string]any { return c.stats }
+
+func TestHealth_AllHealthyWithStats(t *testing.T) {
+	h := NewHealthHandler(stubStatsChecker{stubChecker{name: "postgres", stats: map[string]any{"total_conns": 3}}})
+
+	w := httptest.NewRecorder()
+	h.HealthCheck(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp oapi.HealthResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, oapi.HealthStatusOk, resp.Status)
+	require.NotNil(t, resp.Checks["postgres"].Stats)
+	assert.EqualValues(t, 3, (*resp.Checks["postgres"].Stats)["total_conns"])
+}
+
+func TestHealth_Unavailable(t *testing.T) {
+	h := NewHealthHandler(
+		stubChecker{name: "postgres", err: errors.New("connection refused")},
+		stubChecker{name: "other"},
+	)
+
+	w := httptest.NewRecorder()
+	h.HealthCheck(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var resp oapi.HealthResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, oapi.HealthStatusUnavailable, resp.Status)
+	assert.Equal(t, oapi.HealthStatusUnavailable, resp.Checks["postgres"].Status)
+	assert.Equal(t, oapi.HealthStatusOk, resp.Checks["other"].Status)
+}
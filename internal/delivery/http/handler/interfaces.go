@@ -6,3 +6,9 @@ type HealthChecker interface {
 	Check(ctx context.Context) error
 	Name() string
 }
+
+// StatsReporter is optionally implemented by a HealthChecker to expose
+// runtime stats (e.g. connection pool usage) on the health endpoint.
+type StatsReporter interface {
+	Stats() map[string]any
+}
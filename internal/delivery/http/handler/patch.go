@@ -0,0 +1,308 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+)
+
+const (
+	mediaTypeJSON       = "application/json"
+	mediaTypeMergePatch = "application/merge-patch+json"
+	mediaTypeJSONPatch  = "application/json-patch+json"
+)
+
+// acceptPatch lists the media types PATCH /companies/{id} takes
+var acceptPatch = strings.Join([]string{mediaTypeJSON, mediaTypeMergePatch, mediaTypeJSONPatch}, ", ")
+
+// errInvalidPatch is a patch document that cannot be applied to a company at
+// all; errPatchTestFailed is a JSON Patch whose test does not hold for the
+// current state.
+var errInvalidPatch = errors.New("invalid patch")
+var errPatchTestFailed = errors.New("patch test failed")
+
+// mergePatchToParams reads an RFC 7396 merge patch. A null description clears
+// it; the other fields are required, so they cannot be removed.
+func mergePatchToParams(body []byte) (company.UpdateParams, error) {
+	var params company.UpdateParams
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(body, &doc); err != nil || doc == nil {
+		return params, fmt.Errorf("%w: a merge patch must be a JSON object", errInvalidPatch)
+	}
+
+	for member, raw := range doc {
+		if isJSONNull(raw) {
+			if member != "description" {
+				return params, removedFieldError(member)
+			}
+			params.Description = new(string)
+			continue
+		}
+
+		var err error
+		switch member {
+		case "name":
+			params.Name, err = decodeMember[string](member, raw)
+		case "description":
+			params.Description, err = decodeMember[string](member, raw)
+		case "employees_count":
+			params.EmployeesCount, err = decodeMember[int](member, raw)
+		case "registered":
+			params.Registered, err = decodeMember[bool](member, raw)
+		case "type":
+			params.Type, err = decodeMember[string](member, raw)
+		case "id":
+			err = fmt.Errorf("%w: id cannot be changed", errInvalidPatch)
+		default:
+			err = fmt.Errorf("%w: unknown member %q", errInvalidPatch, member)
+		}
+		if err != nil {
+			return params, err
+		}
+	}
+	return params, nil
+}
+
+func isJSONNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+func decodeMember[T any](member string, raw json.RawMessage) (*T, error) {
+	v := new(T)
+	if err := json.Unmarshal(raw, v); err != nil {
+		return nil, fmt.Errorf("%w: invalid %s", errInvalidPatch, member)
+	}
+	return v, nil
+}
+
+func removedFieldError(member string) error {
+	switch member {
+	case "name", "employees_count", "registered", "type":
+		return fmt.Errorf("%w: %s cannot be removed", errInvalidPatch, member)
+	case "id":
+		return fmt.Errorf("%w: id cannot be changed", errInvalidPatch)
+	default:
+		return fmt.Errorf("%w: unknown member %q", errInvalidPatch, member)
+	}
+}
+
+// patchOperation is one operation of an RFC 6902 JSON Patch. Value is nil
+// when the member is absent and "null" when it is null.
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+func decodeJSONPatch(body []byte) ([]patchOperation, error) {
+	var ops []patchOperation
+	if err := json.Unmarshal(body, &ops); err != nil {
+		return nil, fmt.Errorf("%w: a JSON patch must be an array of operations", errInvalidPatch)
+	}
+
+	for i, op := range ops {
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return nil, fmt.Errorf("%w: operation %d (%s) needs a value", errInvalidPatch, i, op.Op)
+			}
+		case "remove", "move", "copy":
+		default:
+			return nil, fmt.Errorf("%w: operation %d has unknown op %q", errInvalidPatch, i, op.Op)
+		}
+	}
+	return ops, nil
+}
+
+// applyJSONPatch applies ops to the JSON representation of c, as served by
+// GET, and returns the changes to make. The operations apply in order and
+// all or none: a failed test or an invalid result leaves c untouched.
+func applyJSONPatch(c *company.Company, ops []patchOperation) (company.UpdateParams, error) {
+	original, err := companyDocument(c)
+	if err != nil {
+		return company.UpdateParams{}, err
+	}
+	doc, err := companyDocument(c)
+	if err != nil {
+		return company.UpdateParams{}, err
+	}
+
+	for i, op := range ops {
+		if doc, err = applyPatchOperation(doc, op); err != nil {
+			return company.UpdateParams{}, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return documentToParams(original, doc)
+}
+
+// companyDocument is the company as a JSON object, the target of a patch.
+func companyDocument(c *company.Company) (map[string]any, error) {
+	raw, err := json.Marshal(CompanyToResponse(c))
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]any
+	return doc, json.Unmarshal(raw, &doc)
+}
+
+// applyPatchOperation applies one operation. A company is a flat object, so a
+// path is the whole document or one of its members.
+func applyPatchOperation(doc map[string]any, op patchOperation) (map[string]any, error) {
+	member, root, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	if op.Value != nil {
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, fmt.Errorf("%w: invalid value", errInvalidPatch)
+		}
+	}
+
+	if root {
+		switch op.Op {
+		case "test":
+			if !reflect.DeepEqual(map[string]any(doc), value) {
+				return nil, errPatchTestFailed
+			}
+			return doc, nil
+		case "add", "replace":
+			replacement, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%w: the document must stay an object", errInvalidPatch)
+			}
+			return replacement, nil
+		default:
+			return nil, fmt.Errorf("%w: cannot %s the whole document", errInvalidPatch, op.Op)
+		}
+	}
+
+	current, exists := doc[member]
+	switch op.Op {
+	case "test":
+		if !exists || !reflect.DeepEqual(current, value) {
+			return nil, errPatchTestFailed
+		}
+	case "add":
+		doc[member] = value
+	case "replace", "remove":
+		if !exists {
+			return nil, fmt.Errorf("%w: %s does not exist", errInvalidPatch, op.Path)
+		}
+		if op.Op == "remove" {
+			delete(doc, member)
+		} else {
+			doc[member] = value
+		}
+	case "move", "copy":
+		from, fromRoot, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		source, ok := doc[from]
+		if fromRoot || !ok {
+			return nil, fmt.Errorf("%w: from %s does not exist", errInvalidPatch, op.From)
+		}
+		if op.Op == "move" {
+			delete(doc, from)
+		}
+		doc[member] = source
+	}
+	return doc, nil
+}
+
+// parsePointer resolves an RFC 6901 JSON pointer into a member name, or root
+// for the whole document.
+func parsePointer(pointer string) (member string, root bool, err error) {
+	if pointer == "" {
+		return "", true, nil
+	}
+
+	tokens := strings.Split(pointer, "/")
+	if tokens[0] != "" {
+		return "", false, fmt.Errorf("%w: invalid path %q", errInvalidPatch, pointer)
+	}
+	if len(tokens) != 2 {
+		return "", false, fmt.Errorf("%w: %s does not exist", errInvalidPatch, pointer)
+	}
+
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(tokens[1]), false, nil
+}
+
+// documentToParams turns a patched document into the fields that differ
+// from the original.
+func documentToParams(original, doc map[string]any) (company.UpdateParams, error) {
+	var params company.UpdateParams
+
+	for member := range doc {
+		switch member {
+		case "id", "name", "description", "employees_count", "registered", "type":
+		default:
+			return params, fmt.Errorf("%w: unknown member %q", errInvalidPatch, member)
+		}
+	}
+	for _, member := range []string{"id", "name", "employees_count", "registered", "type"} {
+		if _, ok := doc[member]; !ok {
+			return params, removedFieldError(member)
+		}
+	}
+
+	changed := func(member string) bool {
+		return !reflect.DeepEqual(original[member], doc[member])
+	}
+	invalid := func(member string) error {
+		return fmt.Errorf("%w: invalid %s", errInvalidPatch, member)
+	}
+
+	if changed("id") {
+		return params, removedFieldError("id")
+	}
+	if changed("name") {
+		name, ok := doc["name"].(string)
+		if !ok {
+			return params, invalid("name")
+		}
+		params.Name = &name
+	}
+	if changed("description") {
+		// Removing the description or setting it to null clears it
+		description, ok := doc["description"].(string)
+		if !ok && doc["description"] != nil {
+			return params, invalid("description")
+		}
+		params.Description = &description
+	}
+	if changed("employees_count") {
+		count, ok := doc["employees_count"].(float64)
+		if !ok || count != math.Trunc(count) || math.Abs(count) > math.MaxInt32 {
+			return params, invalid("employees_count")
+		}
+		n := int(count)
+		params.EmployeesCount = &n
+	}
+	if changed("registered") {
+		registered, ok := doc["registered"].(bool)
+		if !ok {
+			return params, invalid("registered")
+		}
+		params.Registered = &registered
+	}
+	if changed("type") {
+		companyType, ok := doc["type"].(string)
+		if !ok {
+			return params, invalid("type")
+		}
+		params.Type = &companyType
+	}
+	return params, nil
+}
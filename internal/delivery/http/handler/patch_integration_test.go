@@ -0,0 +1,76 @@
+//go:build integration
+
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatchFormats_Integration(t *testing.T) {
+	f := setupBatch(t)
+
+	code, resp := f.batch(t, oapi.CompanyBatchRequest{Operations: []oapi.CompanyBatchOperation{createOp("Acme")}})
+	require.Equal(t, http.StatusOK, code)
+	id := resp.Results[0].Id.String()
+
+	patch := func(contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/companies/"+id, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer "+f.token)
+		w := httptest.NewRecorder()
+		f.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := patch("application/merge-patch+json", `{"description": "Widgets"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = patch("application/json-patch+json", `[{"op": "test", "path": "/description", "value": "Gadgets"}, {"op": "remove", "path": "/description"}]`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = patch("application/merge-patch+json", `{"description": null}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var c oapi.Company
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&c))
+	assert.Nil(t, c.Description)
+
+	assert.Equal(t, []string{"CompanyCreated", "CompanyUpdated", "CompanyUpdated"}, f.outboxEvents(t))
+}
+
+func TestReplaceCompany_Integration(t *testing.T) {
+	f := setupBatch(t)
+	id := uuid.NewString()
+
+	put := func(body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/companies/"+id, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+f.token)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		f.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := put(`{"name": "Mirrored", "employees_count": 3, "registered": false, "type": "Cooperative"}`, nil)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	etag := w.Header().Get("ETag")
+
+	w = put(`{"name": "Mirrored", "employees_count": 4, "registered": false, "type": "Cooperative"}`, map[string]string{"If-Match": etag})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = put(`{"name": "Mirrored", "employees_count": 5, "registered": false, "type": "Cooperative"}`, map[string]string{"If-Match": etag})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	assert.Equal(t, []string{"CompanyCreated", "CompanyUpdated"}, f.outboxEvents(t))
+}
@@ -0,0 +1,151 @@
+//go:build unit
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/infra/memory"
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPatchFixture(t *testing.T) (*CompanyHandler, *memory.Store, string) {
+	t.Helper()
+	store := memory.NewStore()
+	service := company.NewCompanyService(memory.NewCompanyRepo(store), memory.NewEventsPublisher(store), memory.NewTxManager(store))
+
+	c, err := service.CreateCompany(context.Background(), company.CreateParams{
+		Name: "Acme", Description: "Widgets", EmployeesCount: 10, Type: "Corporations",
+	})
+	require.NoError(t, err)
+	return NewCompanyHandler(service, ""), store, c.ID().String()
+}
+
+func patchCompany(t *testing.T, h *CompanyHandler, id, contentType, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/companies/"+id, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	h.UpdateCompany(w, req, uuid.MustParse(id))
+	return w
+}
+
+func decodeCompany(t *testing.T, w *httptest.ResponseRecorder) oapi.Company {
+	t.Helper()
+	var c oapi.Company
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&c))
+	return c
+}
+
+func TestUpdateCompany_MergePatch(t *testing.T) {
+	h, _, id := newPatchFixture(t)
+
+	w := patchCompany(t, h, id, "application/merge-patch+json", `{"description": null, "employees_count": 12}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	c := decodeCompany(t, w)
+	assert.Nil(t, c.Description, "null clears the description")
+	assert.Equal(t, 12, c.EmployeesCount)
+	assert.Equal(t, "Acme", c.Name)
+}
+
+func TestUpdateCompany_MergePatchInvalid(t *testing.T) {
+	h, _, id := newPatchFixture(t)
+
+	for name, body := range map[string]string{
+		"remove required": `{"name": null}`,
+		"unknown member":  `{"founded": 1999}`,
+		"change id":       `{"id": "00000000-0000-0000-0000-000000000000"}`,
+		"wrong type":      `{"employees_count": "ten"}`,
+		"not an object":   `[]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := patchCompany(t, h, id, "application/merge-patch+json", body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestUpdateCompany_EmptyMergePatchReturnsCompany(t *testing.T) {
+	h, store, id := newPatchFixture(t)
+
+	w := patchCompany(t, h, id, "application/merge-patch+json", `{}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Acme", decodeCompany(t, w).Name)
+	assert.Len(t, store.Events(), 1, "only the create")
+}
+
+func TestUpdateCompany_JSONPatch(t *testing.T) {
+	h, _, id := newPatchFixture(t)
+
+	w := patchCompany(t, h, id, "application/json-patch+json", `[
+		{"op": "test", "path": "/employees_count", "value": 10},
+		{"op": "replace", "path": "/employees_count", "value": 11},
+		{"op": "remove", "path": "/description"},
+		{"op": "copy", "from": "/name", "path": "/description"},
+		{"op": "replace", "path": "/registered", "value": true}
+	]`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	c := decodeCompany(t, w)
+	assert.Equal(t, 11, c.EmployeesCount)
+	assert.Equal(t, "Acme", *c.Description)
+	assert.True(t, c.Registered)
+}
+
+func TestUpdateCompany_JSONPatchFailedTestChangesNothing(t *testing.T) {
+	h, store, id := newPatchFixture(t)
+
+	w := patchCompany(t, h, id, "application/json-patch+json", `[
+		{"op": "replace", "path": "/employees_count", "value": 11},
+		{"op": "test", "path": "/name", "value": "Globex"}
+	]`)
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+
+	c, err := memory.NewCompanyRepo(store).GetByID(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, 10, c.EmployeesCount().Int())
+}
+
+func TestUpdateCompany_JSONPatchInvalid(t *testing.T) {
+	h, _, id := newPatchFixture(t)
+
+	for name, body := range map[string]string{
+		"unknown op":        `[{"op": "merge", "path": "/name"}]`,
+		"missing value":     `[{"op": "replace", "path": "/name"}]`,
+		"remove required":   `[{"op": "remove", "path": "/type"}]`,
+		"replace missing":   `[{"op": "replace", "path": "/founded", "value": 1999}]`,
+		"nested path":       `[{"op": "add", "path": "/name/first", "value": "A"}]`,
+		"fractional count":  `[{"op": "replace", "path": "/employees_count", "value": 1.5}]`,
+		"change id":         `[{"op": "replace", "path": "/id", "value": "00000000-0000-0000-0000-000000000000"}]`,
+		"domain validation": `[{"op": "replace", "path": "/type", "value": "Guild"}]`,
+		"not an array":      `{"op": "remove", "path": "/description"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := patchCompany(t, h, id, "application/json-patch+json", body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestUpdateCompany_UnsupportedMediaType(t *testing.T) {
+	h, _, id := newPatchFixture(t)
+
+	w := patchCompany(t, h, id, "text/plain", `name=Globex`)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Contains(t, w.Header().Get("Accept-Patch"), "application/merge-patch+json")
+
+	w = patchCompany(t, h, id, "", `{"employees_count": 5}`)
+	assert.Equal(t, http.StatusOK, w.Code, "a body without Content-Type is read as plain JSON")
+}
@@ -0,0 +1,114 @@
+//go:build unit
+
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/infra/memory"
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func putCompany(t *testing.T, h *CompanyHandler, id, body string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	var params oapi.ReplaceCompanyParams
+	if v, ok := headers["If-Match"]; ok {
+		params.IfMatch = &v
+	}
+	if v, ok := headers["If-None-Match"]; ok {
+		params.IfNoneMatch = &v
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/companies/"+id, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ReplaceCompany(w, req, uuid.MustParse(id), params)
+	return w
+}
+
+func eventNames(store *memory.Store) []string {
+	var names []string
+	for _, e := range store.Events() {
+		names = append(names, e.EventName())
+	}
+	return names
+}
+
+func TestReplaceCompany_CreatesThenReplaces(t *testing.T) {
+	h, store, _ := newPatchFixture(t)
+	id := uuid.NewString()
+	body := `{"name": "Globex", "description": "Mirrored", "employees_count": 3, "registered": false, "type": "NonProfit"}`
+
+	w := putCompany(t, h, id, body, nil)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, "/api/v1/companies/"+id, w.Header().Get("Location"))
+	created := decodeCompany(t, w)
+	assert.Equal(t, id, created.Id.String())
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	w = putCompany(t, h, id, body, nil)
+	require.Equal(t, http.StatusOK, w.Code, "sending it again is safe")
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	w = putCompany(t, h, id, `{"name": "Globex", "employees_count": 4, "registered": true, "type": "NonProfit"}`, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	replaced := decodeCompany(t, w)
+	assert.Nil(t, replaced.Description, "absent fields are not kept")
+	assert.Equal(t, 4, replaced.EmployeesCount)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+
+	assert.Equal(t, []string{
+		company.EventCompanyCreated, // fixture
+		company.EventCompanyCreated,
+		company.EventCompanyUpdated,
+		company.EventCompanyRegistered,
+	}, eventNames(store))
+}
+
+func TestReplaceCompany_Preconditions(t *testing.T) {
+	h, _, id := newPatchFixture(t)
+	body := `{"name": "Acme", "employees_count": 11, "registered": false, "type": "Corporations"}`
+
+	get := httptest.NewRecorder()
+	h.GetCompany(get, httptest.NewRequest(http.MethodGet, "/", nil), uuid.MustParse(id), oapi.GetCompanyParams{})
+	etag := get.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	tests := []struct {
+		name    string
+		id      string
+		headers map[string]string
+		status  int
+	}{
+		{"stale if-match", id, map[string]string{"If-Match": `"stale"`}, http.StatusPreconditionFailed},
+		{"weak if-match", id, map[string]string{"If-Match": "W/" + etag}, http.StatusPreconditionFailed},
+		{"if-none-match any on existing", id, map[string]string{"If-None-Match": "*"}, http.StatusPreconditionFailed},
+		{"if-match any on missing", uuid.NewString(), map[string]string{"If-Match": "*"}, http.StatusPreconditionFailed},
+		{"matching if-match", id, map[string]string{"If-Match": `"other", ` + etag}, http.StatusOK},
+		{"if-match is now stale", id, map[string]string{"If-Match": etag}, http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := putCompany(t, h, tt.id, body, tt.headers)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestReplaceCompany_Invalid(t *testing.T) {
+	h, _, id := newPatchFixture(t)
+
+	w := putCompany(t, h, id, `{"name": "Acme", "employees_count": 1, "type": "Guild"}`, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = putCompany(t, h, uuid.NewString(), `{"name": "Acme", "employees_count": 1, "type": "Corporations"}`, nil)
+	assert.Equal(t, http.StatusConflict, w.Code, "the name belongs to another company")
+}
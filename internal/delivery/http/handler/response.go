@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 
@@ -19,3 +20,23 @@ func writeJSON(w http.ResponseWriter, status int, data any) {
 func writeErr(w http.ResponseWriter, status int, code oapi.ErrorCode, message string) {
 	writeJSON(w, status, oapi.Error{Code: code, Message: message})
 }
+
+// WriteParamErr answers a path, query or header parameter that does not
+// parse as the spec declares it; the generated server calls it before any
+// handler.
+func WriteParamErr(w http.ResponseWriter, _ *http.Request, err error) {
+	message := err.Error()
+	var invalid *oapi.InvalidParamFormatError
+	if errors.As(err, &invalid) {
+		message = "invalid " + invalid.ParamName
+	}
+	writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, message)
+}
+
+// NotEnabled answers the operations of a feature that is off in this
+// deployment, such as the outbox tooling with STORAGE=memory.
+func NotEnabled(feature string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeErr(w, http.StatusNotFound, oapi.ErrorCodeNotFound, feature+" is not enabled")
+	})
+}
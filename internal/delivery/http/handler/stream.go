@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	"github.com/google/uuid"
+)
+
+// streamReplayPage is how many missed events are loaded at a time on resume
+const streamReplayPage = 500
+
+// CompanyEventStream delivers company events as they are written to the
+// outbox.
+type CompanyEventStream interface {
+	// Subscribe returns the live events matching filter. The channel is
+	// closed once the subscriber falls behind its buffer, the stream fails
+	// to load events or it stops; cancel unsubscribes.
+	Subscribe(filter StreamFilter) (events <-chan StreamEvent, cancel func())
+	// Since returns up to limit events matching filter with an ID above
+	// afterID, in ID order. An event with a lower ID that commits after
+	// afterID was sent is never returned, so resuming is best effort.
+	Since(ctx context.Context, filter StreamFilter, afterID int64, limit int) ([]StreamEvent, error)
+}
+
+// StreamFilter selects events; an empty list matches everything.
+type StreamFilter struct {
+	CompanyIDs []string
+	EventTypes []string
+}
+
+func (f StreamFilter) Match(e StreamEvent) bool {
+	return (len(f.CompanyIDs) == 0 || slices.Contains(f.CompanyIDs, e.CompanyID)) &&
+		(len(f.EventTypes) == 0 || slices.Contains(f.EventTypes, e.EventType))
+}
+
+type StreamEvent struct {
+	ID        int64
+	EventType string
+	CompanyID string
+	Payload   json.RawMessage
+	CreatedAt int64
+}
+
+// StreamHandler serves company events as Server-Sent Events.
+type StreamHandler struct {
+	stream       CompanyEventStream
+	heartbeat    time.Duration
+	writeTimeout time.Duration
+}
+
+// NewStreamHandler sends a keep-alive after heartbeat without events and
+// disconnects a client that does not take a write within writeTimeout.
+func NewStreamHandler(stream CompanyEventStream, heartbeat, writeTimeout time.Duration) *StreamHandler {
+	return &StreamHandler{stream: stream, heartbeat: heartbeat, writeTimeout: writeTimeout}
+}
+
+func (h *StreamHandler) StreamCompanyEvents(w http.ResponseWriter, r *http.Request, params oapi.StreamCompanyEventsParams) {
+	filter, err := streamFilterFromParams(params)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, err.Error())
+		return
+	}
+
+	lastID, resume, err := lastEventID(params)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, err.Error())
+		return
+	}
+
+	// Subscribe before loading missed events, so nothing written in between
+	// is lost
+	events, cancel := h.stream.Subscribe(filter)
+	defer cancel()
+
+	out := &sseWriter{w: w, rc: http.NewResponseController(w), timeout: h.writeTimeout}
+	// The stream outlives the server's request timeouts
+	_ = out.rc.SetReadDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := out.comment("connected"); err != nil {
+		return
+	}
+
+	// replayedUpTo is the highest ID sent from the outbox; outbox IDs only
+	// grow, so live events up to it were sent already
+	var replayedUpTo int64
+	if resume {
+		for {
+			page, err := h.stream.Since(r.Context(), filter, lastID, streamReplayPage)
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					slog.Error("failed to load missed company events", "error", err, "last_event_id", lastID)
+				}
+				return
+			}
+
+			for _, e := range page {
+				if err := out.event(e); err != nil {
+					return
+				}
+				replayedUpTo = e.ID
+			}
+			if len(page) < streamReplayPage {
+				break
+			}
+			lastID = page[len(page)-1].ID
+		}
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				// Fell behind or shutting down; the client resumes with
+				// Last-Event-ID
+				return
+			}
+			if e.ID <= replayedUpTo {
+				continue
+			}
+			if err := out.event(e); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := out.comment("keep-alive"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func streamFilterFromParams(params oapi.StreamCompanyEventsParams) (StreamFilter, error) {
+	var f StreamFilter
+	if params.CompanyId != nil {
+		for _, id := range *params.CompanyId {
+			f.CompanyIDs = append(f.CompanyIDs, id.String())
+		}
+	}
+
+	if params.EventType != nil {
+		for _, eventType := range *params.EventType {
+			if !slices.Contains(company.EventNames, string(eventType)) {
+				return f, fmt.Errorf("unknown event_type %q", eventType)
+			}
+			f.EventTypes = append(f.EventTypes, string(eventType))
+		}
+	}
+	return f, nil
+}
+
+// lastEventID reads where a reconnecting client left off, from the
+// Last-Event-ID header or the last_event_id query parameter.
+func lastEventID(params oapi.StreamCompanyEventsParams) (int64, bool, error) {
+	var id int64
+	switch {
+	case params.LastEventID != nil && *params.LastEventID != "":
+		var err error
+		if id, err = strconv.ParseInt(*params.LastEventID, 10, 64); err != nil {
+			return 0, false, errors.New("invalid last event id")
+		}
+	case params.LastEventId != nil:
+		id = *params.LastEventId
+	default:
+		return 0, false, nil
+	}
+
+	if id < 0 {
+		return 0, false, errors.New("invalid last event id")
+	}
+	return id, true, nil
+}
+
+// sseWriter writes and flushes Server-Sent Events, each within timeout.
+type sseWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	timeout time.Duration
+}
+
+func (s *sseWriter) event(e StreamEvent) error {
+	companyID, err := uuid.Parse(e.CompanyID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(oapi.CompanyEvent{
+		Id:          e.ID,
+		EventType:   e.EventType,
+		AggregateId: companyID,
+		CreatedAt:   e.CreatedAt,
+		Payload:     e.Payload,
+	})
+	if err != nil {
+		return err
+	}
+	return s.write(fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.EventType, data))
+}
+
+func (s *sseWriter) comment(text string) error {
+	return s.write(": " + text + "\n\n")
+}
+
+func (s *sseWriter) write(frame string) error {
+	if s.timeout > 0 {
+		_ = s.rc.SetWriteDeadline(time.Now().Add(s.timeout))
+	}
+	if _, err := s.w.Write([]byte(frame)); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
@@ -0,0 +1,123 @@
+//go:build unit
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubEventStream serves stored events from Since and whatever is queued on
+// live; closing live ends the stream.
+type stubEventStream struct {
+	stored []StreamEvent
+	live   chan StreamEvent
+	filter StreamFilter
+}
+
+func (s *stubEventStream) Subscribe(filter StreamFilter) (<-chan StreamEvent, func()) {
+	s.filter = filter
+	return s.live, func() {}
+}
+
+func (s *stubEventStream) Since(_ context.Context, filter StreamFilter, afterID int64, limit int) ([]StreamEvent, error) {
+	var out []StreamEvent
+	for _, e := range s.stored {
+		if e.ID > afterID && filter.Match(e) && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func testStreamEvent(id int64, companyID string) StreamEvent {
+	return StreamEvent{ID: id, EventType: "CompanyUpdated", CompanyID: companyID, Payload: json.RawMessage(`{}`), CreatedAt: 1700000000}
+}
+
+// sseIDs returns the IDs of the events in an SSE body, checking each frame.
+func sseIDs(t *testing.T, body string) []int64 {
+	t.Helper()
+	var ids []int64
+	for _, frame := range strings.Split(body, "\n\n") {
+		if !strings.HasPrefix(frame, "id: ") {
+			continue
+		}
+		lines := strings.Split(frame, "\n")
+		require.Len(t, lines, 3)
+		assert.Equal(t, "event: CompanyUpdated", lines[1])
+
+		var e oapi.CompanyEvent
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(lines[2], "data: ")), &e))
+		assert.Equal(t, "id: "+strconv.FormatInt(e.Id, 10), lines[0])
+		ids = append(ids, e.Id)
+	}
+	return ids
+}
+
+func TestStreamCompanyEvents_ResumesThenStreamsLive(t *testing.T) {
+	companyID := uuid.NewString()
+	stream := &stubEventStream{live: make(chan StreamEvent, 3)}
+	for id := int64(1); id <= 5; id++ {
+		stream.stored = append(stream.stored, testStreamEvent(id, companyID))
+	}
+	// Event 5 was written between subscribing and loading what was missed,
+	// and nothing at or below the last replayed ID is sent live
+	stream.live <- testStreamEvent(2, companyID)
+	stream.live <- testStreamEvent(5, companyID)
+	stream.live <- testStreamEvent(6, companyID)
+	close(stream.live)
+
+	lastID := "3"
+	params := oapi.StreamCompanyEventsParams{CompanyId: &[]uuid.UUID{uuid.MustParse(companyID)}, LastEventID: &lastID}
+	w := httptest.NewRecorder()
+	NewStreamHandler(stream, time.Minute, time.Second).StreamCompanyEvents(w, httptest.NewRequest(http.MethodGet, "/", nil), params)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, []int64{4, 5, 6}, sseIDs(t, w.Body.String()))
+	assert.Equal(t, []string{companyID}, stream.filter.CompanyIDs)
+}
+
+func TestStreamCompanyEvents_LiveOnlyWithoutLastEventID(t *testing.T) {
+	stream := &stubEventStream{
+		stored: []StreamEvent{testStreamEvent(1, uuid.NewString())},
+		live:   make(chan StreamEvent, 1),
+	}
+	stream.live <- testStreamEvent(2, uuid.NewString())
+	close(stream.live)
+
+	params := oapi.StreamCompanyEventsParams{EventType: &[]oapi.WebhookEventType{"CompanyUpdated", "CompanyDeleted"}}
+	w := httptest.NewRecorder()
+	NewStreamHandler(stream, time.Minute, time.Second).StreamCompanyEvents(w, httptest.NewRequest(http.MethodGet, "/", nil), params)
+
+	assert.Equal(t, []int64{2}, sseIDs(t, w.Body.String()))
+	assert.Equal(t, []string{"CompanyUpdated", "CompanyDeleted"}, stream.filter.EventTypes)
+}
+
+func TestStreamCompanyEvents_InvalidRequest(t *testing.T) {
+	negative, header := int64(-1), "soon"
+	for name, params := range map[string]oapi.StreamCompanyEventsParams{
+		"event type":           {EventType: &[]oapi.WebhookEventType{"CompanyMoved"}},
+		"last event id":        {LastEventId: &negative},
+		"last event id header": {LastEventID: &header},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			NewStreamHandler(&stubEventStream{}, time.Minute, time.Second).
+				StreamCompanyEvents(w, httptest.NewRequest(http.MethodGet, "/", nil), params)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	"github.com/dubininme/xm-assessment/pkg/jobs"
+	"github.com/google/uuid"
+)
+
+// exportFlushEvery is how many exported companies are buffered before they
+// are sent
+const exportFlushEvery = 500
+
+// importJobTTL is how long a finished import job can still be looked up
+const importJobTTL = 24 * time.Hour
+
+// TransferHandler exports and imports companies in bulk as CSV or NDJSON.
+type TransferHandler struct {
+	service   *company.CompanyService
+	jobs      *jobs.Registry[company.ImportReport]
+	maxBytes  int64
+	syncBytes int64
+	timeout   time.Duration
+}
+
+// NewTransferHandler rejects uploads over maxBytes and imports the ones over
+// syncBytes as background jobs, which stop when ctx is cancelled. timeout
+// bounds an upload, a synchronous import and each flush of an export,
+// replacing the server's request timeouts.
+func NewTransferHandler(ctx context.Context, service *company.CompanyService, maxBytes, syncBytes int64, timeout time.Duration) *TransferHandler {
+	return &TransferHandler{
+		service:   service,
+		jobs:      jobs.NewRegistry[company.ImportReport](ctx, importJobTTL),
+		maxBytes:  maxBytes,
+		syncBytes: syncBytes,
+		timeout:   timeout,
+	}
+}
+
+func (h *TransferHandler) ExportCompanies(w http.ResponseWriter, r *http.Request) {
+	mediaType, ok := exportMediaType(r.Header.Get("Accept"))
+	if !ok {
+		writeErr(w, http.StatusNotAcceptable, oapi.ErrorCodeNotAcceptable, "export is available as text/csv or application/x-ndjson")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(h.timeout))
+
+	out, err := newCompanyWriter(mediaType, w)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, oapi.ErrorCodeInternalError, "internal error")
+		return
+	}
+
+	// Nothing reaches the client before the first flush, so a failure until
+	// then can still be answered with an error
+	sent := false
+	flush := func() error {
+		if !sent {
+			w.Header().Set("Content-Type", mediaType)
+			w.Header().Set("Content-Disposition", `attachment; filename="companies`+exportExtension(mediaType)+`"`)
+			sent = true
+		}
+		if err := out.Flush(); err != nil {
+			return err
+		}
+		_ = rc.SetWriteDeadline(time.Now().Add(h.timeout))
+		return rc.Flush()
+	}
+
+	written := 0
+	err = h.service.Export(r.Context(), func(c *company.Company) error {
+		if err := out.Write(c); err != nil {
+			return err
+		}
+		written++
+		if written%exportFlushEvery == 0 {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err == nil || r.Context().Err() != nil {
+		return
+	}
+
+	slog.Error("failed to export companies", "error", err, "written", written)
+	if !sent {
+		writeErr(w, http.StatusInternalServerError, oapi.ErrorCodeInternalError, "internal error")
+		return
+	}
+	// Break the connection so a truncated export is not taken for a whole one
+	panic(http.ErrAbortHandler)
+}
+
+func (h *TransferHandler) ImportCompanies(w http.ResponseWriter, r *http.Request, params oapi.ImportCompaniesParams) {
+	async := params.Async != nil && *params.Async
+
+	mediaType, ok := importMediaType(r.Header.Get("Content-Type"))
+	if !ok {
+		writeErr(w, http.StatusUnsupportedMediaType, oapi.ErrorCodeUnsupportedMediaType, "upload text/csv or application/x-ndjson")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Now().Add(h.timeout))
+	_ = rc.SetWriteDeadline(time.Now().Add(h.timeout))
+
+	body := http.MaxBytesReader(w, r.Body, h.maxBytes)
+	head, err := io.ReadAll(io.LimitReader(body, h.syncBytes+1))
+	if err != nil {
+		writeUploadErr(w, err)
+		return
+	}
+
+	if !async && int64(len(head)) <= h.syncBytes {
+		src, err := newImportSource(mediaType, bytes.NewReader(head))
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, err.Error())
+			return
+		}
+
+		report, err := h.service.Import(r.Context(), src, nil)
+		logImportErrors(report)
+		if err != nil {
+			if r.Context().Err() == nil {
+				writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, err.Error())
+			}
+			return
+		}
+
+		writeJSON(w, http.StatusOK, importReportToResponse(report))
+		return
+	}
+
+	// The job outlives the request, so the upload is kept in a temporary file
+	file, err := spoolUpload(head, body)
+	if err != nil {
+		writeUploadErr(w, err)
+		return
+	}
+
+	src, err := newImportSource(mediaType, file)
+	if err != nil {
+		removeUpload(file)
+		writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, err.Error())
+		return
+	}
+
+	job := h.jobs.Start("import", func(ctx context.Context, onProgress func(company.ImportReport)) (company.ImportReport, error) {
+		defer removeUpload(file)
+
+		report, err := h.service.Import(ctx, src, onProgress)
+		logImportErrors(report)
+		return report, err
+	})
+
+	w.Header().Set("Location", "/api/v1/companies/import/"+job.ID)
+	writeJSON(w, http.StatusAccepted, importJobToResponse(job))
+}
+
+func (h *TransferHandler) GetCompanyImportJob(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	job, ok := h.jobs.Get(id.String())
+	if !ok {
+		writeErr(w, http.StatusNotFound, oapi.ErrorCodeNotFound, "job not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, importJobToResponse(job))
+}
+
+func exportExtension(mediaType string) string {
+	if mediaType == mediaTypeNDJSON {
+		return ".ndjson"
+	}
+	return ".csv"
+}
+
+func spoolUpload(head []byte, rest io.Reader) (*os.File, error) {
+	file, err := os.CreateTemp("", "company-import-*")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := file.Write(head); err != nil {
+		removeUpload(file)
+		return nil, err
+	}
+	if _, err := io.Copy(file, rest); err != nil {
+		removeUpload(file)
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		removeUpload(file)
+		return nil, err
+	}
+	return file, nil
+}
+
+func removeUpload(file *os.File) {
+	_ = file.Close()
+	if err := os.Remove(file.Name()); err != nil {
+		slog.Error("failed to remove import upload", "file", file.Name(), "error", err)
+	}
+}
+
+func writeUploadErr(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeErr(w, http.StatusRequestEntityTooLarge, oapi.ErrorCodePayloadTooLarge, "upload too large")
+		return
+	}
+
+	slog.Error("failed to read import upload", "error", err)
+	writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, "failed to read request body")
+}
+
+// logImportErrors logs the rows that failed for reasons other than their
+// content, which the report only shows as an internal error.
+func logImportErrors(report company.ImportReport) {
+	for _, rowErr := range report.Errors {
+		if status, _ := CompanyError(rowErr.Err); status == http.StatusInternalServerError {
+			slog.Error("failed to import company row", "line", rowErr.Line, "error", rowErr.Err)
+		}
+	}
+}
+
+func importReportToResponse(report company.ImportReport) oapi.CompanyImportReport {
+	resp := oapi.CompanyImportReport{
+		Rows:      report.Rows,
+		Created:   report.Created,
+		Updated:   report.Updated,
+		Unchanged: report.Unchanged,
+		Failed:    report.Failed,
+		Errors:    make([]oapi.CompanyImportRowError, len(report.Errors)),
+	}
+
+	for i, rowErr := range report.Errors {
+		_, body := CompanyError(rowErr.Err)
+		resp.Errors[i] = oapi.CompanyImportRowError{Line: rowErr.Line, Error: body}
+		if rowErr.Name != "" {
+			resp.Errors[i].Name = &rowErr.Name
+		}
+	}
+	return resp
+}
+
+func importJobToResponse(job jobs.Job[company.ImportReport]) oapi.CompanyImportJob {
+	resp := oapi.CompanyImportJob{
+		Id:        uuid.MustParse(job.ID),
+		Status:    oapi.CompanyImportJobStatus(job.Status),
+		Report:    importReportToResponse(job.Progress),
+		StartedAt: job.StartedAt,
+	}
+	if job.Error != "" {
+		resp.Error = &job.Error
+	}
+	if !job.FinishedAt.IsZero() {
+		resp.FinishedAt = &job.FinishedAt
+	}
+	return resp
+}
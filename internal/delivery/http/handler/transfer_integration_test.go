@@ -0,0 +1,64 @@
+//go:build integration
+
+package handler_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportExport_Integration(t *testing.T) {
+	f := setupBatch(t)
+
+	body := "name,description,employees_count,registered,type\n" +
+		"Acme,Widgets,10,true,Corporations\n" +
+		"Globex,,3,false,NonProfit\n" +
+		"Acme,Gadgets,10,true,Corporations\n" +
+		"TooLongCompanyName,,1,false,Corporations\n"
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/companies/import", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("Authorization", "Bearer "+f.token)
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var report oapi.CompanyImportReport
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	assert.Equal(t, 2, report.Created)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, []string{"CompanyCreated", "CompanyCreated", "CompanyUpdated"}, f.outboxEvents(t))
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/companies/export", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	req.Header.Set("Authorization", "Bearer "+f.token)
+	w = httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	exported := map[string]oapi.Company{}
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var c oapi.Company
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &c))
+		exported[c.Name] = c
+	}
+	require.Len(t, exported, 2)
+	assert.Equal(t, "Gadgets", *exported["Acme"].Description)
+
+	// The export route is protected and not taken for a company ID
+	req = httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/api/v1/companies/export", nil)
+	w = httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
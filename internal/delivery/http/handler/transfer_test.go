@@ -0,0 +1,218 @@
+//go:build unit
+
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/infra/memory"
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTransferHandler(t *testing.T, syncBytes int64) (*TransferHandler, *memory.Store) {
+	t.Helper()
+	store := memory.NewStore()
+	service := company.NewCompanyService(memory.NewCompanyRepo(store), memory.NewEventsPublisher(store), memory.NewTxManager(store))
+	return NewTransferHandler(t.Context(), service, 1<<20, syncBytes, time.Minute), store
+}
+
+func importRequest(contentType, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/companies/import", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	return req
+}
+
+func TestImportCompanies_CSVReportsRowErrors(t *testing.T) {
+	h, store := newTestTransferHandler(t, 1<<20)
+
+	body := "\ufeffname,employees_count,registered,type,description\n" +
+		"Acme,10,true,Corporations,First\n" +
+		"Acme,12,true,Corporations,First\n" +
+		"Globex,ten,false,NonProfit,\n" +
+		"Initech,5,false,Unknown,\n" +
+		"Acme,12,true,Corporations,First\n"
+
+	w := httptest.NewRecorder()
+	h.ImportCompanies(w, importRequest("text/csv", body), oapi.ImportCompaniesParams{})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var report oapi.CompanyImportReport
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	assert.Equal(t, 5, report.Rows)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 1, report.Unchanged)
+	assert.Equal(t, 2, report.Failed)
+
+	require.Len(t, report.Errors, 2)
+	assert.Equal(t, 4, report.Errors[0].Line)
+	assert.Equal(t, oapi.ErrorCodeBadRequest, report.Errors[0].Error.Code)
+	assert.Contains(t, report.Errors[0].Error.Message, "employees_count")
+	assert.Equal(t, 5, report.Errors[1].Line)
+	assert.Equal(t, "Initech", *report.Errors[1].Name)
+	assert.Contains(t, report.Errors[1].Error.Message, company.ErrInvalidCompanyType.Error())
+
+	// Created, then updated; the unchanged row publishes nothing
+	assert.Len(t, store.Events(), 2)
+}
+
+func TestImportCompanies_NDJSON(t *testing.T) {
+	h, _ := newTestTransferHandler(t, 1<<20)
+
+	body := `{"name":"Acme","employees_count":10,"registered":false,"type":"Corporations"}` + "\n\n" +
+		`{"name":` + "\n" +
+		`{"id":"ignored","name":"Globex","employees_count":3,"registered":true,"type":"Cooperative"}` + "\n"
+
+	w := httptest.NewRecorder()
+	h.ImportCompanies(w, importRequest("application/x-ndjson", body), oapi.ImportCompaniesParams{})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var report oapi.CompanyImportReport
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	assert.Equal(t, 3, report.Rows)
+	assert.Equal(t, 2, report.Created)
+	require.Len(t, report.Errors, 1)
+	assert.Equal(t, 3, report.Errors[0].Line, "blank lines count")
+}
+
+func TestImportCompanies_RejectedUploads(t *testing.T) {
+	h, _ := newTestTransferHandler(t, 1<<20)
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{"media type", importRequest("application/json", "[]"), http.StatusUnsupportedMediaType},
+		{"missing column", importRequest("text/csv", "name,registered,type\n"), http.StatusBadRequest},
+		{"empty csv", importRequest("text/csv", ""), http.StatusBadRequest},
+		{"too large", importRequest("text/csv", strings.Repeat("x", 2<<20)), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ImportCompanies(w, tt.req, oapi.ImportCompaniesParams{})
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestImportCompanies_LargeUploadRunsAsJob(t *testing.T) {
+	h, _ := newTestTransferHandler(t, 64)
+
+	var body strings.Builder
+	body.WriteString("name,employees_count,registered,type\n")
+	for _, name := range []string{"Acme", "Globex", "Initech", "Umbrella"} {
+		body.WriteString(name + ",1,false,Corporations\n")
+	}
+
+	w := httptest.NewRecorder()
+	h.ImportCompanies(w, importRequest("text/csv", body.String()), oapi.ImportCompaniesParams{})
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+
+	var job oapi.CompanyImportJob
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&job))
+	assert.Equal(t, "/api/v1/companies/import/"+job.Id.String(), w.Header().Get("Location"))
+
+	require.Eventually(t, func() bool {
+		w := httptest.NewRecorder()
+		h.GetCompanyImportJob(w, httptest.NewRequest(http.MethodGet, "/", nil), job.Id)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&job))
+		return job.Status != oapi.CompanyImportJobStatusRunning
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, oapi.CompanyImportJobStatusSucceeded, job.Status)
+	assert.Equal(t, 4, job.Report.Created)
+	assert.NotNil(t, job.FinishedAt)
+}
+
+func TestExportCompanies(t *testing.T) {
+	h, _ := newTestTransferHandler(t, 1<<20)
+
+	w := httptest.NewRecorder()
+	h.ImportCompanies(w, importRequest("text/csv", "name,description,employees_count,registered,type\n"+
+		"Acme,\"Quoted, with comma\",10,true,Corporations\n"+
+		"Globex,,3,false,NonProfit\n"), oapi.ImportCompaniesParams{})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	t.Run("csv", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/companies/export", nil)
+		req.Header.Set("Accept", "text/html, text/*;q=0.8")
+		h.ExportCompanies(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+
+		records, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.Equal(t, csvColumns, records[0])
+
+		byName := map[string][]string{records[1][1]: records[1], records[2][1]: records[2]}
+		assert.Equal(t, []string{"Quoted, with comma", "10", "true", "Corporations"}, byName["Acme"][2:])
+		assert.Equal(t, []string{"", "3", "false", "NonProfit"}, byName["Globex"][2:])
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/companies/export", nil)
+		req.Header.Set("Accept", "application/x-ndjson")
+		h.ExportCompanies(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+		var names []string
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			var c oapi.Company
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &c))
+			names = append(names, c.Name)
+		}
+		assert.ElementsMatch(t, []string{"Acme", "Globex"}, names)
+	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/companies/export", nil)
+		req.Header.Set("Accept", "application/json, text/csv;q=0")
+		h.ExportCompanies(w, req)
+
+		assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	})
+}
+
+func TestExportCompanies_RoundTrip(t *testing.T) {
+	h, store := newTestTransferHandler(t, 1<<20)
+	service := company.NewCompanyService(memory.NewCompanyRepo(store), memory.NewEventsPublisher(store), memory.NewTxManager(store))
+	for _, name := range []string{"Acme", "Globex"} {
+		_, err := service.CreateCompany(context.Background(), company.CreateParams{Name: name, EmployeesCount: 1, Type: "Cooperative"})
+		require.NoError(t, err)
+	}
+
+	w := httptest.NewRecorder()
+	h.ExportCompanies(w, httptest.NewRequest(http.MethodGet, "/api/v1/companies/export", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	imported := httptest.NewRecorder()
+	h.ImportCompanies(imported, importRequest("text/csv", w.Body.String()), oapi.ImportCompaniesParams{})
+	require.Equal(t, http.StatusOK, imported.Code)
+
+	var report oapi.CompanyImportReport
+	require.NoError(t, json.NewDecoder(imported.Body).Decode(&report))
+	assert.Equal(t, 2, report.Unchanged, "an export imports back without changes")
+}
@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/dubininme/xm-assessment/internal/domain/webhook"
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 200
+)
+
+// WebhookHandler manages webhook subscriptions and their delivery log.
+type WebhookHandler struct {
+	service *webhook.Service
+}
+
+func NewWebhookHandler(service *webhook.Service) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req oapi.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, "invalid request body")
+		return
+	}
+
+	params := webhook.CreateParams{URL: req.Url, Secret: req.Secret}
+	if req.EventTypes != nil {
+		params.EventTypes = eventTypesFromRequest(*req.EventTypes)
+	}
+
+	sub, err := h.service.Create(r.Context(), params)
+	if err != nil {
+		writeWebhookErr(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, webhookToResponse(sub))
+}
+
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.service.List(r.Context())
+	if err != nil {
+		writeWebhookErr(w, err)
+		return
+	}
+
+	resp := oapi.WebhookList{Items: make([]oapi.Webhook, 0, len(subs))}
+	for i := range subs {
+		resp.Items = append(resp.Items, webhookToResponse(&subs[i]))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	sub, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		writeWebhookErr(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, webhookToResponse(sub))
+}
+
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	var req oapi.UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, "invalid request body")
+		return
+	}
+
+	params := webhook.UpdateParams{URL: req.Url, Secret: req.Secret, Active: req.Active}
+	if req.EventTypes != nil {
+		eventTypes := eventTypesFromRequest(*req.EventTypes)
+		params.EventTypes = &eventTypes
+	}
+
+	sub, err := h.service.Update(r.Context(), id, params)
+	if err != nil {
+		writeWebhookErr(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, webhookToResponse(sub))
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		writeWebhookErr(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request, id uuid.UUID, params oapi.ListWebhookDeliveriesParams) {
+	limit := defaultDeliveriesLimit
+	if params.Limit != nil {
+		if *params.Limit < 1 || *params.Limit > maxDeliveriesLimit {
+			writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, "limit must be between 1 and 200")
+			return
+		}
+		limit = *params.Limit
+	}
+
+	deliveries, err := h.service.Deliveries(r.Context(), id, limit)
+	if err != nil {
+		writeWebhookErr(w, err)
+		return
+	}
+
+	resp := oapi.WebhookDeliveryList{Items: make([]oapi.WebhookDelivery, 0, len(deliveries))}
+	for i := range deliveries {
+		resp.Items = append(resp.Items, deliveryToResponse(&deliveries[i]))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *WebhookHandler) RedeliverWebhookDelivery(w http.ResponseWriter, r *http.Request, id uuid.UUID, deliveryID int64) {
+	d, err := h.service.Redeliver(r.Context(), id, deliveryID)
+	if err != nil {
+		writeWebhookErr(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, deliveryToResponse(d))
+}
+
+func writeWebhookErr(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, webhook.ErrSubscriptionNotFound), errors.Is(err, webhook.ErrDeliveryNotFound):
+		writeErr(w, http.StatusNotFound, oapi.ErrorCodeNotFound, err.Error())
+	case errors.Is(err, webhook.ErrInvalidURL),
+		errors.Is(err, webhook.ErrURLNotAllowed),
+		errors.Is(err, webhook.ErrInvalidEventType),
+		errors.Is(err, webhook.ErrSecretTooShort),
+		errors.Is(err, webhook.ErrNoFieldsToUpdate):
+		writeErr(w, http.StatusBadRequest, oapi.ErrorCodeBadRequest, err.Error())
+	default:
+		writeErr(w, http.StatusInternalServerError, oapi.ErrorCodeInternalError, "internal error")
+	}
+}
+
+func eventTypesFromRequest(types []oapi.WebhookEventType) []string {
+	out := make([]string, len(types))
+	for i, t := range types {
+		out[i] = string(t)
+	}
+	return out
+}
+
+func webhookToResponse(s *webhook.Subscription) oapi.Webhook {
+	eventTypes := make([]oapi.WebhookEventType, len(s.EventTypes))
+	for i, t := range s.EventTypes {
+		eventTypes[i] = oapi.WebhookEventType(t)
+	}
+
+	return oapi.Webhook{
+		Id:         s.ID,
+		Url:        s.URL,
+		EventTypes: eventTypes,
+		Active:     s.Active,
+		CreatedAt:  s.CreatedAt,
+		UpdatedAt:  s.UpdatedAt,
+	}
+}
+
+func deliveryToResponse(d *webhook.Delivery) oapi.WebhookDelivery {
+	resp := oapi.WebhookDelivery{
+		Id:        d.ID,
+		WebhookId: d.SubscriptionID,
+		OutboxId:  d.OutboxID,
+		EventType: d.EventType,
+		Status:    oapi.WebhookDeliveryStatus(d.Status),
+		Attempts:  d.Attempts,
+		CreatedAt: d.CreatedAt,
+	}
+
+	if d.ResponseStatus != 0 {
+		resp.ResponseStatus = &d.ResponseStatus
+	}
+	if d.Error != "" {
+		resp.Error = &d.Error
+	}
+	if d.Status == webhook.DeliveryPending {
+		resp.NextAttemptAt = &d.NextAttemptAt
+	}
+	if !d.DeliveredAt.IsZero() {
+		resp.DeliveredAt = &d.DeliveredAt
+	}
+	return resp
+}
@@ -2,8 +2,6 @@ package middleware
 
 import (
 	"context"
-	"encoding/json"
-	"log/slog"
 	"net/http"
 
 	"github.com/dubininme/xm-assessment/internal/infra/auth"
@@ -47,16 +45,39 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 	})
 }
 
-func writeUnauthorized(w http.ResponseWriter, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusUnauthorized)
+// AuthenticateSecured authenticates the operations the spec secures with
+// bearerAuth and lets the others through. It reads the scopes the generated
+// server puts in the context, so it must run inside it.
+func (m *AuthMiddleware) AuthenticateSecured(next http.Handler) http.Handler {
+	authenticated := m.Authenticate(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, secured := r.Context().Value(oapi.BearerAuthScopes).([]string); !secured {
+			next.ServeHTTP(w, r)
+			return
+		}
+		authenticated.ServeHTTP(w, r)
+	})
+}
+
+// Identify puts the user of a valid token in the context and lets every
+// request through, so that the handler decides what needs a user, like the
+// mutations of /graphql.
+func (m *AuthMiddleware) Identify(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenString, err := m.jwtService.ExtractToken(r.Header.Get("Authorization"))
+		if err == nil {
+			if claims, err := m.jwtService.ValidateToken(tokenString); err == nil {
+				r = r.WithContext(context.WithValue(r.Context(), UserIDKey, claims.UserID))
+			}
+		}
 
-	resp := oapi.Error{
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeUnauthorized(w http.ResponseWriter, message string) {
+	writeError(w, http.StatusUnauthorized, oapi.Error{
 		Code:    oapi.ErrorCodeUnauthorized,
 		Message: message,
-	}
-
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		slog.Error("failed to encode JSON response", "error", err)
-	}
+	})
 }
@@ -0,0 +1,320 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// ValidatorOptions tunes a Validator.
+type ValidatorOptions struct {
+	// MaxBodyBytes is the largest JSON request body accepted
+	MaxBodyBytes int64
+	// ValidateResponses checks JSON responses too and replaces one that does
+	// not match the spec with a 500; it is meant for tests
+	ValidateResponses bool
+}
+
+// Validator checks requests against the OpenAPI spec before they reach the
+// handlers, so that unknown members, wrong types, missing required fields
+// and trailing garbage are rejected with a 400 listing every problem.
+type Validator struct {
+	router            routers.Router
+	maxBodyBytes      int64
+	validateResponses bool
+}
+
+func NewValidator(spec []byte, opts ValidatorOptions) (*Validator, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("error loading OpenAPI spec: %w", err)
+	}
+	if err := doc.Validate(loader.Context); err != nil {
+		return nil, fmt.Errorf("error validating OpenAPI spec: %w", err)
+	}
+
+	// Requests are matched on their path, whatever host serves them
+	doc.Servers = nil
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("error routing OpenAPI spec: %w", err)
+	}
+
+	return &Validator{
+		router:            router,
+		maxBodyBytes:      opts.MaxBodyBytes,
+		validateResponses: opts.ValidateResponses,
+	}, nil
+}
+
+func (v *Validator) Validate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := v.router.FindRoute(r)
+		if err != nil {
+			// Routes the spec does not describe are left to the handlers
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				MultiError:          true,
+				SkipSettingDefaults: true,
+				// Tokens are checked by the AuthMiddleware
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			},
+		}
+		if body := route.Operation.RequestBody; body != nil && body.Value != nil {
+			if !v.readBody(w, r, body.Value, input.Options) {
+				return
+			}
+		}
+
+		if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			writeError(w, http.StatusBadRequest, oapi.Error{
+				Code:    oapi.ErrorCodeBadRequest,
+				Message: "invalid request",
+				Details: validationDetails(err),
+			})
+			return
+		}
+
+		if !v.validateResponses || !jsonResponses(route.Operation) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		buf := &responseBuffer{w: w, header: http.Header{}}
+		next.ServeHTTP(buf, r)
+		v.writeResponse(w, buf, input)
+	})
+}
+
+// readBody checks the media type of a request body and reads a JSON body
+// within the size limit, putting it back for the handler. Other media types,
+// like the CSV of an import, are streamed by their handlers and not checked.
+func (v *Validator) readBody(w http.ResponseWriter, r *http.Request, body *openapi3.RequestBody, opts *openapi3filter.Options) bool {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" && body.Content.Get("application/json") != nil {
+		// Like the handlers, a body without a media type is read as JSON
+		contentType = "application/json"
+		r.Header.Set("Content-Type", contentType)
+	}
+	if contentType == "" {
+		opts.ExcludeRequestBody = true
+		return true
+	}
+
+	if body.Content.Get(contentType) == nil {
+		accepted := strings.Join(slices.Sorted(maps.Keys(body.Content)), ", ")
+		if r.Method == http.MethodPatch {
+			w.Header().Set("Accept-Patch", accepted)
+		}
+		writeError(w, http.StatusUnsupportedMediaType, oapi.Error{
+			Code:    oapi.ErrorCodeUnsupportedMediaType,
+			Message: "request body must be one of " + accepted,
+		})
+		return false
+	}
+	if !isJSONMediaType(contentType) {
+		opts.ExcludeRequestBody = true
+		return true
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, v.maxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, oapi.Error{
+				Code:    oapi.ErrorCodePayloadTooLarge,
+				Message: fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit),
+			})
+			return false
+		}
+		writeError(w, http.StatusBadRequest, oapi.Error{Code: oapi.ErrorCodeBadRequest, Message: "error reading request body"})
+		return false
+	}
+	if len(data) > 0 && !json.Valid(data) {
+		writeError(w, http.StatusBadRequest, oapi.Error{
+			Code:    oapi.ErrorCodeBadRequest,
+			Message: "request body must be a single JSON value",
+		})
+		return false
+	}
+
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	r.Body, _ = r.GetBody()
+	r.ContentLength = int64(len(data))
+	return true
+}
+
+func isJSONMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// jsonResponses reports whether every response of op is JSON, so that it
+// can be buffered for validation; streams and exports are not.
+func jsonResponses(op *openapi3.Operation) bool {
+	for _, response := range op.Responses.Map() {
+		if response.Value == nil {
+			continue
+		}
+		for contentType := range response.Value.Content {
+			if !isJSONMediaType(contentType) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// validationDetails lists the problems of a request, one per parameter or
+// body member.
+func validationDetails(err error) *[]oapi.ErrorDetail {
+	details := errorDetails(err, oapi.ErrorDetail{})
+	return &details
+}
+
+func errorDetails(err error, parent oapi.ErrorDetail) []oapi.ErrorDetail {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		var details []oapi.ErrorDetail
+		for _, inner := range e {
+			details = append(details, errorDetails(inner, parent)...)
+		}
+		return details
+
+	case *openapi3filter.RequestError:
+		detail := oapi.ErrorDetail{Location: oapi.ErrorDetailLocationBody, Message: e.Reason}
+		if p := e.Parameter; p != nil {
+			name := p.Name
+			detail.Location = oapi.ErrorDetailLocation(p.In)
+			detail.Field = &name
+		}
+		if e.Err == nil {
+			return []oapi.ErrorDetail{detail}
+		}
+		return errorDetails(e.Err, detail)
+
+	case *openapi3.SchemaError:
+		detail := parent
+		detail.Message = e.Reason
+		if pointer := e.JSONPointer(); detail.Location == oapi.ErrorDetailLocationBody && len(pointer) > 0 {
+			field := jsonPointer(pointer)
+			detail.Field = &field
+		}
+		return []oapi.ErrorDetail{detail}
+
+	default:
+		detail := parent
+		if detail.Location == "" {
+			detail.Location = oapi.ErrorDetailLocationBody
+		}
+		if detail.Message == "" {
+			detail.Message = err.Error()
+		} else {
+			detail.Message += ": " + err.Error()
+		}
+		return []oapi.ErrorDetail{detail}
+	}
+}
+
+// jsonPointer joins the tokens of an RFC 6901 JSON pointer.
+func jsonPointer(tokens []string) string {
+	escape := strings.NewReplacer("~", "~0", "/", "~1")
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteString("/")
+		b.WriteString(escape.Replace(token))
+	}
+	return b.String()
+}
+
+// responseBuffer holds a response until it has been checked against the spec.
+type responseBuffer struct {
+	w      http.ResponseWriter
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *responseBuffer) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(p)
+}
+
+// Unwrap lets handlers set deadlines on the underlying connection.
+func (b *responseBuffer) Unwrap() http.ResponseWriter {
+	return b.w
+}
+
+func (v *Validator) writeResponse(w http.ResponseWriter, buf *responseBuffer, input *openapi3filter.RequestValidationInput) {
+	buf.WriteHeader(http.StatusOK)
+
+	response := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 buf.status,
+		Header:                 buf.header,
+		Options:                &openapi3filter.Options{MultiError: true},
+	}
+	if err := openapi3filter.ValidateResponse(input.Request.Context(), response.SetBodyBytes(buf.body.Bytes())); err != nil {
+		slog.Error("response does not match the OpenAPI spec",
+			"method", input.Request.Method, "path", input.Route.Path, "status", buf.status, "error", err)
+		writeError(w, http.StatusInternalServerError, oapi.Error{
+			Code:    oapi.ErrorCodeInternalError,
+			Message: "response does not match the OpenAPI spec: " + err.Error(),
+		})
+		return
+	}
+
+	for key, values := range buf.header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(buf.status)
+	if buf.body.Len() == 0 {
+		return
+	}
+	if _, err := w.Write(buf.body.Bytes()); err != nil {
+		slog.Error("failed to write response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, resp oapi.Error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("failed to encode JSON response", "error", err)
+	}
+}
@@ -0,0 +1,198 @@
+//go:build unit
+
+package middleware_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dubininme/xm-assessment/api"
+	"github.com/dubininme/xm-assessment/internal/delivery/http/middleware"
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const companyID = "6f1c9e44-3f4a-4f0e-9a55-2b1f2d0c7a11"
+
+func newValidator(t *testing.T, responses bool) *middleware.Validator {
+	t.Helper()
+	v, err := middleware.NewValidator(api.Spec, middleware.ValidatorOptions{MaxBodyBytes: 256, ValidateResponses: responses})
+	require.NoError(t, err)
+	return v
+}
+
+// echo records the body it receives and answers 204.
+func echo(received *string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*received = string(body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func request(method, path, contentType, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req
+}
+
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) oapi.Error {
+	t.Helper()
+	var resp oapi.Error
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	return resp
+}
+
+func TestValidate_PassesValidRequests(t *testing.T) {
+	var received string
+	h := newValidator(t, false).Validate(echo(&received))
+
+	for name, req := range map[string]*http.Request{
+		"create": request(http.MethodPost, "/api/v1/companies", "application/json",
+			`{"name": "Acme", "employees_count": 10, "registered": true, "type": "Corporations"}`),
+		"no media type": request(http.MethodPatch, "/api/v1/companies/"+companyID, "", `{"employees_count": 5}`),
+		"merge patch": request(http.MethodPatch, "/api/v1/companies/"+companyID, "application/merge-patch+json",
+			`{"description": null}`),
+		"csv import": request(http.MethodPost, "/api/v1/companies/import", "text/csv",
+			"name,employees_count,registered,type\nAcme,10,true,Corporations\n"+strings.Repeat(" ", 512)),
+		"not in spec": request(http.MethodPost, "/internal/debug", "text/plain", "anything"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			body, _ := io.ReadAll(req.Body)
+			req.Body = io.NopCloser(strings.NewReader(string(body)))
+			received = ""
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+			assert.Equal(t, string(body), received, "the handler reads the body as sent")
+		})
+	}
+}
+
+func TestValidate_RejectsInvalidBodies(t *testing.T) {
+	var received string
+	h := newValidator(t, false).Validate(echo(&received))
+
+	tests := []struct {
+		name   string
+		body   string
+		fields []string
+	}{
+		{"unknown member", `{"name": "Acme", "employees_count": 1, "registered": true, "type": "Corporations", "founded": 1999}`, nil},
+		{"wrong type", `{"name": "Acme", "employees_count": "ten", "registered": true, "type": "Corporations"}`, []string{"/employees_count"}},
+		{"missing required", `{"name": "Acme"}`, nil},
+		{"invalid enum and length", `{"name": "A very long company name", "employees_count": 1, "registered": true, "type": "Guild"}`,
+			[]string{"/name", "/type"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, request(http.MethodPost, "/api/v1/companies", "application/json", tt.body))
+			require.Equal(t, http.StatusBadRequest, w.Code)
+
+			resp := decodeError(t, w)
+			assert.Equal(t, oapi.ErrorCodeBadRequest, resp.Code)
+			require.NotNil(t, resp.Details)
+			require.NotEmpty(t, *resp.Details)
+
+			var fields []string
+			for _, d := range *resp.Details {
+				assert.Equal(t, oapi.ErrorDetailLocationBody, d.Location)
+				assert.NotEmpty(t, d.Message)
+				if d.Field != nil {
+					fields = append(fields, *d.Field)
+				}
+			}
+			for _, field := range tt.fields {
+				assert.Contains(t, fields, field)
+			}
+		})
+	}
+	assert.Empty(t, received, "no invalid request reaches the handler")
+}
+
+func TestValidate_RejectsMalformedRequests(t *testing.T) {
+	var received string
+	h := newValidator(t, false).Validate(echo(&received))
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+		code   oapi.ErrorCode
+	}{
+		{"trailing garbage", request(http.MethodPost, "/api/v1/auth/token", "application/json",
+			`{"user_id": "u", "password": "p"} {"user_id": "v"}`), http.StatusBadRequest, oapi.ErrorCodeBadRequest},
+		{"too large", request(http.MethodPost, "/api/v1/auth/token", "application/json",
+			`{"user_id": "`+strings.Repeat("u", 512)+`", "password": "p"}`), http.StatusRequestEntityTooLarge, oapi.ErrorCodePayloadTooLarge},
+		{"media type", request(http.MethodPost, "/api/v1/companies", "text/plain", `name=Acme`),
+			http.StatusUnsupportedMediaType, oapi.ErrorCodeUnsupportedMediaType},
+		{"query parameter", request(http.MethodGet, "/api/v1/companies/events?last_event_id=soon", "", ""),
+			http.StatusBadRequest, oapi.ErrorCodeBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, tt.req)
+			require.Equal(t, tt.status, w.Code, w.Body.String())
+			assert.Equal(t, tt.code, decodeError(t, w).Code)
+		})
+	}
+	assert.Empty(t, received)
+}
+
+func TestValidate_PatchMediaType(t *testing.T) {
+	var received string
+	h := newValidator(t, false).Validate(echo(&received))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, request(http.MethodPatch, "/api/v1/companies/"+companyID, "text/plain", `name=Acme`))
+	require.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Contains(t, w.Header().Get("Accept-Patch"), "application/json-patch+json")
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, request(http.MethodPatch, "/api/v1/companies/"+companyID, "application/json-patch+json",
+		`[{"op": "merge", "path": "/name"}]`))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestValidate_Responses(t *testing.T) {
+	respond := func(body string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", `"v1"`)
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, body)
+		})
+	}
+	get := func() *http.Request {
+		return request(http.MethodGet, "/api/v1/companies/"+companyID, "", "")
+	}
+
+	valid := `{"id": "` + companyID + `", "name": "Acme", "employees_count": 1, "registered": true, "type": "Corporations"}`
+	w := httptest.NewRecorder()
+	newValidator(t, true).Validate(respond(valid)).ServeHTTP(w, get())
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, `"v1"`, w.Header().Get("ETag"))
+	assert.JSONEq(t, valid, w.Body.String())
+
+	invalid := `{"id": "` + companyID + `", "name": "Acme"}`
+	w = httptest.NewRecorder()
+	newValidator(t, true).Validate(respond(invalid)).ServeHTTP(w, get())
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, oapi.ErrorCodeInternalError, decodeError(t, w).Code)
+
+	w = httptest.NewRecorder()
+	newValidator(t, false).Validate(respond(invalid)).ServeHTTP(w, get())
+	assert.Equal(t, http.StatusOK, w.Code, "responses are only checked when asked to")
+}
@@ -3,8 +3,11 @@ package http
 import (
 	"net/http"
 
+	"github.com/dubininme/xm-assessment/api"
+	"github.com/dubininme/xm-assessment/internal/delivery/graphql"
 	"github.com/dubininme/xm-assessment/internal/delivery/http/handler"
 	"github.com/dubininme/xm-assessment/internal/delivery/http/middleware"
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
 	"github.com/gorilla/mux"
 )
 
@@ -12,27 +15,68 @@ func NewRouter(
 	companyHandler *handler.CompanyHandler,
 	healthHandler *handler.HealthHandler,
 	authHandler *handler.AuthHandler,
+	adminHandler *handler.AdminHandler,
+	webhookHandler *handler.WebhookHandler,
+	streamHandler *handler.StreamHandler,
+	transferHandler *handler.TransferHandler,
+	graphQLHandler *graphql.Handler,
 	authMiddleware *middleware.AuthMiddleware,
+	validator *middleware.Validator,
 ) *mux.Router {
 	router := mux.NewRouter().StrictSlash(true)
 
-	// Health check without prefix (for load balancers, k8s probes, etc.)
-	router.HandleFunc("/health", healthHandler.Health).Methods(http.MethodGet)
+	// The spec itself, and a page to browse it
+	docs := handler.NewDocsHandler(api.Spec)
+	router.HandleFunc("/openapi.yaml", docs.Spec).Methods(http.MethodGet)
+	router.HandleFunc("/docs", docs.UI).Methods(http.MethodGet)
+	for _, asset := range handler.DocsAssets {
+		router.HandleFunc("/docs/"+asset, docs.Asset).Methods(http.MethodGet)
+	}
 
-	// API v1 routes
-	apiV1 := router.PathPrefix("/api/v1").Subrouter()
+	// GraphQL is not in the OpenAPI spec; its mutations check the user that
+	// Identify finds, its queries are public
+	if graphQLHandler != nil {
+		router.Handle("/graphql", authMiddleware.Identify(graphQLHandler)).Methods(http.MethodPost)
+	} else {
+		router.Handle("/graphql", handler.NotEnabled("GraphQL"))
+	}
 
-	// Public routes
-	apiV1.HandleFunc("/companies/{id}", companyHandler.GetCompany).Methods(http.MethodGet)
-	apiV1.HandleFunc("/auth/token", authHandler.GenerateToken).Methods(http.MethodPost)
+	// Features that are off in this deployment answer 404; these routes are
+	// registered first, so they shadow the operations of the spec
+	if streamHandler == nil {
+		// The event stream reads the outbox, so it is absent with STORAGE=memory
+		router.Handle("/api/v1/companies/events", handler.NotEnabled("the event stream")).Methods(http.MethodGet)
+	}
+	if adminHandler == nil {
+		// Outbox tooling needs the postgres outbox, so it is absent with STORAGE=memory
+		router.PathPrefix("/api/v1/admin/outbox/").Handler(handler.NotEnabled("the outbox tooling"))
+	}
+	if webhookHandler == nil {
+		// Webhook subscriptions are only served when the subscriptions sink runs
+		router.PathPrefix("/api/v1/webhooks").Handler(handler.NotEnabled("webhook subscriptions"))
+	}
 
-	// Protected routes
-	protected := apiV1.NewRoute().Subrouter()
-	protected.Use(authMiddleware.Authenticate)
+	// The generated server applies the last middleware first: a request is
+	// authenticated, when its operation is secured, before it is validated
+	middlewares := []oapi.MiddlewareFunc{authMiddleware.AuthenticateSecured}
+	if validator != nil {
+		middlewares = []oapi.MiddlewareFunc{validator.Validate, authMiddleware.AuthenticateSecured}
+	}
 
-	protected.HandleFunc("/companies", companyHandler.CreateCompany).Methods(http.MethodPost)
-	protected.HandleFunc("/companies/{id}", companyHandler.UpdateCompany).Methods(http.MethodPatch)
-	protected.HandleFunc("/companies/{id}", companyHandler.DeleteCompany).Methods(http.MethodDelete)
+	server := &Server{
+		CompanyHandler:  companyHandler,
+		TransferHandler: transferHandler,
+		AuthHandler:     authHandler,
+		HealthHandler:   healthHandler,
+		StreamHandler:   streamHandler,
+		AdminHandler:    adminHandler,
+		WebhookHandler:  webhookHandler,
+	}
+	oapi.HandlerWithOptions(server, oapi.GorillaServerOptions{
+		BaseRouter:       router,
+		Middlewares:      middlewares,
+		ErrorHandlerFunc: handler.WriteParamErr,
+	})
 
 	return router
 }
@@ -0,0 +1,162 @@
+//go:build unit
+
+package http_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dubininme/xm-assessment/api"
+	"github.com/dubininme/xm-assessment/internal/delivery/graphql"
+	deliveryHttp "github.com/dubininme/xm-assessment/internal/delivery/http"
+	"github.com/dubininme/xm-assessment/internal/delivery/http/handler"
+	"github.com/dubininme/xm-assessment/internal/delivery/http/middleware"
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/domain/webhook"
+	"github.com/dubininme/xm-assessment/internal/infra/auth"
+	"github.com/dubininme/xm-assessment/internal/infra/memory"
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRouter builds the router over memory storage; optional features are on
+// when all is set.
+func newRouter(t *testing.T, all bool) *mux.Router {
+	t.Helper()
+	store := memory.NewStore()
+	service := company.NewCompanyService(memory.NewCompanyRepo(store), memory.NewEventsPublisher(store), memory.NewTxManager(store))
+	jwtService := auth.NewJWTService("test-secret")
+
+	var (
+		adminHandler   *handler.AdminHandler
+		webhookHandler *handler.WebhookHandler
+		streamHandler  *handler.StreamHandler
+		graphQLHandler *graphql.Handler
+	)
+	if all {
+		// Only routing is exercised, so the features need no backing
+		adminHandler = handler.NewAdminHandler(nil)
+		webhookHandler = handler.NewWebhookHandler(webhook.NewService(nil, nil, webhook.TargetPolicy{}))
+		streamHandler = handler.NewStreamHandler(nil, time.Minute, time.Second)
+
+		var err error
+		graphQLHandler, err = graphql.NewHandler(api.GraphQLSchema, service, 1<<20)
+		require.NoError(t, err)
+	}
+
+	return deliveryHttp.NewRouter(handler.NewCompanyHandler(service, ""), handler.NewHealthHandler(), handler.NewAuthHandler(jwtService),
+		adminHandler, webhookHandler, streamHandler, handler.NewTransferHandler(t.Context(), service, 1<<20, 64<<10, time.Minute),
+		graphQLHandler, middleware.NewAuthMiddleware(jwtService), nil)
+}
+
+func loadSpec(t *testing.T) *openapi3.T {
+	t.Helper()
+	doc, err := openapi3.NewLoader().LoadFromData(api.Spec)
+	require.NoError(t, err)
+	return doc
+}
+
+func TestRouter_RoutesEveryOperation(t *testing.T) {
+	router := newRouter(t, true)
+	fill := strings.NewReplacer("{id}", "6f1c9e44-3f4a-4f0e-9a55-2b1f2d0c7a11", "{delivery_id}", "1")
+
+	for path, item := range loadSpec(t).Paths.Map() {
+		for method, op := range item.Operations() {
+			t.Run(op.OperationID, func(t *testing.T) {
+				var match mux.RouteMatch
+				require.True(t, router.Match(httptest.NewRequest(method, fill.Replace(path), nil), &match),
+					"%s %s has no route", method, path)
+				require.NoError(t, match.MatchErr)
+
+				template, err := match.Route.GetPathTemplate()
+				require.NoError(t, err)
+				assert.Equal(t, path, template, "another route takes %s %s", method, path)
+			})
+		}
+	}
+}
+
+func TestRouter_ServesNothingOutsideTheSpec(t *testing.T) {
+	paths := loadSpec(t).Paths
+	extra := map[string]bool{"/openapi.yaml": true, "/docs": true, "/graphql": true}
+	for _, asset := range handler.DocsAssets {
+		extra["/docs/"+asset] = true
+	}
+
+	err := newRouter(t, true).Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		assert.True(t, paths.Find(template) != nil || extra[template], "%s is not in the spec", template)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestRouter_SecuredOperations(t *testing.T) {
+	router := newRouter(t, false)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{"secured without token", http.MethodPost, "/api/v1/companies", http.StatusUnauthorized},
+		{"secured export", http.MethodGet, "/api/v1/companies/export", http.StatusUnauthorized},
+		{"public", http.MethodGet, "/api/v1/companies/6f1c9e44-3f4a-4f0e-9a55-2b1f2d0c7a11", http.StatusNotFound},
+		{"invalid path parameter", http.MethodGet, "/api/v1/companies/nope", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			require.Equal(t, tt.status, w.Code, w.Body.String())
+
+			var resp oapi.Error
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		})
+	}
+}
+
+func TestRouter_DisabledFeatures(t *testing.T) {
+	router := newRouter(t, false)
+
+	for _, path := range []string{"/api/v1/companies/events", "/api/v1/admin/outbox/jobs/6f1c9e44-3f4a-4f0e-9a55-2b1f2d0c7a11", "/api/v1/webhooks", "/graphql"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+		assert.Contains(t, w.Body.String(), "is not enabled", path)
+	}
+}
+
+func TestRouter_ServesSpec(t *testing.T) {
+	router := newRouter(t, false)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.yaml", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, api.Spec, w.Body.Bytes())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `url: "/openapi.yaml"`)
+	assert.NotContains(t, w.Body.String(), "https://", "every asset is served from this origin")
+
+	for _, asset := range handler.DocsAssets {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/"+asset, nil))
+		require.Equal(t, http.StatusOK, w.Code, asset)
+		assert.NotEmpty(t, w.Body.Bytes(), asset)
+	}
+}
@@ -0,0 +1,21 @@
+package http
+
+import (
+	"github.com/dubininme/xm-assessment/internal/delivery/http/handler"
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+)
+
+// Server implements the operations of the OpenAPI spec with the handlers of
+// each resource. The compiler checks it against the generated interface, so
+// an operation added to the spec does not build until it is implemented.
+type Server struct {
+	*handler.CompanyHandler
+	*handler.TransferHandler
+	*handler.AuthHandler
+	*handler.HealthHandler
+	*handler.StreamHandler
+	*handler.AdminHandler
+	*handler.WebhookHandler
+}
+
+var _ oapi.ServerInterface = (*Server)(nil)
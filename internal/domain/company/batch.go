@@ -0,0 +1,152 @@
+package company
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// MaxBatchOperations caps the operations of one batch.
+const MaxBatchOperations = 500
+
+var ErrEmptyBatch = errors.New("batch has no operations")
+var ErrBatchTooLarge = fmt.Errorf("batch has more than %d operations", MaxBatchOperations)
+var ErrInvalidBatchMode = errors.New("invalid batch mode")
+var ErrInvalidBatchAction = errors.New("invalid batch action")
+
+type BatchMode string
+
+const (
+	// BatchAllOrNothing applies every operation in one transaction, or none
+	// if any of them fails
+	BatchAllOrNothing BatchMode = "all_or_nothing"
+	// BatchBestEffort applies each operation in its own transaction and
+	// carries on past failures
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+type BatchAction string
+
+const (
+	BatchCreate BatchAction = "create"
+	BatchUpdate BatchAction = "update"
+	BatchDelete BatchAction = "delete"
+)
+
+// BatchOperation is one item of a batch. CompanyID is required for updates
+// and deletes; Create and Update hold the params of their action.
+type BatchOperation struct {
+	Action    BatchAction
+	CompanyID string
+	Create    CreateParams
+	Update    UpdateParams
+}
+
+type BatchStatus string
+
+const (
+	BatchSucceeded BatchStatus = "succeeded"
+	BatchFailed    BatchStatus = "failed"
+	// BatchAborted marks the other operations of an all-or-nothing batch
+	// that failed: rolled back or never attempted
+	BatchAborted BatchStatus = "aborted"
+)
+
+// BatchResult is the outcome of the operation at the same index. Company is
+// the state after a create or update; Err is set when Status is failed.
+type BatchResult struct {
+	Action    BatchAction
+	Status    BatchStatus
+	CompanyID string
+	Company   *Company
+	Err       error
+}
+
+// Batch applies ops in order and reports the outcome of each. Every applied
+// operation publishes the same events as its single-item counterpart. In
+// all-or-nothing mode a failed operation aborts the others and is reported
+// in its result; the returned error is only set when the batch is invalid or
+// its transaction could not be committed.
+func (s *CompanyService) Batch(ctx context.Context, mode BatchMode, ops []BatchOperation) ([]BatchResult, error) {
+	switch {
+	case len(ops) == 0:
+		return nil, ErrEmptyBatch
+	case len(ops) > MaxBatchOperations:
+		return nil, ErrBatchTooLarge
+	}
+
+	switch mode {
+	case BatchBestEffort:
+		results := make([]BatchResult, len(ops))
+		for i, op := range ops {
+			results[i] = s.applyBatchOperation(ctx, op)
+		}
+		return results, nil
+	case BatchAllOrNothing:
+		return s.batchAllOrNothing(ctx, ops)
+	default:
+		return nil, ErrInvalidBatchMode
+	}
+}
+
+// batchAllOrNothing nests the operations' transactions in one outer
+// REPEATABLE READ transaction, the strictest isolation they ask for. The
+// outer transaction may be retried, so the results are rebuilt each time.
+func (s *CompanyService) batchAllOrNothing(ctx context.Context, ops []BatchOperation) ([]BatchResult, error) {
+	var results []BatchResult
+	failed := -1
+
+	err := s.txManager.Do(ctx, func(ctx context.Context) error {
+		results = make([]BatchResult, len(ops))
+		failed = -1
+
+		for i, op := range ops {
+			results[i] = s.applyBatchOperation(ctx, op)
+			if results[i].Err != nil {
+				failed = i
+				return results[i].Err
+			}
+		}
+		return nil
+	}, TxOptions{Isolation: IsolationRepeatableRead})
+
+	if err == nil {
+		return results, nil
+	}
+	if failed < 0 {
+		return nil, fmt.Errorf("failed to commit batch: %w", err)
+	}
+
+	for i := range results {
+		if i != failed {
+			results[i] = BatchResult{Action: ops[i].Action, Status: BatchAborted, CompanyID: ops[i].CompanyID}
+		}
+	}
+	return results, nil
+}
+
+func (s *CompanyService) applyBatchOperation(ctx context.Context, op BatchOperation) BatchResult {
+	res := BatchResult{Action: op.Action, CompanyID: op.CompanyID}
+
+	var err error
+	switch op.Action {
+	case BatchCreate:
+		res.Company, err = s.CreateCompany(ctx, op.Create)
+		if err == nil {
+			res.CompanyID = res.Company.ID().String()
+		}
+	case BatchUpdate:
+		res.Company, err = s.UpdateCompany(ctx, op.CompanyID, op.Update)
+	case BatchDelete:
+		err = s.DeleteCompany(ctx, op.CompanyID)
+	default:
+		err = ErrInvalidBatchAction
+	}
+
+	if err != nil {
+		res.Status, res.Company, res.Err = BatchFailed, nil, err
+		return res
+	}
+	res.Status = BatchSucceeded
+	return res
+}
@@ -0,0 +1,84 @@
+//go:build unit
+
+package company
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func createOperation(name string) BatchOperation {
+	return BatchOperation{Action: BatchCreate, Create: CreateParams{Name: name, EmployeesCount: 10, Type: "Corporations"}}
+}
+
+func TestBatch_BestEffortCarriesOn(t *testing.T) {
+	service, mockRepo, mockPublisher, mockTxManager := setupServiceMocks(t)
+	missingID := uuid.New().String()
+
+	mockTxManager.On("Do", mock.Anything, mock.AnythingOfType("func(context.Context) error")).Return(nil)
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("company.Company")).Return(nil)
+	mockRepo.On("GetByID", mock.Anything, missingID).Return(nil, ErrCompanyNotFound)
+	mockPublisher.On("Publish", mock.Anything, isCompanyCreatedEvent()).Return(nil)
+
+	results, err := service.Batch(context.Background(), BatchBestEffort, []BatchOperation{
+		createOperation("First"),
+		{Action: BatchDelete, CompanyID: missingID},
+		createOperation(""),
+		createOperation("Second"),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+	assert.Equal(t, BatchSucceeded, results[0].Status)
+	assert.Equal(t, results[0].Company.ID().String(), results[0].CompanyID)
+	assert.ErrorIs(t, results[1].Err, ErrCompanyNotFound)
+	assert.Equal(t, missingID, results[1].CompanyID)
+	assert.ErrorIs(t, results[2].Err, ErrInvalidCompanyNameLength)
+	assert.Equal(t, BatchSucceeded, results[3].Status)
+
+	mockRepo.AssertNumberOfCalls(t, "Create", 2)
+	mockPublisher.AssertNumberOfCalls(t, "Publish", 2)
+}
+
+func TestBatch_AllOrNothingAbortsOnFailure(t *testing.T) {
+	service, mockRepo, mockPublisher, mockTxManager := setupServiceMocks(t)
+
+	mockTxManager.On("Do", mock.Anything, mock.AnythingOfType("func(context.Context) error")).Return(nil)
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("company.Company")).Return(nil)
+	mockPublisher.On("Publish", mock.Anything, isCompanyCreatedEvent()).Return(nil)
+
+	results, err := service.Batch(context.Background(), BatchAllOrNothing, []BatchOperation{
+		createOperation("First"),
+		{Action: BatchUpdate, CompanyID: uuid.New().String()},
+		createOperation("Third"),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, BatchAborted, results[0].Status)
+	assert.Nil(t, results[0].Company, "rolled back")
+	assert.Equal(t, BatchFailed, results[1].Status)
+	assert.ErrorIs(t, results[1].Err, ErrNoFieldsToUpdate)
+	assert.Equal(t, BatchAborted, results[2].Status)
+
+	mockRepo.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func TestBatch_InvalidBatch(t *testing.T) {
+	service, _, _, _ := setupServiceMocks(t)
+	ctx := context.Background()
+
+	_, err := service.Batch(ctx, BatchAllOrNothing, nil)
+	assert.ErrorIs(t, err, ErrEmptyBatch)
+
+	_, err = service.Batch(ctx, BatchAllOrNothing, make([]BatchOperation, MaxBatchOperations+1))
+	assert.ErrorIs(t, err, ErrBatchTooLarge)
+
+	_, err = service.Batch(ctx, "sometimes", []BatchOperation{createOperation("First")})
+	assert.ErrorIs(t, err, ErrInvalidBatchMode)
+}
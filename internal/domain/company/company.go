@@ -2,6 +2,7 @@ package company
 
 import (
 	"fmt"
+	"time"
 	"unicode/utf8"
 
 	"github.com/google/uuid"
@@ -113,6 +114,17 @@ func NewEmployeesCount(count int) (*EmployeesCount, error) {
 	return &c, nil
 }
 
+// Field is a bit flag identifying a mutable company field for dirty tracking.
+type Field uint8
+
+const (
+	FieldName Field = 1 << iota
+	FieldDescription
+	FieldEmployeesCount
+	FieldRegistered
+	FieldType
+)
+
 type Company struct {
 	id             uuid.UUID
 	name           CompanyName
@@ -120,6 +132,12 @@ type Company struct {
 	employeesCount EmployeesCount
 	registered     bool
 	cType          CompanyType
+
+	// updatedAt is when the company was last written; zero until it is
+	updatedAt time.Time
+
+	// dirty tracks fields whose value changed since the company was loaded
+	dirty Field
 }
 
 func NewCompany(id uuid.UUID, name string, description string, employeesCount int, companyType string) (*Company, error) {
@@ -178,8 +196,20 @@ func (c *Company) IsRegistered() bool {
 	return c.registered
 }
 
+// UpdatedAt returns when the company was last created or changed.
+func (c *Company) UpdatedAt() time.Time {
+	return c.updatedAt
+}
+
+// SetUpdatedAt records when the company was written. The service sets it on
+// every write and repositories restore it on load; it is not a field of the
+// company, so it is not tracked as dirty.
+func (c *Company) SetUpdatedAt(t time.Time) {
+	c.updatedAt = t
+}
+
 func (c *Company) Register() {
-	c.registered = true
+	c.SetRegistered(true)
 }
 
 func (c *Company) SetName(name string) error {
@@ -187,7 +217,10 @@ func (c *Company) SetName(name string) error {
 	if err != nil {
 		return err
 	}
-	c.name = *n
+	if c.name != *n {
+		c.name = *n
+		c.dirty |= FieldName
+	}
 	return nil
 }
 
@@ -196,7 +229,10 @@ func (c *Company) SetDescription(desc string) error {
 	if err != nil {
 		return err
 	}
-	c.description = *d
+	if c.description != *d {
+		c.description = *d
+		c.dirty |= FieldDescription
+	}
 	return nil
 }
 
@@ -205,7 +241,10 @@ func (c *Company) SetEmployeesCount(count int) error {
 	if err != nil {
 		return err
 	}
-	c.employeesCount = *e
+	if c.employeesCount != *e {
+		c.employeesCount = *e
+		c.dirty |= FieldEmployeesCount
+	}
 	return nil
 }
 
@@ -214,10 +253,32 @@ func (c *Company) SetType(t string) error {
 	if err != nil {
 		return err
 	}
-	c.cType = *ct
+	if c.cType != *ct {
+		c.cType = *ct
+		c.dirty |= FieldType
+	}
 	return nil
 }
 
 func (c *Company) SetRegistered(r bool) {
-	c.registered = r
+	if c.registered != r {
+		c.registered = r
+		c.dirty |= FieldRegistered
+	}
+}
+
+// IsDirty reports whether any field changed since the last ClearDirty.
+func (c *Company) IsDirty() bool {
+	return c.dirty != 0
+}
+
+// IsFieldDirty reports whether field f changed since the last ClearDirty.
+func (c *Company) IsFieldDirty(f Field) bool {
+	return c.dirty&f != 0
+}
+
+// ClearDirty marks the company as in sync with storage. Repositories call it
+// on every company they load or persist.
+func (c *Company) ClearDirty() {
+	c.dirty = 0
 }
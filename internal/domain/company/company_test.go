@@ -90,3 +90,24 @@ func TestCompany_SetRegistered(t *testing.T) {
 	c.SetRegistered(false)
 	assert.False(t, c.IsRegistered())
 }
+
+func TestCompany_DirtyTracking(t *testing.T) {
+	c, _ := NewCompany(uuid.New(), "Name", "Desc", 10, "Corporations")
+	assert.False(t, c.IsDirty())
+
+	require.NoError(t, c.SetName("Name"))
+	require.NoError(t, c.SetEmployeesCount(10))
+	c.SetRegistered(false)
+	assert.False(t, c.IsDirty(), "setting the current value is not a change")
+
+	require.NoError(t, c.SetDescription("Other"))
+	c.Register()
+	assert.True(t, c.IsDirty())
+	assert.True(t, c.IsFieldDirty(FieldRegistered))
+	assert.True(t, c.IsFieldDirty(FieldDescription))
+	assert.False(t, c.IsFieldDirty(FieldName))
+
+	c.ClearDirty()
+	assert.False(t, c.IsDirty())
+	assert.False(t, c.IsFieldDirty(FieldRegistered))
+}
@@ -2,22 +2,92 @@ package company
 
 import "time"
 
+// EventSchemaVersion is bumped whenever the event payload format changes.
+//
+// Version 1 carried only the request fields: the created params, the updated
+// fields that were set, or just the ID on delete. Version 2 adds full company
+// snapshots. The version 1 fields are kept at the top level of the payload, so
+// version 1 consumers keep working.
+const EventSchemaVersion = 2
+
+// Event names, as returned by EventName.
+const (
+	EventCompanyCreated      = "CompanyCreated"
+	EventCompanyUpdated      = "CompanyUpdated"
+	EventCompanyDeleted      = "CompanyDeleted"
+	EventCompanyRegistered   = "CompanyRegistered"
+	EventCompanyUnregistered = "CompanyUnregistered"
+	EventCompanySnapshot     = "CompanySnapshot"
+)
+
+// EventNames lists every event the company aggregate emits.
+var EventNames = []string{
+	EventCompanyCreated,
+	EventCompanyUpdated,
+	EventCompanyDeleted,
+	EventCompanyRegistered,
+	EventCompanyUnregistered,
+	EventCompanySnapshot,
+}
+
+// Snapshot is the full state of a company as carried by events.
+type Snapshot struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	EmployeesCount int    `json:"employees_count"`
+	Registered     bool   `json:"registered"`
+	Type           string `json:"type"`
+}
+
+func (c *Company) Snapshot() Snapshot {
+	return Snapshot{
+		ID:             c.ID().String(),
+		Name:           c.Name().String(),
+		Description:    c.Description().String(),
+		EmployeesCount: c.EmployeesCount().Int(),
+		Registered:     c.IsRegistered(),
+		Type:           c.CompanyType().String(),
+	}
+}
+
+// ChangedFields lists the JSON names of the fields that differ between s and other.
+func (s Snapshot) ChangedFields(other Snapshot) []string {
+	changed := []string{}
+	if s.Name != other.Name {
+		changed = append(changed, "name")
+	}
+	if s.Description != other.Description {
+		changed = append(changed, "description")
+	}
+	if s.EmployeesCount != other.EmployeesCount {
+		changed = append(changed, "employees_count")
+	}
+	if s.Registered != other.Registered {
+		changed = append(changed, "registered")
+	}
+	if s.Type != other.Type {
+		changed = append(changed, "type")
+	}
+	return changed
+}
+
 type CompanyCreatedEvent struct {
 	companyID string
 	created   int64
-	payload   CreateParams
+	snapshot  Snapshot
 }
 
-func NewCompanyCreatedEvent(companyID string, params CreateParams) CompanyCreatedEvent {
+func NewCompanyCreatedEvent(c *Company) CompanyCreatedEvent {
 	return CompanyCreatedEvent{
-		companyID: companyID,
+		companyID: c.ID().String(),
 		created:   time.Now().Unix(),
-		payload:   params,
+		snapshot:  c.Snapshot(),
 	}
 }
 
 func (e CompanyCreatedEvent) EventName() string {
-	return "CompanyCreated"
+	return EventCompanyCreated
 }
 
 func (e CompanyCreatedEvent) AggregateID() string {
@@ -28,13 +98,26 @@ func (e CompanyCreatedEvent) CreatedAt() int64 {
 	return e.created
 }
 
+// CompanyCreatedPayload is the CompanyCreated message; its schema lives in api/schemas.
+type CompanyCreatedPayload struct {
+	SchemaVersion int    `json:"schema_version"`
+	CompanyID     string `json:"company_id"`
+	CreateParams
+	Company Snapshot `json:"company"`
+}
+
 func (e CompanyCreatedEvent) Payload() any {
-	return struct {
-		CompanyID string `json:"company_id"`
-		CreateParams
-	}{
-		CompanyID:    e.companyID,
-		CreateParams: e.payload,
+	return CompanyCreatedPayload{
+		SchemaVersion: EventSchemaVersion,
+		CompanyID:     e.companyID,
+		CreateParams: CreateParams{
+			Name:           e.snapshot.Name,
+			Description:    e.snapshot.Description,
+			EmployeesCount: e.snapshot.EmployeesCount,
+			Registered:     e.snapshot.Registered,
+			Type:           e.snapshot.Type,
+		},
+		Company: e.snapshot,
 	}
 }
 
@@ -42,18 +125,22 @@ type CompanyUpdatedEvent struct {
 	companyID string
 	created   int64
 	payload   UpdateParams
+	previous  Snapshot
+	current   Snapshot
 }
 
-func NewCompanyUpdatedEvent(companyID string, params UpdateParams) CompanyUpdatedEvent {
+func NewCompanyUpdatedEvent(previous, current *Company, params UpdateParams) CompanyUpdatedEvent {
 	return CompanyUpdatedEvent{
-		companyID: companyID,
+		companyID: current.ID().String(),
 		created:   time.Now().Unix(),
 		payload:   params,
+		previous:  previous.Snapshot(),
+		current:   current.Snapshot(),
 	}
 }
 
 func (e CompanyUpdatedEvent) EventName() string {
-	return "CompanyUpdated"
+	return EventCompanyUpdated
 }
 
 func (e CompanyUpdatedEvent) AggregateID() string {
@@ -64,30 +151,43 @@ func (e CompanyUpdatedEvent) CreatedAt() int64 {
 	return e.created
 }
 
+// CompanyUpdatedPayload is the CompanyUpdated message; its schema lives in api/schemas.
+type CompanyUpdatedPayload struct {
+	SchemaVersion int    `json:"schema_version"`
+	CompanyID     string `json:"company_id"`
+	UpdateParams
+	Company       Snapshot `json:"company"`
+	Previous      Snapshot `json:"previous"`
+	ChangedFields []string `json:"changed_fields"`
+}
+
 func (e CompanyUpdatedEvent) Payload() any {
-	return struct {
-		CompanyID string `json:"company_id"`
-		UpdateParams
-	}{
-		CompanyID:    e.companyID,
-		UpdateParams: e.payload,
+	return CompanyUpdatedPayload{
+		SchemaVersion: EventSchemaVersion,
+		CompanyID:     e.companyID,
+		UpdateParams:  e.payload,
+		Company:       e.current,
+		Previous:      e.previous,
+		ChangedFields: e.previous.ChangedFields(e.current),
 	}
 }
 
 type CompanyDeletedEvent struct {
 	companyID string
 	created   int64
+	last      Snapshot
 }
 
-func NewCompanyDeletedEvent(companyID string) CompanyDeletedEvent {
+func NewCompanyDeletedEvent(last *Company) CompanyDeletedEvent {
 	return CompanyDeletedEvent{
-		companyID: companyID,
+		companyID: last.ID().String(),
 		created:   time.Now().Unix(),
+		last:      last.Snapshot(),
 	}
 }
 
 func (e CompanyDeletedEvent) EventName() string {
-	return "CompanyDeleted"
+	return EventCompanyDeleted
 }
 
 func (e CompanyDeletedEvent) AggregateID() string {
@@ -98,10 +198,108 @@ func (e CompanyDeletedEvent) CreatedAt() int64 {
 	return e.created
 }
 
+// CompanyDeletedPayload is the CompanyDeleted message; its schema lives in api/schemas.
+type CompanyDeletedPayload struct {
+	SchemaVersion int      `json:"schema_version"`
+	CompanyID     string   `json:"company_id"`
+	Company       Snapshot `json:"company"`
+}
+
 func (e CompanyDeletedEvent) Payload() any {
-	return struct {
-		CompanyID string `json:"company_id"`
-	}{
-		CompanyID: e.companyID,
+	return CompanyDeletedPayload{
+		SchemaVersion: EventSchemaVersion,
+		CompanyID:     e.companyID,
+		Company:       e.last,
+	}
+}
+
+// CompanyRegistrationChangedEvent is emitted alongside CompanyUpdated when the
+// registered flag flips, as CompanyRegistered or CompanyUnregistered.
+type CompanyRegistrationChangedEvent struct {
+	companyID string
+	created   int64
+	snapshot  Snapshot
+}
+
+func NewCompanyRegistrationChangedEvent(c *Company) CompanyRegistrationChangedEvent {
+	return CompanyRegistrationChangedEvent{
+		companyID: c.ID().String(),
+		created:   time.Now().Unix(),
+		snapshot:  c.Snapshot(),
+	}
+}
+
+func (e CompanyRegistrationChangedEvent) EventName() string {
+	if e.snapshot.Registered {
+		return EventCompanyRegistered
+	}
+	return EventCompanyUnregistered
+}
+
+func (e CompanyRegistrationChangedEvent) AggregateID() string {
+	return e.companyID
+}
+
+func (e CompanyRegistrationChangedEvent) CreatedAt() int64 {
+	return e.created
+}
+
+// CompanyRegistrationChangedPayload is the CompanyRegistered and
+// CompanyUnregistered message; its schema lives in api/schemas.
+type CompanyRegistrationChangedPayload struct {
+	SchemaVersion int      `json:"schema_version"`
+	CompanyID     string   `json:"company_id"`
+	Company       Snapshot `json:"company"`
+}
+
+func (e CompanyRegistrationChangedEvent) Payload() any {
+	return CompanyRegistrationChangedPayload{
+		SchemaVersion: EventSchemaVersion,
+		CompanyID:     e.companyID,
+		Company:       e.snapshot,
+	}
+}
+
+// CompanySnapshotEvent is a synthetic event carrying the current state of a
+// company. It is never written to the outbox; the snapshot tooling publishes
+// it for every company so new consumers can bootstrap.
+type CompanySnapshotEvent struct {
+	companyID string
+	created   int64
+	snapshot  Snapshot
+}
+
+func NewCompanySnapshotEvent(c *Company) CompanySnapshotEvent {
+	return CompanySnapshotEvent{
+		companyID: c.ID().String(),
+		created:   time.Now().Unix(),
+		snapshot:  c.Snapshot(),
+	}
+}
+
+func (e CompanySnapshotEvent) EventName() string {
+	return EventCompanySnapshot
+}
+
+func (e CompanySnapshotEvent) AggregateID() string {
+	return e.companyID
+}
+
+func (e CompanySnapshotEvent) CreatedAt() int64 {
+	return e.created
+}
+
+// CompanySnapshotPayload is the CompanySnapshot message; its schema lives in api/schemas.
+type CompanySnapshotPayload struct {
+	SchemaVersion int      `json:"schema_version"`
+	CompanyID     string   `json:"company_id"`
+	Company       Snapshot `json:"company"`
+}
+
+func (e CompanySnapshotEvent) Payload() any {
+	return CompanySnapshotPayload{
+		SchemaVersion: EventSchemaVersion,
+		CompanyID:     e.companyID,
+		Company:       e.snapshot,
 	}
 }
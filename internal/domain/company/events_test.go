@@ -0,0 +1,79 @@
+//go:build unit
+
+package company
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func payloadJSON(t *testing.T, payload any) map[string]any {
+	t.Helper()
+
+	raw, err := json.Marshal(payload)
+	require.NoError(t, err)
+
+	var out map[string]any
+	require.NoError(t, json.Unmarshal(raw, &out))
+	return out
+}
+
+func TestCompanyCreatedEvent_Payload(t *testing.T) {
+	c, _ := NewCompany(uuid.New(), "TechCorp", "", 50, "Corporations")
+	c.Register()
+
+	p := payloadJSON(t, NewCompanyCreatedEvent(c).Payload())
+
+	assert.EqualValues(t, EventSchemaVersion, p["schema_version"])
+	assert.Equal(t, c.ID().String(), p["company_id"])
+	// Version 1 fields stay at the top level
+	assert.Equal(t, "TechCorp", p["name"])
+	assert.EqualValues(t, 50, p["employees_count"])
+	assert.Equal(t, true, p["registered"])
+	assert.NotContains(t, p, "description")
+
+	snapshot := p["company"].(map[string]any)
+	assert.Equal(t, c.ID().String(), snapshot["id"])
+	assert.Equal(t, "", snapshot["description"])
+	assert.Equal(t, "Corporations", snapshot["type"])
+}
+
+func TestCompanyUpdatedEvent_Payload(t *testing.T) {
+	previous, _ := NewCompany(uuid.New(), "OldName", "Desc", 5, "Corporations")
+	current := *previous
+	require.NoError(t, current.SetName("NewName"))
+	current.SetRegistered(true)
+
+	newName := "NewName"
+	registered := true
+	params := UpdateParams{Name: &newName, Registered: &registered}
+
+	p := payloadJSON(t, NewCompanyUpdatedEvent(previous, &current, params).Payload())
+
+	assert.EqualValues(t, EventSchemaVersion, p["schema_version"])
+	assert.Equal(t, "NewName", p["name"])
+	assert.NotContains(t, p, "employees_count", "only set params are in the version 1 fields")
+	assert.Equal(t, "NewName", p["company"].(map[string]any)["name"])
+	assert.Equal(t, "OldName", p["previous"].(map[string]any)["name"])
+	assert.Equal(t, []any{"name", "registered"}, p["changed_fields"])
+}
+
+func TestCompanyDeletedEvent_Payload(t *testing.T) {
+	c, _ := NewCompany(uuid.New(), "Gone", "Desc", 5, "NonProfit")
+
+	p := payloadJSON(t, NewCompanyDeletedEvent(c).Payload())
+
+	assert.Equal(t, c.ID().String(), p["company_id"])
+	assert.Equal(t, "Gone", p["company"].(map[string]any)["name"])
+	assert.Equal(t, "NonProfit", p["company"].(map[string]any)["type"])
+}
+
+func TestSnapshot_ChangedFields_None(t *testing.T) {
+	c, _ := NewCompany(uuid.New(), "Same", "Desc", 5, "NonProfit")
+
+	assert.Empty(t, c.Snapshot().ChangedFields(c.Snapshot()))
+}
@@ -9,4 +9,8 @@ type CompanyRepository interface {
 	Update(ctx context.Context, company Company) error
 	Delete(ctx context.Context, companyID string) error
 	GetByID(ctx context.Context, companyID string) (*Company, error)
+	GetByName(ctx context.Context, name string) (*Company, error)
+	// List returns up to limit companies matching filter with an ID above
+	// afterID, in ID order; pass an empty afterID for the first page.
+	List(ctx context.Context, filter ListFilter, afterID string, limit int) ([]*Company, error)
 }
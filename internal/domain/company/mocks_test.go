@@ -27,6 +27,22 @@ func (m *MockCompanyRepository) GetByID(ctx context.Context, id string) (*Compan
 	return args.Get(0).(*Company), args.Error(1)
 }
 
+func (m *MockCompanyRepository) GetByName(ctx context.Context, name string) (*Company, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Company), args.Error(1)
+}
+
+func (m *MockCompanyRepository) List(ctx context.Context, filter ListFilter, afterID string, limit int) ([]*Company, error) {
+	args := m.Called(ctx, filter, afterID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*Company), args.Error(1)
+}
+
 func (m *MockCompanyRepository) Update(ctx context.Context, c Company) error {
 	args := m.Called(ctx, c)
 	return args.Error(0)
@@ -52,7 +68,7 @@ type MockTxManager struct {
 	mock.Mock
 }
 
-func (m *MockTxManager) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+func (m *MockTxManager) Do(ctx context.Context, fn func(ctx context.Context) error, _ ...TxOptions) error {
 	args := m.Called(ctx, fn)
 
 	// Always execute the function to simulate real transaction behavior
@@ -97,3 +113,10 @@ func isCompanyDeletedEvent() interface{} {
 		return ok
 	})
 }
+
+func isCompanyRegistrationChangedEvent(name string) interface{} {
+	return mock.MatchedBy(func(e events.Event) bool {
+		_, ok := e.(CompanyRegistrationChangedEvent)
+		return ok && e.EventName() == name
+	})
+}
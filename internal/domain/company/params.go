@@ -1,5 +1,10 @@
 package company
 
+import (
+	"slices"
+	"strings"
+)
+
 type CreateParams struct {
 	Name           string `json:"name"`
 	Description    string `json:"description,omitempty"`
@@ -24,3 +29,31 @@ func (p UpdateParams) IsEmpty() bool {
 		p.Registered == nil &&
 		p.Type == nil
 }
+
+// ListFilter narrows a List; a zero field matches every company.
+type ListFilter struct {
+	// IDs matches any of its values
+	IDs []string
+	// NameContains matches names containing it, ignoring case
+	NameContains string
+	Type         *CompanyType
+	Registered   *bool
+}
+
+// Matches reports whether c passes the filter, for storage that cannot
+// filter by itself.
+func (f ListFilter) Matches(c *Company) bool {
+	if len(f.IDs) > 0 && !slices.Contains(f.IDs, c.ID().String()) {
+		return false
+	}
+	if f.NameContains != "" && !strings.Contains(strings.ToLower(c.Name().String()), strings.ToLower(f.NameContains)) {
+		return false
+	}
+	if f.Type != nil && c.CompanyType() != *f.Type {
+		return false
+	}
+	if f.Registered != nil && c.IsRegistered() != *f.Registered {
+		return false
+	}
+	return true
+}
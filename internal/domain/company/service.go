@@ -2,14 +2,19 @@ package company
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/dubininme/xm-assessment/internal/domain/events"
 	"github.com/google/uuid"
 )
 
+// TxManager runs fn in a transaction. At most one TxOptions may be passed; a
+// nested Do runs fn in a savepoint of the outer transaction, so its error
+// rolls back only the nested work, and it is committed with the outer one.
 type TxManager interface {
-	Do(ctx context.Context, fn func(ctx context.Context) error) error
+	Do(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOptions) error
 }
 
 type CompanyService struct {
@@ -18,12 +23,22 @@ type CompanyService struct {
 	txManager TxManager
 }
 
+// now is the time of a write, at the microsecond resolution of Postgres so
+// the company returned equals the one read back.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
 func NewCompanyService(repo CompanyRepository, publisher events.EventsPublisher, txManager TxManager) *CompanyService {
 	return &CompanyService{repo: repo, publisher: publisher, txManager: txManager}
 }
 
 func (s *CompanyService) CreateCompany(ctx context.Context, params CreateParams) (*Company, error) {
-	c, err := NewCompany(uuid.New(), params.Name, params.Description, params.EmployeesCount, params.Type)
+	return s.createCompany(ctx, uuid.New(), params)
+}
+
+func (s *CompanyService) createCompany(ctx context.Context, id uuid.UUID, params CreateParams) (*Company, error) {
+	c, err := NewCompany(id, params.Name, params.Description, params.EmployeesCount, params.Type)
 	if err != nil {
 		return nil, err
 	}
@@ -32,6 +47,7 @@ func (s *CompanyService) CreateCompany(ctx context.Context, params CreateParams)
 	if params.Registered {
 		c.Register()
 	}
+	c.SetUpdatedAt(now())
 
 	err = s.txManager.Do(ctx, func(ctx context.Context) error {
 		err := s.repo.Create(ctx, *c)
@@ -39,10 +55,7 @@ func (s *CompanyService) CreateCompany(ctx context.Context, params CreateParams)
 			return fmt.Errorf("failed to create company: %w", err)
 		}
 
-		err = s.publisher.Publish(ctx, NewCompanyCreatedEvent(
-			c.ID().String(),
-			params,
-		))
+		err = s.publisher.Publish(ctx, NewCompanyCreatedEvent(c))
 
 		if err != nil {
 			return fmt.Errorf("failed to publish company created event: %w", err)
@@ -64,64 +77,137 @@ func (s *CompanyService) UpdateCompany(ctx context.Context, companyID string, pa
 		return nil, ErrNoFieldsToUpdate
 	}
 
-	c, err := s.repo.GetByID(ctx, companyID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get company by ID: %w", err)
-	}
+	var c *Company
 
-	if params.Name != nil {
-		err := c.SetName(*params.Name)
+	// The read and the write share a REPEATABLE READ transaction so concurrent
+	// updates of the same company fail with a serialization error and are
+	// retried on fresh data instead of silently overwriting each other.
+	err := s.txManager.Do(ctx, func(ctx context.Context) error {
+		previous, err := s.repo.GetByID(ctx, companyID)
 		if err != nil {
-			// Return validation errors directly without wrapping
-			return nil, err
+			return fmt.Errorf("failed to get company by ID: %w", err)
 		}
-	}
 
-	if params.Description != nil {
-		err := c.SetDescription(*params.Description)
+		updated := *previous
+		updated.ClearDirty()
+		c = &updated
+
+		// Return validation errors directly without wrapping
+		if err := applyUpdate(c, params); err != nil {
+			return err
+		}
+
+		// Every supplied value equals the current one: skip the write and the event
+		if !c.IsDirty() {
+			return nil
+		}
+		c.SetUpdatedAt(now())
+
+		err = s.repo.Update(ctx, *c)
 		if err != nil {
-			// Return validation errors directly without wrapping
-			return nil, err
+			return fmt.Errorf("failed to update company: %w", err)
 		}
-	}
 
-	if params.EmployeesCount != nil {
-		err := c.SetEmployeesCount(*params.EmployeesCount)
+		err = s.publisher.Publish(ctx, NewCompanyUpdatedEvent(previous, c, params))
+
 		if err != nil {
-			// Return validation errors directly without wrapping
-			return nil, err
+			return fmt.Errorf("failed to publish company updated event: %w", err)
+		}
+
+		if c.IsFieldDirty(FieldRegistered) {
+			err = s.publisher.Publish(ctx, NewCompanyRegistrationChangedEvent(c))
+			if err != nil {
+				return fmt.Errorf("failed to publish company registration event: %w", err)
+			}
 		}
+
+		return nil
+	}, TxOptions{Isolation: IsolationRepeatableRead})
+
+	if err != nil {
+		return nil, err
 	}
 
-	if params.Type != nil {
-		err := c.SetType(*params.Type)
+	return c, nil
+}
+
+// ReplaceCompany creates the company with the given ID, or replaces every
+// field of the existing one, publishing the same events as CreateCompany and
+// UpdateCompany. check, if set, is called in the same transaction with the
+// current state, nil when there is none, and aborts the write by returning an
+// error.
+func (s *CompanyService) ReplaceCompany(ctx context.Context, id uuid.UUID, params CreateParams, check func(current *Company) error) (*Company, UpsertResult, error) {
+	var c *Company
+	var result UpsertResult
+
+	err := s.txManager.Do(ctx, func(ctx context.Context) error {
+		current, err := s.repo.GetByID(ctx, id.String())
+		if err != nil && !errors.Is(err, ErrCompanyNotFound) {
+			return fmt.Errorf("failed to get company by ID: %w", err)
+		}
+
+		if check != nil {
+			if err := check(current); err != nil {
+				return err
+			}
+		}
+
+		if current == nil {
+			c, err = s.createCompany(ctx, id, params)
+			result = UpsertCreated
+			return err
+		}
+
+		c, err = s.UpdateCompany(ctx, id.String(), UpdateParams{
+			Name:           &params.Name,
+			Description:    &params.Description,
+			EmployeesCount: &params.EmployeesCount,
+			Registered:     &params.Registered,
+			Type:           &params.Type,
+		})
 		if err != nil {
-			// Return validation errors directly without wrapping
-			return nil, err
+			return err
 		}
-	}
 
-	if params.Registered != nil {
-		c.SetRegistered(*params.Registered)
+		result = UpsertUpdated
+		if sameFields(current, c) {
+			result = UpsertUnchanged
+		}
+		return nil
+	}, TxOptions{Isolation: IsolationRepeatableRead})
+
+	if err != nil {
+		return nil, "", err
 	}
+	return c, result, nil
+}
 
-	err = s.txManager.Do(ctx, func(ctx context.Context) error {
-		err := s.repo.Update(ctx, *c)
+// PatchCompany updates a company with the params fn derives from its current
+// state, for patches that check or depend on that state. The read and the
+// update share a REPEATABLE READ transaction, so what fn saw is what gets
+// updated. When fn asks for no change the company is returned as it is.
+func (s *CompanyService) PatchCompany(ctx context.Context, companyID string, fn func(current *Company) (UpdateParams, error)) (*Company, error) {
+	var c *Company
+
+	err := s.txManager.Do(ctx, func(ctx context.Context) error {
+		current, err := s.repo.GetByID(ctx, companyID)
 		if err != nil {
-			return fmt.Errorf("failed to update company: %w", err)
+			return fmt.Errorf("failed to get company by ID: %w", err)
 		}
 
-		err = s.publisher.Publish(ctx, NewCompanyUpdatedEvent(
-			c.ID().String(),
-			params,
-		))
-
+		params, err := fn(current)
 		if err != nil {
-			return fmt.Errorf("failed to publish company updated event: %w", err)
+			return err
 		}
 
-		return nil
-	})
+		if params.IsEmpty() {
+			c = current
+			return nil
+		}
+
+		c, err = s.UpdateCompany(ctx, companyID, params)
+		return err
+	}, TxOptions{Isolation: IsolationRepeatableRead})
 
 	if err != nil {
 		return nil, err
@@ -130,6 +216,38 @@ func (s *CompanyService) UpdateCompany(ctx context.Context, companyID string, pa
 	return c, nil
 }
 
+func applyUpdate(c *Company, params UpdateParams) error {
+	if params.Name != nil {
+		if err := c.SetName(*params.Name); err != nil {
+			return err
+		}
+	}
+
+	if params.Description != nil {
+		if err := c.SetDescription(*params.Description); err != nil {
+			return err
+		}
+	}
+
+	if params.EmployeesCount != nil {
+		if err := c.SetEmployeesCount(*params.EmployeesCount); err != nil {
+			return err
+		}
+	}
+
+	if params.Type != nil {
+		if err := c.SetType(*params.Type); err != nil {
+			return err
+		}
+	}
+
+	if params.Registered != nil {
+		c.SetRegistered(*params.Registered)
+	}
+
+	return nil
+}
+
 func (s *CompanyService) GetByID(ctx context.Context, companyID string) (*Company, error) {
 	company, err := s.repo.GetByID(ctx, companyID)
 	if err != nil {
@@ -139,18 +257,51 @@ func (s *CompanyService) GetByID(ctx context.Context, companyID string) (*Compan
 	return company, nil
 }
 
+// GetByIDs returns the companies with the given IDs that exist, in ID order,
+// in a single read.
+func (s *CompanyService) GetByIDs(ctx context.Context, companyIDs []string) ([]*Company, error) {
+	if len(companyIDs) == 0 {
+		return nil, nil
+	}
+
+	companies, err := s.repo.List(ctx, ListFilter{IDs: companyIDs}, "", len(companyIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get companies by ID: %w", err)
+	}
+
+	return companies, nil
+}
+
+// ListCompanies returns up to limit companies matching filter with an ID
+// above afterID, in ID order.
+func (s *CompanyService) ListCompanies(ctx context.Context, filter ListFilter, afterID string, limit int) ([]*Company, error) {
+	companies, err := s.repo.List(ctx, filter, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list companies: %w", err)
+	}
+
+	return companies, nil
+}
+
 func (s *CompanyService) DeleteCompany(ctx context.Context, companyID string) error {
+	// The last known state is read in the same REPEATABLE READ transaction so
+	// the deleted event carries exactly what was removed.
 	return s.txManager.Do(ctx, func(ctx context.Context) error {
-		err := s.repo.Delete(ctx, companyID)
+		last, err := s.repo.GetByID(ctx, companyID)
+		if err != nil {
+			return fmt.Errorf("failed to get company by ID: %w", err)
+		}
+
+		err = s.repo.Delete(ctx, companyID)
 		if err != nil {
 			return fmt.Errorf("failed to delete company: %w", err)
 		}
 
-		err = s.publisher.Publish(ctx, NewCompanyDeletedEvent(companyID))
+		err = s.publisher.Publish(ctx, NewCompanyDeletedEvent(last))
 		if err != nil {
 			return fmt.Errorf("failed to publish company deleted event: %w", err)
 		}
 
 		return nil
-	})
+	}, TxOptions{Isolation: IsolationRepeatableRead})
 }
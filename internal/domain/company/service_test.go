@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -46,6 +47,7 @@ func TestCreateCompany_Success(t *testing.T) {
 	assert.Equal(t, "TechCorp", company.Name().String())
 	assert.Equal(t, 50, company.EmployeesCount().Int())
 	assert.False(t, company.IsRegistered())
+	assert.WithinDuration(t, time.Now(), company.UpdatedAt(), time.Minute)
 
 	mockRepo.AssertExpectations(t)
 	mockPublisher.AssertExpectations(t)
@@ -96,6 +98,7 @@ func TestUpdateCompany_Success(t *testing.T) {
 
 	companyID := uuid.New()
 	existingCompany, _ := NewCompany(companyID, "OldName", "Old Desc", 5, "Corporations")
+	existingCompany.SetUpdatedAt(time.Now().Add(-time.Hour))
 
 	newName := "NewName"
 	newDesc := "New Description"
@@ -115,6 +118,136 @@ func TestUpdateCompany_Success(t *testing.T) {
 	assert.NotNil(t, result)
 	assert.Equal(t, "NewName", result.Name().String())
 	assert.Equal(t, "New Description", result.Description().String())
+	assert.WithinDuration(t, time.Now(), result.UpdatedAt(), time.Minute)
+	mockRepo.AssertExpectations(t)
+	mockPublisher.AssertExpectations(t)
+}
+
+func TestUpdateCompany_NoChanges(t *testing.T) {
+	service, mockRepo, mockPublisher, mockTxManager := setupServiceMocks(t)
+
+	companyID := uuid.New()
+	existingCompany, _ := NewCompany(companyID, "SameName", "Same Desc", 5, "Corporations")
+	updatedAt := time.Now().Add(-time.Hour)
+	existingCompany.SetUpdatedAt(updatedAt)
+
+	sameName := "SameName"
+	sameCount := 5
+	params := UpdateParams{Name: &sameName, EmployeesCount: &sameCount}
+
+	mockTxManager.On("Do", mock.Anything, mock.AnythingOfType("func(context.Context) error")).Return(nil)
+	mockRepo.On("GetByID", mock.Anything, companyID.String()).Return(existingCompany, nil)
+
+	result, err := service.UpdateCompany(context.Background(), companyID.String(), params)
+
+	require.NoError(t, err)
+	assert.Equal(t, "SameName", result.Name().String())
+	assert.False(t, result.IsDirty())
+	assert.Equal(t, updatedAt, result.UpdatedAt())
+	mockRepo.AssertNotCalled(t, "Update")
+	mockPublisher.AssertNotCalled(t, "Publish")
+}
+
+func TestReplaceCompany_CreatesWithGivenID(t *testing.T) {
+	service, mockRepo, mockPublisher, mockTxManager := setupServiceMocks(t)
+	companyID := uuid.New()
+
+	mockTxManager.On("Do", mock.Anything, mock.AnythingOfType("func(context.Context) error")).Return(nil)
+	mockRepo.On("GetByID", mock.Anything, companyID.String()).Return(nil, ErrCompanyNotFound)
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("company.Company")).Return(nil)
+	mockPublisher.On("Publish", mock.Anything, isCompanyCreatedEvent()).Return(nil)
+
+	var checked *Company
+	result, outcome, err := service.ReplaceCompany(context.Background(), companyID,
+		CreateParams{Name: "Mirrored", EmployeesCount: 3, Type: "Cooperative"},
+		func(current *Company) error {
+			checked = current
+			return nil
+		})
+
+	require.NoError(t, err)
+	assert.Nil(t, checked)
+	assert.Equal(t, UpsertCreated, outcome)
+	assert.Equal(t, companyID, result.ID())
+}
+
+func TestReplaceCompany_CheckAbortsWrite(t *testing.T) {
+	service, mockRepo, mockPublisher, mockTxManager := setupServiceMocks(t)
+	companyID := uuid.New()
+	existingCompany, _ := NewCompany(companyID, "Name", "Desc", 5, "Corporations")
+	errStale := errors.New("stale")
+
+	mockTxManager.On("Do", mock.Anything, mock.AnythingOfType("func(context.Context) error")).Return(nil)
+	mockRepo.On("GetByID", mock.Anything, companyID.String()).Return(existingCompany, nil)
+
+	_, _, err := service.ReplaceCompany(context.Background(), companyID,
+		CreateParams{Name: "Name", EmployeesCount: 6, Type: "Corporations"},
+		func(*Company) error { return errStale })
+
+	assert.ErrorIs(t, err, errStale)
+	mockRepo.AssertNotCalled(t, "Update")
+	mockPublisher.AssertNotCalled(t, "Publish")
+}
+
+func TestPatchCompany_UsesCurrentState(t *testing.T) {
+	service, mockRepo, mockPublisher, mockTxManager := setupServiceMocks(t)
+
+	companyID := uuid.New()
+	existingCompany, _ := NewCompany(companyID, "Name", "Desc", 5, "Corporations")
+
+	mockTxManager.On("Do", mock.Anything, mock.AnythingOfType("func(context.Context) error")).Return(nil)
+	mockRepo.On("GetByID", mock.Anything, companyID.String()).Return(existingCompany, nil)
+	mockRepo.On("Update", mock.Anything, mock.AnythingOfType("company.Company")).Return(nil)
+	mockPublisher.On("Publish", mock.Anything, mock.Anything).Return(nil)
+
+	result, err := service.PatchCompany(context.Background(), companyID.String(), func(current *Company) (UpdateParams, error) {
+		doubled := current.EmployeesCount().Int() * 2
+		return UpdateParams{EmployeesCount: &doubled}, nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 10, result.EmployeesCount().Int())
+	mockRepo.AssertNumberOfCalls(t, "Update", 1)
+}
+
+func TestPatchCompany_NoChangesReturnsCurrent(t *testing.T) {
+	service, mockRepo, mockPublisher, mockTxManager := setupServiceMocks(t)
+
+	companyID := uuid.New()
+	existingCompany, _ := NewCompany(companyID, "Name", "Desc", 5, "Corporations")
+
+	mockTxManager.On("Do", mock.Anything, mock.AnythingOfType("func(context.Context) error")).Return(nil)
+	mockRepo.On("GetByID", mock.Anything, companyID.String()).Return(existingCompany, nil)
+
+	result, err := service.PatchCompany(context.Background(), companyID.String(), func(*Company) (UpdateParams, error) {
+		return UpdateParams{}, nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, existingCompany, result)
+	mockRepo.AssertNotCalled(t, "Update")
+	mockPublisher.AssertNotCalled(t, "Publish")
+}
+
+func TestUpdateCompany_RegistrationFlip(t *testing.T) {
+	service, mockRepo, mockPublisher, mockTxManager := setupServiceMocks(t)
+
+	companyID := uuid.New()
+	existingCompany, _ := NewCompany(companyID, "Name", "Desc", 5, "Corporations")
+
+	registered := true
+	params := UpdateParams{Registered: &registered}
+
+	mockTxManager.On("Do", mock.Anything, mock.AnythingOfType("func(context.Context) error")).Return(nil)
+	mockRepo.On("GetByID", mock.Anything, companyID.String()).Return(existingCompany, nil)
+	mockRepo.On("Update", mock.Anything, mock.AnythingOfType("Company")).Return(nil)
+	mockPublisher.On("Publish", mock.Anything, isCompanyUpdatedEvent()).Return(nil).Once()
+	mockPublisher.On("Publish", mock.Anything, isCompanyRegistrationChangedEvent("CompanyRegistered")).Return(nil).Once()
+
+	result, err := service.UpdateCompany(context.Background(), companyID.String(), params)
+
+	require.NoError(t, err)
+	assert.True(t, result.IsRegistered())
 	mockRepo.AssertExpectations(t)
 	mockPublisher.AssertExpectations(t)
 }
@@ -136,12 +269,13 @@ func TestUpdateCompany_EmptyParams(t *testing.T) {
 }
 
 func TestUpdateCompany_NotFound(t *testing.T) {
-	service, mockRepo, mockPublisher, _ := setupServiceMocks(t)
+	service, mockRepo, mockPublisher, mockTxManager := setupServiceMocks(t)
 
 	companyID := uuid.New().String()
 	newName := "NewName"
 	params := UpdateParams{Name: &newName}
 
+	mockTxManager.On("Do", mock.Anything, mock.AnythingOfType("func(context.Context) error")).Return(nil)
 	mockRepo.On("GetByID", mock.Anything, companyID).Return(nil, ErrCompanyNotFound)
 
 	result, err := service.UpdateCompany(context.Background(), companyID, params)
@@ -156,13 +290,15 @@ func TestUpdateCompany_NotFound(t *testing.T) {
 func TestDeleteCompany_Success(t *testing.T) {
 	service, mockRepo, mockPublisher, mockTxManager := setupServiceMocks(t)
 
-	companyID := uuid.New().String()
+	companyID := uuid.New()
+	existingCompany, _ := NewCompany(companyID, "ToDelete", "Desc", 5, "Corporations")
 
 	mockTxManager.On("Do", mock.Anything, mock.AnythingOfType("func(context.Context) error")).Return(nil)
-	mockRepo.On("Delete", mock.Anything, companyID).Return(nil)
+	mockRepo.On("GetByID", mock.Anything, companyID.String()).Return(existingCompany, nil)
+	mockRepo.On("Delete", mock.Anything, companyID.String()).Return(nil)
 	mockPublisher.On("Publish", mock.Anything, isCompanyDeletedEvent()).Return(nil)
 
-	err := service.DeleteCompany(context.Background(), companyID)
+	err := service.DeleteCompany(context.Background(), companyID.String())
 
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
@@ -175,13 +311,14 @@ func TestDeleteCompany_NotFound(t *testing.T) {
 	companyID := uuid.New().String()
 
 	mockTxManager.On("Do", mock.Anything, mock.AnythingOfType("func(context.Context) error")).Return(ErrCompanyNotFound)
-	mockRepo.On("Delete", mock.Anything, companyID).Return(ErrCompanyNotFound)
+	mockRepo.On("GetByID", mock.Anything, companyID).Return(nil, ErrCompanyNotFound)
 
 	err := service.DeleteCompany(context.Background(), companyID)
 
 	assert.Error(t, err)
 	assert.ErrorIs(t, err, ErrCompanyNotFound)
 	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Delete")
 	// Важно: событие НЕ должно быть опубликовано
 	mockPublisher.AssertNotCalled(t, "Publish")
 }
@@ -189,14 +326,36 @@ func TestDeleteCompany_NotFound(t *testing.T) {
 func TestDeleteCompany_RepoError(t *testing.T) {
 	service, mockRepo, _, mockTxManager := setupServiceMocks(t)
 
-	companyID := uuid.New().String()
+	companyID := uuid.New()
+	existingCompany, _ := NewCompany(companyID, "ToDelete", "Desc", 5, "Corporations")
 	repoErr := errors.New("delete failed")
 
 	mockTxManager.On("Do", mock.Anything, mock.AnythingOfType("func(context.Context) error")).Return(nil)
-	mockRepo.On("Delete", mock.Anything, companyID).Return(repoErr)
+	mockRepo.On("GetByID", mock.Anything, companyID.String()).Return(existingCompany, nil)
+	mockRepo.On("Delete", mock.Anything, companyID.String()).Return(repoErr)
 
-	err := service.DeleteCompany(context.Background(), companyID)
+	err := service.DeleteCompany(context.Background(), companyID.String())
 
 	assert.Error(t, err)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestGetByIDs_SingleRead(t *testing.T) {
+	service, mockRepo, _, _ := setupServiceMocks(t)
+	ctx := context.Background()
+	ids := []string{uuid.NewString(), uuid.NewString()}
+	found, err := NewCompany(uuid.MustParse(ids[0]), "TechCorp", "", 5, "Corporations")
+	require.NoError(t, err)
+
+	mockRepo.On("List", ctx, ListFilter{IDs: ids}, "", 2).Return([]*Company{found}, nil).Once()
+
+	companies, err := service.GetByIDs(ctx, ids)
+	require.NoError(t, err)
+	assert.Equal(t, []*Company{found}, companies)
+
+	companies, err = service.GetByIDs(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, companies, "no IDs need no read")
+
+	mockRepo.AssertExpectations(t)
+}
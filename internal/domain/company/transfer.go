@@ -0,0 +1,171 @@
+package company
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// exportPageSize is how many companies Export loads at a time
+	exportPageSize = 500
+	// maxImportErrors caps the row errors kept in an ImportReport; Failed
+	// still counts all of them
+	maxImportErrors = 1000
+	// importProgressEvery is how many rows pass between progress reports
+	importProgressEvery = 100
+)
+
+// ErrInvalidImportRow marks a row that could not be decoded. It fails only
+// that row; any other error from an ImportSource aborts the import.
+var ErrInvalidImportRow = errors.New("invalid import row")
+
+// Export calls fn with every company in ID order, loading a page at a time so
+// memory use does not grow with the table. Companies written meanwhile may or
+// may not be included.
+func (s *CompanyService) Export(ctx context.Context, fn func(c *Company) error) error {
+	afterID := ""
+	for {
+		page, err := s.repo.List(ctx, ListFilter{}, afterID, exportPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to list companies: %w", err)
+		}
+
+		for _, c := range page {
+			if err := fn(c); err != nil {
+				return err
+			}
+		}
+		if len(page) < exportPageSize {
+			return nil
+		}
+		afterID = page[len(page)-1].ID().String()
+	}
+}
+
+type UpsertResult string
+
+const (
+	UpsertCreated   UpsertResult = "created"
+	UpsertUpdated   UpsertResult = "updated"
+	UpsertUnchanged UpsertResult = "unchanged"
+)
+
+// UpsertByName creates a company named params.Name, or replaces every field
+// of the existing one. It publishes the same events as CreateCompany and
+// UpdateCompany, and none when nothing changed.
+func (s *CompanyService) UpsertByName(ctx context.Context, params CreateParams) (*Company, UpsertResult, error) {
+	var c *Company
+	var result UpsertResult
+
+	err := s.txManager.Do(ctx, func(ctx context.Context) error {
+		existing, err := s.repo.GetByName(ctx, params.Name)
+		if errors.Is(err, ErrCompanyNotFound) {
+			c, err = s.CreateCompany(ctx, params)
+			result = UpsertCreated
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get company by name: %w", err)
+		}
+
+		c, err = s.UpdateCompany(ctx, existing.ID().String(), UpdateParams{
+			Description:    &params.Description,
+			EmployeesCount: &params.EmployeesCount,
+			Registered:     &params.Registered,
+			Type:           &params.Type,
+		})
+		if err != nil {
+			return err
+		}
+
+		result = UpsertUpdated
+		if sameFields(existing, c) {
+			result = UpsertUnchanged
+		}
+		return nil
+	}, TxOptions{Isolation: IsolationRepeatableRead})
+
+	if err != nil {
+		return nil, "", err
+	}
+	return c, result, nil
+}
+
+func sameFields(a, b *Company) bool {
+	return a.Name() == b.Name() &&
+		a.Description() == b.Description() &&
+		a.EmployeesCount() == b.EmployeesCount() &&
+		a.IsRegistered() == b.IsRegistered() &&
+		a.CompanyType() == b.CompanyType()
+}
+
+// ImportSource yields the rows of an import. Next returns io.EOF after the
+// last row; line is the row's position in the file, for the report.
+type ImportSource interface {
+	Next() (line int, params CreateParams, err error)
+}
+
+type ImportRowError struct {
+	Line int
+	Name string
+	Err  error
+}
+
+// ImportReport counts the outcome of every row read so far and lists the
+// first row errors.
+type ImportReport struct {
+	Rows      int
+	Created   int
+	Updated   int
+	Unchanged int
+	Failed    int
+	Errors    []ImportRowError
+}
+
+// Import upserts every row of src by name, each in its own transaction, so a
+// failed row is reported and the import carries on. onProgress, if set, is
+// called with the report so far every few rows. The returned error is only
+// set when src itself fails or ctx is done; the report then covers the rows
+// read until then.
+func (s *CompanyService) Import(ctx context.Context, src ImportSource, onProgress func(ImportReport)) (ImportReport, error) {
+	var report ImportReport
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		line, params, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		if err != nil && !errors.Is(err, ErrInvalidImportRow) {
+			return report, fmt.Errorf("failed to read import row %d: %w", line, err)
+		}
+
+		report.Rows++
+		var result UpsertResult
+		if err == nil {
+			_, result, err = s.UpsertByName(ctx, params)
+		}
+
+		switch {
+		case err != nil:
+			report.Failed++
+			if len(report.Errors) < maxImportErrors {
+				report.Errors = append(report.Errors, ImportRowError{Line: line, Name: params.Name, Err: err})
+			}
+		case result == UpsertCreated:
+			report.Created++
+		case result == UpsertUpdated:
+			report.Updated++
+		default:
+			report.Unchanged++
+		}
+
+		if onProgress != nil && report.Rows%importProgressEvery == 0 {
+			onProgress(report)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+//go:build unit
+
+package company
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// rowsSource yields its rows, then err (io.EOF when unset).
+type rowsSource struct {
+	rows []CreateParams
+	errs map[int]error
+	err  error
+	next int
+}
+
+func (s *rowsSource) Next() (int, CreateParams, error) {
+	if s.next == len(s.rows) {
+		if s.err != nil {
+			return s.next + 1, CreateParams{}, s.err
+		}
+		return s.next + 1, CreateParams{}, io.EOF
+	}
+	s.next++
+	return s.next, s.rows[s.next-1], s.errs[s.next]
+}
+
+func TestExport_PagesThroughCompanies(t *testing.T) {
+	service, mockRepo, _, _ := setupServiceMocks(t)
+
+	first := make([]*Company, exportPageSize)
+	for i := range first {
+		c, err := NewCompany(uuid.New(), fmt.Sprint("c", i), "", 1, "Corporations")
+		require.NoError(t, err)
+		first[i] = c
+	}
+	last := first[exportPageSize-1]
+
+	mockRepo.On("List", mock.Anything, ListFilter{}, "", exportPageSize).Return(first, nil)
+	mockRepo.On("List", mock.Anything, ListFilter{}, last.ID().String(), exportPageSize).Return([]*Company{last}, nil)
+
+	count := 0
+	err := service.Export(context.Background(), func(*Company) error {
+		count++
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, exportPageSize+1, count)
+}
+
+func TestImport_ReportsRowsAndStopsOnSourceError(t *testing.T) {
+	service, mockRepo, mockPublisher, mockTxManager := setupServiceMocks(t)
+
+	mockTxManager.On("Do", mock.Anything, mock.AnythingOfType("func(context.Context) error")).Return(nil)
+	mockRepo.On("GetByName", mock.Anything, mock.Anything).Return(nil, ErrCompanyNotFound)
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("company.Company")).Return(nil)
+	mockPublisher.On("Publish", mock.Anything, isCompanyCreatedEvent()).Return(nil)
+
+	sourceErr := errors.New("line too long")
+	src := &rowsSource{
+		rows: []CreateParams{
+			{Name: "First", EmployeesCount: 1, Type: "Corporations"},
+			{Name: "Garbled"},
+			{Name: "", EmployeesCount: 1, Type: "Corporations"},
+		},
+		errs: map[int]error{2: fmt.Errorf("%w: invalid JSON", ErrInvalidImportRow)},
+		err:  sourceErr,
+	}
+
+	report, err := service.Import(context.Background(), src, nil)
+
+	assert.ErrorIs(t, err, sourceErr)
+	assert.Equal(t, 3, report.Rows)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 2, report.Failed)
+	require.Len(t, report.Errors, 2)
+	assert.ErrorIs(t, report.Errors[0].Err, ErrInvalidImportRow)
+	assert.Equal(t, 3, report.Errors[1].Line)
+	assert.ErrorIs(t, report.Errors[1].Err, ErrInvalidCompanyNameLength)
+}
@@ -0,0 +1,32 @@
+package company
+
+type IsolationLevel int
+
+const (
+	// IsolationDefault uses the database default (READ COMMITTED in Postgres)
+	IsolationDefault IsolationLevel = iota
+	IsolationReadCommitted
+	IsolationRepeatableRead
+	IsolationSerializable
+)
+
+func (l IsolationLevel) String() string {
+	switch l {
+	case IsolationReadCommitted:
+		return "read committed"
+	case IsolationRepeatableRead:
+		return "repeatable read"
+	case IsolationSerializable:
+		return "serializable"
+	default:
+		return "default"
+	}
+}
+
+// TxOptions configures a transaction started by TxManager.Do. Callbacks run
+// with REPEATABLE READ or SERIALIZABLE may be retried on serialization
+// failures, so they must be safe to re-run.
+type TxOptions struct {
+	Isolation IsolationLevel
+	ReadOnly  bool
+}
@@ -0,0 +1,11 @@
+package webhook
+
+import "errors"
+
+var ErrInvalidURL = errors.New("url must be an absolute http or https URL")
+var ErrURLNotAllowed = errors.New("url must not point to a loopback, private or link-local address")
+var ErrInvalidEventType = errors.New("unknown event type")
+var ErrSecretTooShort = errors.New("secret must be at least 16 characters")
+var ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+var ErrDeliveryNotFound = errors.New("webhook delivery not found")
+var ErrNoFieldsToUpdate = errors.New("at least one field must be provided for update")
@@ -0,0 +1,22 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type Repository interface {
+	Create(ctx context.Context, s Subscription) error
+	Update(ctx context.Context, s Subscription) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Subscription, error)
+	List(ctx context.Context) ([]Subscription, error)
+
+	// ListDeliveries returns up to limit deliveries of a subscription, newest
+	// first.
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]Delivery, error)
+	GetDelivery(ctx context.Context, subscriptionID uuid.UUID, deliveryID int64) (*Delivery, error)
+	// CreateDelivery queues a new delivery of an outbox event.
+	CreateDelivery(ctx context.Context, subscriptionID uuid.UUID, outboxID int64) (*Delivery, error)
+}
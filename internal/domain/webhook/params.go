@@ -0,0 +1,23 @@
+package webhook
+
+type CreateParams struct {
+	URL string
+	// EventTypes filters the events delivered; empty means all of them
+	EventTypes []string
+	Secret     string
+}
+
+type UpdateParams struct {
+	URL        *string
+	EventTypes *[]string
+	Secret     *string
+	Active     *bool
+}
+
+// IsEmpty checks if all fields are nil (no actual changes)
+func (p UpdateParams) IsEmpty() bool {
+	return p.URL == nil &&
+		p.EventTypes == nil &&
+		p.Secret == nil &&
+		p.Active == nil
+}
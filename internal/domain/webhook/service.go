@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/google/uuid"
+)
+
+type Service struct {
+	repo      Repository
+	txManager company.TxManager
+	targets   TargetPolicy
+	now       func() time.Time
+}
+
+// NewService refuses subscription URLs that targets does not permit.
+func NewService(repo Repository, txManager company.TxManager, targets TargetPolicy) *Service {
+	return &Service{repo: repo, txManager: txManager, targets: targets, now: time.Now}
+}
+
+func (s *Service) Create(ctx context.Context, params CreateParams) (*Subscription, error) {
+	sub, err := NewSubscription(uuid.New(), params, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.targets.CheckURL(sub.URL); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, *sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateParams) (*Subscription, error) {
+	if params.IsEmpty() {
+		return nil, ErrNoFieldsToUpdate
+	}
+
+	var sub *Subscription
+
+	// The read and the write share a REPEATABLE READ transaction so that
+	// concurrent updates are retried on fresh data instead of overwriting
+	// each other, e.g. a secret rotation lost to a toggle of active.
+	err := s.txManager.Do(ctx, func(ctx context.Context) error {
+		var err error
+		sub, err = s.repo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := sub.Apply(params, s.now()); err != nil {
+			return err
+		}
+		if err := s.targets.CheckURL(sub.URL); err != nil {
+			return err
+		}
+
+		return s.repo.Update(ctx, *sub)
+	}, company.TxOptions{Isolation: company.IsolationRepeatableRead})
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Subscription, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *Service) List(ctx context.Context) ([]Subscription, error) {
+	return s.repo.List(ctx)
+}
+
+// Deliveries returns the most recent deliveries of a subscription.
+func (s *Service) Deliveries(ctx context.Context, id uuid.UUID, limit int) ([]Delivery, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListDeliveries(ctx, id, limit)
+}
+
+// Redeliver queues the event of an earlier delivery again, whatever that
+// delivery's outcome. The original delivery is left as it was.
+func (s *Service) Redeliver(ctx context.Context, id uuid.UUID, deliveryID int64) (*Delivery, error) {
+	d, err := s.repo.GetDelivery(ctx, id, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.CreateDelivery(ctx, id, d.OutboxID)
+}
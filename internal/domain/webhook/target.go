@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// sharedAddressSpace is the carrier-grade NAT range, where some clouds put
+// their metadata endpoints.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// TargetPolicy decides which addresses subscription deliveries may be sent
+// to. Loopback, private, link-local (cloud metadata endpoints among them),
+// shared, unspecified and multicast addresses are refused unless they fall
+// in one of the Allowed networks, so that a partner cannot make this service
+// call into its own network.
+type TargetPolicy struct {
+	Allowed []netip.Prefix
+}
+
+// Permits reports whether deliveries may connect to addr.
+func (p TargetPolicy) Permits(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if slices.ContainsFunc(p.Allowed, func(n netip.Prefix) bool { return n.Contains(addr) }) {
+		return true
+	}
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsUnspecified() &&
+		!addr.IsMulticast() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// CheckURL refuses a subscription URL whose host is an address, or
+// localhost, that the policy does not permit. Other host names are only
+// resolved when a delivery connects, so the client that sends deliveries must
+// check the addresses it dials as well.
+func (p TargetPolicy) CheckURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ErrInvalidURL
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		host = "127.0.0.1"
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return nil
+	}
+	if !p.Permits(addr) {
+		return ErrURLNotAllowed
+	}
+	return nil
+}
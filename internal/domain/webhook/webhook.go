@@ -0,0 +1,108 @@
+// Package webhook manages the subscriptions of partners who receive company
+// events as signed HTTP callbacks, and the log of their deliveries.
+package webhook
+
+import (
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/google/uuid"
+)
+
+const minSecretLength = 16
+
+// Subscription is a partner endpoint events are delivered to. The secret
+// keys the HMAC-SHA256 signature of every request and is never returned by
+// the API.
+type Subscription struct {
+	ID         uuid.UUID
+	URL        string
+	EventTypes []string
+	Secret     string
+	Active     bool
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func NewSubscription(id uuid.UUID, params CreateParams, now time.Time) (*Subscription, error) {
+	s := &Subscription{
+		ID:         id,
+		URL:        params.URL,
+		EventTypes: params.EventTypes,
+		Secret:     params.Secret,
+		Active:     true,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Apply updates the fields set in params.
+func (s *Subscription) Apply(params UpdateParams, now time.Time) error {
+	if params.URL != nil {
+		s.URL = *params.URL
+	}
+	if params.EventTypes != nil {
+		s.EventTypes = *params.EventTypes
+	}
+	if params.Secret != nil {
+		s.Secret = *params.Secret
+	}
+	if params.Active != nil {
+		s.Active = *params.Active
+	}
+	s.UpdatedAt = now
+	return s.validate()
+}
+
+// Wants reports whether events of eventType are delivered to s.
+func (s *Subscription) Wants(eventType string) bool {
+	return s.Active && (len(s.EventTypes) == 0 || slices.Contains(s.EventTypes, eventType))
+}
+
+func (s *Subscription) validate() error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL
+	}
+	for _, eventType := range s.EventTypes {
+		if !slices.Contains(company.EventNames, eventType) {
+			return ErrInvalidEventType
+		}
+	}
+	if len(s.Secret) < minSecretLength {
+		return ErrSecretTooShort
+	}
+	return nil
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Delivery is one outbox event sent, or to be sent, to a subscription. A
+// pending delivery is retried with backoff until it succeeds or runs out of
+// attempts.
+type Delivery struct {
+	ID             int64
+	SubscriptionID uuid.UUID
+	OutboxID       int64
+	EventType      string
+	Status         DeliveryStatus
+	Attempts       int
+	// ResponseStatus of the last attempt; 0 when no response was received
+	ResponseStatus int
+	Error          string
+	NextAttemptAt  time.Time
+	CreatedAt      time.Time
+	DeliveredAt    time.Time
+}
@@ -0,0 +1,119 @@
+//go:build unit
+
+package webhook
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var validParams = CreateParams{
+	URL:        "https://partner.example.com/hooks",
+	EventTypes: []string{company.EventCompanyCreated},
+	Secret:     "0123456789abcdef",
+}
+
+func TestNewSubscription_Success(t *testing.T) {
+	id, now := uuid.New(), time.Unix(1700000000, 0)
+	s, err := NewSubscription(id, validParams, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, id, s.ID)
+	assert.True(t, s.Active)
+	assert.Equal(t, now, s.CreatedAt)
+	assert.Equal(t, now, s.UpdatedAt)
+}
+
+func TestNewSubscription_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(p *CreateParams)
+		err    error
+	}{
+		{"relative url", func(p *CreateParams) { p.URL = "/hooks" }, ErrInvalidURL},
+		{"unsupported scheme", func(p *CreateParams) { p.URL = "ftp://partner.example.com" }, ErrInvalidURL},
+		{"unknown event type", func(p *CreateParams) { p.EventTypes = []string{"CompanyMoved"} }, ErrInvalidEventType},
+		{"short secret", func(p *CreateParams) { p.Secret = "short" }, ErrSecretTooShort},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := validParams
+			tt.modify(&params)
+
+			s, err := NewSubscription(uuid.New(), params, time.Now())
+			assert.ErrorIs(t, err, tt.err)
+			assert.Nil(t, s)
+		})
+	}
+}
+
+func TestSubscription_Apply(t *testing.T) {
+	s, err := NewSubscription(uuid.New(), validParams, time.Unix(1700000000, 0))
+	require.NoError(t, err)
+
+	active := false
+	later := time.Unix(1700000100, 0)
+	require.NoError(t, s.Apply(UpdateParams{Active: &active}, later))
+	assert.False(t, s.Active)
+	assert.Equal(t, validParams.URL, s.URL, "unset fields are kept")
+	assert.Equal(t, later, s.UpdatedAt)
+
+	secret := "short"
+	assert.ErrorIs(t, s.Apply(UpdateParams{Secret: &secret}, later), ErrSecretTooShort)
+}
+
+func TestSubscription_Wants(t *testing.T) {
+	s, err := NewSubscription(uuid.New(), validParams, time.Now())
+	require.NoError(t, err)
+
+	assert.True(t, s.Wants(company.EventCompanyCreated))
+	assert.False(t, s.Wants(company.EventCompanyDeleted))
+
+	s.EventTypes = nil
+	assert.True(t, s.Wants(company.EventCompanyDeleted), "no filter means every event")
+
+	s.Active = false
+	assert.False(t, s.Wants(company.EventCompanyCreated))
+}
+
+func TestTargetPolicy_CheckURL(t *testing.T) {
+	tests := []struct {
+		url string
+		err error
+	}{
+		{"https://partner.example.com/hooks", nil},
+		{"https://93.184.215.14/hooks", nil},
+		{"http://localhost:8080/hooks", ErrURLNotAllowed},
+		{"http://api.localhost/hooks", ErrURLNotAllowed},
+		{"http://127.0.0.1/hooks", ErrURLNotAllowed},
+		{"http://[::1]/hooks", ErrURLNotAllowed},
+		{"http://10.0.0.5/hooks", ErrURLNotAllowed},
+		{"http://192.168.1.1/hooks", ErrURLNotAllowed},
+		{"http://169.254.169.254/latest/meta-data", ErrURLNotAllowed},
+		{"http://100.100.100.200/latest/meta-data", ErrURLNotAllowed},
+		{"http://0.0.0.0/hooks", ErrURLNotAllowed},
+		{"http://[::ffff:127.0.0.1]/hooks", ErrURLNotAllowed},
+		{"http://[fd00::1]/hooks", ErrURLNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			assert.ErrorIs(t, TargetPolicy{}.CheckURL(tt.url), tt.err)
+		})
+	}
+}
+
+func TestTargetPolicy_AllowedNetworks(t *testing.T) {
+	policy := TargetPolicy{Allowed: []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")}}
+
+	assert.True(t, policy.Permits(netip.MustParseAddr("10.1.2.3")))
+	assert.False(t, policy.Permits(netip.MustParseAddr("10.2.0.1")))
+	assert.False(t, policy.Permits(netip.MustParseAddr("127.0.0.1")))
+	assert.NoError(t, policy.CheckURL("http://10.1.2.3/hooks"))
+}
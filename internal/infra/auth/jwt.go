@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/dubininme/xm-assessment/internal/delivery/http/handler"
@@ -26,14 +27,34 @@ type UserClaims struct {
 
 var _ handler.TokenGenerator = (*JWTService)(nil)
 
+// JWTService signs and validates HMAC tokens. The secret may hold a key set,
+// one key per line: the first key signs new tokens and every key is accepted
+// for validation, which allows rotating keys without invalidating live tokens.
 type JWTService struct {
-	secret []byte
+	keys atomic.Pointer[[][]byte]
 }
 
 func NewJWTService(secret string) *JWTService {
-	return &JWTService{
-		secret: []byte(secret),
+	s := &JWTService{}
+	s.SetSecret(secret)
+	return s
+}
+
+// SetSecret replaces the key set, e.g. after the secret file was rotated.
+func (s *JWTService) SetSecret(secret string) {
+	var keys [][]byte
+	for _, line := range strings.Split(secret, "\n") {
+		key := strings.TrimSpace(line)
+		if len(key) > 0 {
+			keys = append(keys, []byte(key))
+		}
+	}
+
+	if len(keys) == 0 {
+		keys = [][]byte{[]byte(secret)}
 	}
+
+	s.keys.Store(&keys)
 }
 
 func (s *JWTService) ValidateToken(tokenString string) (*UserClaims, error) {
@@ -43,7 +64,13 @@ func (s *JWTService) ValidateToken(tokenString string) (*UserClaims, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
 		}
-		return s.secret, nil
+
+		keys := *s.keys.Load()
+		verificationKeys := make([]jwt.VerificationKey, 0, len(keys))
+		for _, key := range keys {
+			verificationKeys = append(verificationKeys, key)
+		}
+		return jwt.VerificationKeySet{Keys: verificationKeys}, nil
 	})
 
 	if err != nil {
@@ -93,5 +120,5 @@ func (s *JWTService) GenerateToken(userID string, expiresIn time.Duration) (stri
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.secret)
+	return token.SignedString((*s.keys.Load())[0])
 }
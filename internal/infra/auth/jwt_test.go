@@ -85,3 +85,22 @@ func TestValidateToken_MalformedToken(t *testing.T) {
 	assert.ErrorIs(t, err, ErrInvalidToken)
 	assert.Nil(t, claims)
 }
+
+func TestValidateToken_KeySetRotation(t *testing.T) {
+	oldService := NewJWTService("old-secret")
+	oldToken, err := oldService.GenerateToken("user-123", 1*time.Hour)
+	require.NoError(t, err)
+
+	service := NewJWTService("old-secret")
+	service.SetSecret("new-secret\nold-secret")
+
+	claims, err := service.ValidateToken(oldToken)
+	require.NoError(t, err)
+	assert.Equal(t, "user-123", claims.UserID)
+
+	newToken, err := service.GenerateToken("user-123", 1*time.Hour)
+	require.NoError(t, err)
+
+	_, err = oldService.ValidateToken(newToken)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
@@ -0,0 +1,21 @@
+// Package cache keeps companies read by ID in front of the company
+// repository: in the memory of each replica and, optionally, in Redis shared
+// by all of them. A write drops the company from both once its transaction
+// commits, and the other replicas drop it from memory when Postgres notifies
+// them of the change.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache stores values by key for a while. Implementations are safe for
+// concurrent use.
+type Cache interface {
+	// Get returns the value of key and whether there is one.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value for ttl; a ttl of zero or less stores nothing.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
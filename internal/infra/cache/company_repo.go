@@ -0,0 +1,332 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/pkg/logger"
+	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// keyPrefix holds the version of entry; bump it when entry changes so
+	// replicas of different versions do not read each other's entries
+	keyPrefix = "company:v1:"
+
+	// relistenDelay is the pause before listening again after the
+	// connection failed
+	relistenDelay = time.Second
+)
+
+// Transactions is what CompanyRepo needs of the transaction manager of the
+// repository it wraps; postgres.TxManager and memory.TxManager provide it.
+type Transactions interface {
+	// InTx reports whether ctx carries a transaction.
+	InTx(ctx context.Context) bool
+	// AfterCommit calls fn once the transaction of ctx commits, or right
+	// away when ctx carries none.
+	AfterCommit(ctx context.Context, fn func())
+}
+
+// ListenFunc runs a LISTEN session on the channel of company changes, see
+// postgres.ListenCompanies.
+type ListenFunc func(ctx context.Context, onListen func(ctx context.Context) error, onID func(id string)) error
+
+var _ company.CompanyRepository = (*CompanyRepo)(nil)
+
+// CompanyRepo is a read-through cache of GetByID in front of a company
+// repository; the other methods go straight to it.
+//
+// Companies are looked up in local, the memory of this replica, then in
+// shared if there is one, then in the repository, and the answer is kept in
+// both. That no company has an ID is kept too, for a shorter time. Concurrent
+// misses of the same ID share one read of the repository.
+//
+// Reads within a transaction bypass the cache, so that they keep the
+// isolation of the transaction and see its own writes. Writes drop the
+// company once their transaction commits; other replicas drop it when
+// notified, see Listen. A company is served stale for at most the TTL when a
+// notification is lost.
+type CompanyRepo struct {
+	next        company.CompanyRepository
+	txs         Transactions
+	local       *LRU
+	shared      Cache
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	group singleflight.Group
+
+	// mu orders fills of local against invalidations: a read that started
+	// before an invalidation does not fill local with what it read, which
+	// may predate the write.
+	mu            sync.Mutex
+	invalidations uint64
+}
+
+// NewCompanyRepo caches the companies of next for ttl, and that one does not
+// exist for negativeTTL. shared may be nil.
+func NewCompanyRepo(next company.CompanyRepository, txs Transactions, local *LRU, shared Cache, ttl, negativeTTL time.Duration) *CompanyRepo {
+	return &CompanyRepo{
+		next:        next,
+		txs:         txs,
+		local:       local,
+		shared:      shared,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}
+}
+
+func (r *CompanyRepo) Create(ctx context.Context, c company.Company) error {
+	if err := r.next.Create(ctx, c); err != nil {
+		return err
+	}
+	// A lookup of the ID before the create may be cached as not found
+	r.invalidateAfterCommit(ctx, c.ID().String())
+	return nil
+}
+
+func (r *CompanyRepo) Update(ctx context.Context, c company.Company) error {
+	if err := r.next.Update(ctx, c); err != nil {
+		return err
+	}
+	r.invalidateAfterCommit(ctx, c.ID().String())
+	return nil
+}
+
+func (r *CompanyRepo) Delete(ctx context.Context, companyID string) error {
+	if err := r.next.Delete(ctx, companyID); err != nil {
+		return err
+	}
+	r.invalidateAfterCommit(ctx, companyID)
+	return nil
+}
+
+func (r *CompanyRepo) GetByID(ctx context.Context, companyID string) (*company.Company, error) {
+	if r.txs.InTx(ctx) {
+		return r.next.GetByID(ctx, companyID)
+	}
+
+	key := keyPrefix + companyID
+	if value, ok, _ := r.local.Get(ctx, key); ok {
+		if c, err := decode(value); !errors.Is(err, errCorruptEntry) {
+			return c, err
+		}
+	}
+
+	// The read is shared, so one caller giving up does not fail the others
+	ch := r.group.DoChan(key, func() (any, error) {
+		return r.load(context.WithoutCancel(ctx), companyID, key)
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return decode(res.Val.([]byte))
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (r *CompanyRepo) GetByName(ctx context.Context, name string) (*company.Company, error) {
+	return r.next.GetByName(ctx, name)
+}
+
+func (r *CompanyRepo) List(ctx context.Context, filter company.ListFilter, afterID string, limit int) ([]*company.Company, error) {
+	return r.next.List(ctx, filter, afterID, limit)
+}
+
+// Invalidate drops the company from the cache.
+func (r *CompanyRepo) Invalidate(ctx context.Context, companyID string) {
+	key := keyPrefix + companyID
+
+	r.mu.Lock()
+	r.invalidations++
+	_ = r.local.Delete(ctx, key)
+	r.mu.Unlock()
+	// Later lookups must not join a read that started before the write
+	r.group.Forget(key)
+
+	if r.shared != nil {
+		if err := r.shared.Delete(ctx, key); err != nil {
+			logger.FromContext(ctx).Warn("failed to drop company from the shared cache", "company_id", companyID, "error", err)
+		}
+	}
+}
+
+// Listen drops the companies written by any replica from the memory of this
+// one as Postgres notifies them, until ctx is done, listening again after
+// failures. Since the changes notified while no listener is connected are
+// lost, the memory is emptied each time listening starts.
+func (r *CompanyRepo) Listen(ctx context.Context, listen ListenFunc) error {
+	log := logger.FromContext(ctx)
+
+	log.Info("company cache invalidation started")
+	for {
+		err := listen(ctx, func(context.Context) error {
+			r.flush()
+			return nil
+		}, func(id string) {
+			r.Invalidate(ctx, id)
+		})
+
+		if ctx.Err() != nil {
+			log.Info("company cache invalidation stopping")
+			return nil
+		}
+
+		log.Warn("company cache lost its listener, reconnecting", "error", err, "retry_in", relistenDelay)
+		select {
+		case <-ctx.Done():
+			log.Info("company cache invalidation stopping")
+			return nil
+		case <-time.After(relistenDelay):
+		}
+	}
+}
+
+func (r *CompanyRepo) invalidateAfterCommit(ctx context.Context, companyID string) {
+	r.txs.AfterCommit(ctx, func() {
+		r.Invalidate(context.WithoutCancel(ctx), companyID)
+	})
+}
+
+// flush empties the memory of this replica; shared entries are left to the
+// writers, which drop them after committing.
+func (r *CompanyRepo) flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.invalidations++
+	r.local.Clear()
+}
+
+// load looks the company up in shared, then in the repository, and returns
+// its entry.
+func (r *CompanyRepo) load(ctx context.Context, companyID, key string) ([]byte, error) {
+	log := logger.FromContext(ctx)
+
+	r.mu.Lock()
+	generation := r.invalidations
+	r.mu.Unlock()
+
+	if r.shared != nil {
+		value, ok, err := r.shared.Get(ctx, key)
+		switch {
+		case err != nil:
+			log.Warn("failed to read the shared company cache", "company_id", companyID, "error", err)
+		case ok:
+			if c, err := decode(value); !errors.Is(err, errCorruptEntry) {
+				r.fillLocal(generation, key, value, r.entryTTL(c))
+				return value, nil
+			}
+			log.Warn("dropping corrupt entry of the shared company cache", "company_id", companyID)
+		}
+	}
+
+	c, err := r.next.GetByID(ctx, companyID)
+	if err != nil && !errors.Is(err, company.ErrCompanyNotFound) {
+		return nil, err
+	}
+
+	value, err := encode(c)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := r.entryTTL(c)
+	if r.fillLocal(generation, key, value, ttl) && r.shared != nil {
+		if err := r.shared.Set(ctx, key, value, ttl); err != nil {
+			log.Warn("failed to fill the shared company cache", "company_id", companyID, "error", err)
+		}
+	}
+	return value, nil
+}
+
+// fillLocal keeps value unless the cache was invalidated since generation,
+// and reports whether it did.
+func (r *CompanyRepo) fillLocal(generation uint64, key string, value []byte, ttl time.Duration) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.invalidations != generation {
+		return false
+	}
+	_ = r.local.Set(context.Background(), key, value, ttl)
+	return true
+}
+
+func (r *CompanyRepo) entryTTL(c *company.Company) time.Duration {
+	if c == nil {
+		return r.negativeTTL
+	}
+	return r.ttl
+}
+
+var errCorruptEntry = errors.New("corrupt company cache entry")
+
+// entry is a cached lookup: the company, or nil when none has the ID.
+type entry struct {
+	Company *cachedCompany `json:"company,omitempty"`
+}
+
+type cachedCompany struct {
+	ID             uuid.UUID `json:"id"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description"`
+	EmployeesCount int       `json:"employees_count"`
+	Registered     bool      `json:"registered"`
+	Type           string    `json:"type"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func encode(c *company.Company) ([]byte, error) {
+	var e entry
+	if c != nil {
+		e.Company = &cachedCompany{
+			ID:             c.ID(),
+			Name:           c.Name().String(),
+			Description:    c.Description().String(),
+			EmployeesCount: c.EmployeesCount().Int(),
+			Registered:     c.IsRegistered(),
+			Type:           c.CompanyType().String(),
+			UpdatedAt:      c.UpdatedAt(),
+		}
+	}
+
+	value, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode company cache entry: %w", err)
+	}
+	return value, nil
+}
+
+// decode returns a new company from value, ErrCompanyNotFound for a
+// negative entry, or errCorruptEntry.
+func decode(value []byte) (*company.Company, error) {
+	var e entry
+	if err := json.Unmarshal(value, &e); err != nil {
+		return nil, fmt.Errorf("%w: %v", errCorruptEntry, err)
+	}
+	if e.Company == nil {
+		return nil, company.ErrCompanyNotFound
+	}
+
+	cc := e.Company
+	c, err := company.NewCompany(cc.ID, cc.Name, cc.Description, cc.EmployeesCount, cc.Type)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errCorruptEntry, err)
+	}
+	if cc.Registered {
+		c.Register()
+	}
+	c.SetUpdatedAt(cc.UpdatedAt)
+	c.ClearDirty()
+	return c, nil
+}
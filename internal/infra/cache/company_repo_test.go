@@ -0,0 +1,367 @@
+//go:build unit
+
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/infra/cache"
+	"github.com/dubininme/xm-assessment/internal/infra/memory"
+	"github.com/dubininme/xm-assessment/internal/infra/repotest"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingRepo counts the reads by ID that reach storage. When gate is set,
+// each read announces itself on started and waits for gate.
+type countingRepo struct {
+	company.CompanyRepository
+	gets    atomic.Int32
+	started chan struct{}
+	gate    chan struct{}
+}
+
+func (r *countingRepo) GetByID(ctx context.Context, companyID string) (*company.Company, error) {
+	r.gets.Add(1)
+	if r.gate != nil {
+		r.started <- struct{}{}
+		<-r.gate
+	}
+	return r.CompanyRepository.GetByID(ctx, companyID)
+}
+
+type fixture struct {
+	store     *memory.Store
+	repo      *countingRepo
+	txManager *memory.TxManager
+	local     *cache.LRU
+	cached    *cache.CompanyRepo
+}
+
+func newFixture(t *testing.T, shared cache.Cache) *fixture {
+	t.Helper()
+	store := memory.NewStore()
+	f := &fixture{
+		store:     store,
+		repo:      &countingRepo{CompanyRepository: memory.NewCompanyRepo(store)},
+		txManager: memory.NewTxManager(store),
+		local:     cache.NewLRU(100),
+	}
+	f.cached = cache.NewCompanyRepo(f.repo, f.txManager, f.local, shared, time.Minute, time.Minute)
+	return f
+}
+
+// replica is another replica over the same storage and shared cache.
+func (f *fixture) replica(shared cache.Cache) *cache.CompanyRepo {
+	return cache.NewCompanyRepo(f.repo, f.txManager, cache.NewLRU(100), shared, time.Minute, time.Minute)
+}
+
+func (f *fixture) create(t *testing.T, name string) *company.Company {
+	t.Helper()
+	c, err := company.NewCompany(uuid.New(), name, "cached", 10, company.CorporationsType.String())
+	require.NoError(t, err)
+	c.SetUpdatedAt(time.Now().UTC())
+	require.NoError(t, f.repo.Create(context.Background(), *c))
+	return c
+}
+
+func newRedis(t *testing.T) (*miniredis.Miniredis, cache.Cache) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	// No retries, so that a test stopping Redis fails over at once
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { _ = client.Close() })
+	return mr, cache.NewRedis(client)
+}
+
+func TestContract(t *testing.T) {
+	repotest.RunContract(t, func(t *testing.T) repotest.Harness {
+		store := memory.NewStore()
+		txManager := memory.NewTxManager(store)
+		_, shared := newRedis(t)
+
+		return repotest.Harness{
+			Repo:      cache.NewCompanyRepo(memory.NewCompanyRepo(store), txManager, cache.NewLRU(100), shared, time.Minute, time.Minute),
+			Publisher: memory.NewEventsPublisher(store),
+			TxManager: txManager,
+			EventCount: func(t *testing.T, aggregateID string) int {
+				count := 0
+				for _, e := range store.Events() {
+					if e.AggregateID() == aggregateID {
+						count++
+					}
+				}
+				return count
+			},
+		}
+	})
+}
+
+func TestCompanyRepo_ReadThrough(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, nil)
+	c := f.create(t, "Acme")
+
+	first, err := f.cached.GetByID(ctx, c.ID().String())
+	require.NoError(t, err)
+	require.NoError(t, first.SetName("Changed"))
+
+	second, err := f.cached.GetByID(ctx, c.ID().String())
+	require.NoError(t, err)
+	assert.Equal(t, "Acme", second.Name().String(), "callers get their own copy")
+	assert.False(t, second.IsDirty())
+	assert.True(t, c.UpdatedAt().Equal(second.UpdatedAt()))
+	assert.EqualValues(t, 1, f.repo.gets.Load())
+}
+
+func TestCompanyRepo_NegativeCaching(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, nil)
+	id := uuid.New()
+
+	for range 2 {
+		_, err := f.cached.GetByID(ctx, id.String())
+		assert.ErrorIs(t, err, company.ErrCompanyNotFound)
+	}
+	assert.EqualValues(t, 1, f.repo.gets.Load())
+
+	c, err := company.NewCompany(id, "Late", "", 1, company.CorporationsType.String())
+	require.NoError(t, err)
+	require.NoError(t, f.cached.Create(ctx, *c))
+
+	got, err := f.cached.GetByID(ctx, id.String())
+	require.NoError(t, err, "a create drops the cached miss")
+	assert.Equal(t, "Late", got.Name().String())
+}
+
+func TestCompanyRepo_InvalidatesAfterCommit(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, nil)
+	c := f.create(t, "Acme")
+	id := c.ID().String()
+
+	_, err := f.cached.GetByID(ctx, id)
+	require.NoError(t, err)
+
+	err = f.txManager.Do(ctx, func(ctx context.Context) error {
+		require.NoError(t, c.SetName("Renamed"))
+		require.NoError(t, f.cached.Update(ctx, *c))
+
+		inTx, err := f.cached.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, "Renamed", inTx.Name().String(), "reads in the transaction see its writes")
+
+		outside, err := f.cached.GetByID(context.Background(), id)
+		require.NoError(t, err)
+		assert.Equal(t, "Acme", outside.Name().String(), "others see the committed state until the commit")
+		return nil
+	})
+	require.NoError(t, err)
+
+	got, err := f.cached.GetByID(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", got.Name().String())
+
+	err = f.txManager.Do(ctx, func(ctx context.Context) error {
+		require.NoError(t, f.cached.Delete(ctx, id))
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+
+	reads := f.repo.gets.Load()
+	_, err = f.cached.GetByID(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, reads, f.repo.gets.Load(), "a rolled back delete keeps the entry")
+
+	require.NoError(t, f.cached.Delete(ctx, id))
+	_, err = f.cached.GetByID(ctx, id)
+	assert.ErrorIs(t, err, company.ErrCompanyNotFound)
+}
+
+func TestCompanyRepo_SingleFlight(t *testing.T) {
+	f := newFixture(t, nil)
+	c := f.create(t, "Acme")
+	f.repo.started = make(chan struct{}, 1)
+	f.repo.gate = make(chan struct{})
+
+	const readers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, readers)
+	for range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := f.cached.GetByID(context.Background(), c.ID().String())
+			errs <- err
+		}()
+	}
+
+	<-f.repo.started
+	// Give the other readers time to join the read in progress
+	time.Sleep(20 * time.Millisecond)
+	close(f.repo.gate)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.EqualValues(t, 1, f.repo.gets.Load())
+}
+
+func TestCompanyRepo_CanceledReaderDoesNotFailOthers(t *testing.T) {
+	f := newFixture(t, nil)
+	c := f.create(t, "Acme")
+	f.repo.started = make(chan struct{}, 1)
+	f.repo.gate = make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := f.cached.GetByID(ctx, c.ID().String())
+		done <- err
+	}()
+	<-f.repo.started
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	other := make(chan error, 1)
+	go func() {
+		_, err := f.cached.GetByID(context.Background(), c.ID().String())
+		other <- err
+	}()
+	close(f.repo.gate)
+	assert.NoError(t, <-other)
+}
+
+func TestCompanyRepo_ReadOverlappingInvalidationIsNotKept(t *testing.T) {
+	f := newFixture(t, nil)
+	c := f.create(t, "Acme")
+	f.repo.started = make(chan struct{}, 1)
+	f.repo.gate = make(chan struct{})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := f.cached.GetByID(context.Background(), c.ID().String())
+		done <- err
+	}()
+	<-f.repo.started
+	f.cached.Invalidate(context.Background(), c.ID().String())
+	close(f.repo.gate)
+	require.NoError(t, <-done)
+
+	f.repo.started = make(chan struct{}, 1)
+	_, err := f.cached.GetByID(context.Background(), c.ID().String())
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, f.repo.gets.Load(), "what the first read got may predate the write")
+}
+
+func TestCompanyRepo_SharedRedis(t *testing.T) {
+	ctx := context.Background()
+	mr, shared := newRedis(t)
+	f := newFixture(t, shared)
+	other := f.replica(shared)
+	c := f.create(t, "Acme")
+	id := c.ID().String()
+
+	_, err := f.cached.GetByID(ctx, id)
+	require.NoError(t, err)
+	got, err := other.GetByID(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "Acme", got.Name().String())
+	assert.EqualValues(t, 1, f.repo.gets.Load(), "the other replica reads the shared entry")
+
+	require.NoError(t, c.SetName("Renamed"))
+	require.NoError(t, f.cached.Update(ctx, *c))
+
+	got, err = other.GetByID(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "Acme", got.Name().String(), "stale in the other replica's memory until notified")
+
+	other.Invalidate(ctx, id)
+	got, err = other.GetByID(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", got.Name().String())
+
+	mr.Close()
+	got, err = f.replica(shared).GetByID(ctx, id)
+	require.NoError(t, err, "a failing Redis falls back to storage")
+	assert.Equal(t, "Renamed", got.Name().String())
+}
+
+func TestCompanyRepo_SharedNegativeEntriesExpireSooner(t *testing.T) {
+	ctx := context.Background()
+	mr, shared := newRedis(t)
+	f := newFixture(t, shared)
+	cached := cache.NewCompanyRepo(f.repo, f.txManager, cache.NewLRU(100), shared, time.Hour, time.Second)
+
+	_, err := cached.GetByID(ctx, uuid.NewString())
+	require.ErrorIs(t, err, company.ErrCompanyNotFound)
+	c := f.create(t, "Acme")
+	_, err = cached.GetByID(ctx, c.ID().String())
+	require.NoError(t, err)
+
+	keys := mr.Keys()
+	require.Len(t, keys, 2)
+	for _, key := range keys {
+		if key == "company:v1:"+c.ID().String() {
+			assert.Equal(t, time.Hour, mr.TTL(key))
+		} else {
+			assert.Equal(t, time.Second, mr.TTL(key))
+		}
+	}
+}
+
+func TestCompanyRepo_Listen(t *testing.T) {
+	f := newFixture(t, nil)
+	first := f.create(t, "Acme")
+	second := f.create(t, "Globex")
+	for _, c := range []*company.Company{first, second} {
+		_, err := f.cached.GetByID(context.Background(), c.ID().String())
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, f.local.Len())
+
+	ids := make(chan string)
+	listening := make(chan struct{})
+	listen := func(ctx context.Context, onListen func(ctx context.Context) error, onID func(id string)) error {
+		if err := onListen(ctx); err != nil {
+			return err
+		}
+		close(listening)
+		for {
+			select {
+			case id := <-ids:
+				onID(id)
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- f.cached.Listen(ctx, listen) }()
+
+	<-listening
+	assert.Zero(t, f.local.Len(), "changes missed before listening are unknown")
+
+	_, err := f.cached.GetByID(context.Background(), first.ID().String())
+	require.NoError(t, err)
+	require.Equal(t, 1, f.local.Len())
+
+	ids <- first.ID().String()
+	assert.Eventually(t, func() bool { return f.local.Len() == 0 }, time.Second, time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
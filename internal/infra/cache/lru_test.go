@@ -0,0 +1,72 @@
+//go:build unit
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+
+	require.NoError(t, c.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, c.Set(ctx, "b", []byte("2"), time.Minute))
+	_, ok, _ := c.Get(ctx, "a")
+	require.True(t, ok)
+
+	require.NoError(t, c.Set(ctx, "c", []byte("3"), time.Minute))
+	assert.Equal(t, 2, c.Len())
+
+	_, ok, _ = c.Get(ctx, "b")
+	assert.False(t, ok, "b was used least recently")
+	value, ok, _ := c.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+	_, ok, _ = c.Get(ctx, "c")
+	assert.True(t, ok)
+}
+
+func TestLRU_Expires(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := NewLRU(10)
+	c.now = func() time.Time { return now }
+
+	require.NoError(t, c.Set(ctx, "a", []byte("1"), time.Second))
+	_, ok, _ := c.Get(ctx, "a")
+	require.True(t, ok)
+
+	now = now.Add(time.Second)
+	_, ok, _ = c.Get(ctx, "a")
+	assert.False(t, ok)
+	assert.Zero(t, c.Len(), "an expired value is removed when looked up")
+}
+
+func TestLRU_SetReplacesAndDeletes(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+
+	require.NoError(t, c.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, c.Set(ctx, "a", []byte("2"), time.Minute))
+	value, _, _ := c.Get(ctx, "a")
+	assert.Equal(t, []byte("2"), value)
+	assert.Equal(t, 1, c.Len())
+
+	require.NoError(t, c.Set(ctx, "a", []byte("3"), 0))
+	_, ok, _ := c.Get(ctx, "a")
+	assert.False(t, ok, "no ttl stores nothing")
+
+	require.NoError(t, c.Set(ctx, "b", []byte("1"), time.Minute))
+	require.NoError(t, c.Set(ctx, "c", []byte("1"), time.Minute))
+	require.NoError(t, c.Delete(ctx, "b", "missing"))
+	assert.Equal(t, 1, c.Len())
+
+	c.Clear()
+	assert.Zero(t, c.Len())
+}
@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+)
+
+var _ company.CompanyRepository = (*CompanyRepo)(nil)
+
+type CompanyRepo struct {
+	store *Store
+}
+
+func NewCompanyRepo(store *Store) *CompanyRepo {
+	return &CompanyRepo{store: store}
+}
+
+func (r *CompanyRepo) Create(ctx context.Context, c company.Company) error {
+	return r.store.withLock(ctx, func() error {
+		if _, ok := r.store.companies[c.ID().String()]; ok {
+			return company.ErrCompanyAlreadyExists
+		}
+
+		if r.nameTaken(c) {
+			return company.ErrCompanyNameAlreadyExists
+		}
+
+		c.ClearDirty()
+		r.store.companies[c.ID().String()] = c
+		return nil
+	})
+}
+
+func (r *CompanyRepo) Update(ctx context.Context, c company.Company) error {
+	return r.store.withLock(ctx, func() error {
+		if _, ok := r.store.companies[c.ID().String()]; !ok {
+			return company.ErrCompanyNotFound
+		}
+
+		if r.nameTaken(c) {
+			return company.ErrCompanyNameAlreadyExists
+		}
+
+		c.ClearDirty()
+		r.store.companies[c.ID().String()] = c
+		return nil
+	})
+}
+
+func (r *CompanyRepo) Delete(ctx context.Context, companyID string) error {
+	return r.store.withLock(ctx, func() error {
+		if _, ok := r.store.companies[companyID]; !ok {
+			return company.ErrCompanyNotFound
+		}
+
+		delete(r.store.companies, companyID)
+		return nil
+	})
+}
+
+func (r *CompanyRepo) GetByID(ctx context.Context, companyID string) (*company.Company, error) {
+	var found company.Company
+	err := r.store.withLock(ctx, func() error {
+		c, ok := r.store.companies[companyID]
+		if !ok {
+			return company.ErrCompanyNotFound
+		}
+
+		found = c
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &found, nil
+}
+
+func (r *CompanyRepo) GetByName(ctx context.Context, name string) (*company.Company, error) {
+	var found company.Company
+	err := r.store.withLock(ctx, func() error {
+		for _, c := range r.store.companies {
+			if c.Name().String() == name {
+				found = c
+				return nil
+			}
+		}
+		return company.ErrCompanyNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &found, nil
+}
+
+func (r *CompanyRepo) List(ctx context.Context, filter company.ListFilter, afterID string, limit int) ([]*company.Company, error) {
+	var page []*company.Company
+	err := r.store.withLock(ctx, func() error {
+		ids := make([]string, 0, len(r.store.companies))
+		for id, c := range r.store.companies {
+			if id > afterID && filter.Matches(&c) {
+				ids = append(ids, id)
+			}
+		}
+		slices.Sort(ids)
+
+		for _, id := range ids[:min(limit, len(ids))] {
+			c := r.store.companies[id]
+			page = append(page, &c)
+		}
+		return nil
+	})
+	return page, err
+}
+
+// nameTaken mirrors the UNIQUE constraint on companies.name.
+func (r *CompanyRepo) nameTaken(c company.Company) bool {
+	for id, existing := range r.store.companies {
+		if id != c.ID().String() && existing.Name() == c.Name() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+//go:build unit
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/infra/repotest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHarness(t *testing.T) repotest.Harness {
+	store := NewStore()
+	return repotest.Harness{
+		Repo:      NewCompanyRepo(store),
+		Publisher: NewEventsPublisher(store),
+		TxManager: NewTxManager(store),
+		EventCount: func(t *testing.T, aggregateID string) int {
+			count := 0
+			for _, e := range store.Events() {
+				if e.AggregateID() == aggregateID {
+					count++
+				}
+			}
+			return count
+		},
+	}
+}
+
+func TestContract(t *testing.T) {
+	repotest.RunContract(t, newHarness)
+}
+
+func TestConcurrentCreateSameName(t *testing.T) {
+	store := NewStore()
+	repo := NewCompanyRepo(store)
+	txManager := NewTxManager(store)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := company.NewCompany(uuid.New(), "SameName", fmt.Sprint(i), 1, company.CorporationsType.String())
+			require.NoError(t, err)
+			errs <- txManager.Do(context.Background(), func(ctx context.Context) error {
+				return repo.Create(ctx, *c)
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.ErrorIs(t, err, company.ErrCompanyNameAlreadyExists)
+	}
+	assert.Equal(t, 1, succeeded)
+}
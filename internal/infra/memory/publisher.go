@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/dubininme/xm-assessment/internal/domain/events"
+	"github.com/dubininme/xm-assessment/pkg/logger"
+)
+
+var _ events.EventsPublisher = (*EventsPublisher)(nil)
+
+// EventsPublisher records events in the store, the in-memory counterpart of
+// the outbox table. Events published in a rolled back transaction are dropped.
+type EventsPublisher struct {
+	store *Store
+}
+
+func NewEventsPublisher(store *Store) *EventsPublisher {
+	return &EventsPublisher{store: store}
+}
+
+func (p *EventsPublisher) Publish(ctx context.Context, event events.Event) error {
+	err := p.store.withLock(ctx, func() error {
+		p.store.events = append(p.store.events, event)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Debug("event published",
+		"event_name", event.EventName(),
+		"aggregate_id", event.AggregateID())
+
+	return nil
+}
@@ -0,0 +1,75 @@
+// Package memory provides thread-safe in-memory implementations of the domain
+// ports for tests and local development (STORAGE=memory). Transactions are
+// serialized by a single lock and rolled back by restoring a snapshot.
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/domain/events"
+)
+
+type ctxKey string
+
+const txKey ctxKey = "memory_tx"
+
+// Store holds the state shared by CompanyRepo, EventsPublisher and TxManager.
+type Store struct {
+	mu        sync.Mutex
+	companies map[string]company.Company
+	events    []events.Event
+}
+
+func NewStore() *Store {
+	return &Store{companies: make(map[string]company.Company)}
+}
+
+// Events returns a copy of all events published and committed so far.
+func (s *Store) Events() []events.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]events.Event(nil), s.events...)
+}
+
+type snapshot struct {
+	companies map[string]company.Company
+	events    int
+}
+
+func (s *Store) snapshot() snapshot {
+	companies := make(map[string]company.Company, len(s.companies))
+	for id, c := range s.companies {
+		companies[id] = c
+	}
+	return snapshot{companies: companies, events: len(s.events)}
+}
+
+func (s *Store) restore(snap snapshot) {
+	s.companies = snap.companies
+	s.events = s.events[:snap.events]
+}
+
+// withLock runs fn holding the store lock, unless ctx already belongs to a
+// transaction of this store (which holds the lock for its whole duration).
+func (s *Store) withLock(ctx context.Context, fn func() error) error {
+	if inTx(ctx, s) {
+		return fn()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn()
+}
+
+// tx is stored in the context while a transaction of store is open.
+type tx struct {
+	store       *Store
+	afterCommit []func()
+}
+
+func inTx(ctx context.Context, s *Store) bool {
+	t, ok := ctx.Value(txKey).(*tx)
+	return ok && t.store == s
+}
@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+)
+
+var _ company.TxManager = (*TxManager)(nil)
+
+// TxManager serializes transactions on the store lock, so every isolation
+// level behaves as SERIALIZABLE and no retries are ever needed.
+type TxManager struct {
+	store *Store
+}
+
+func NewTxManager(store *Store) *TxManager {
+	return &TxManager{store: store}
+}
+
+// Do runs fn atomically: on error all changes made by fn are rolled back. A
+// nested Do behaves like a savepoint and rolls back only its own changes.
+func (m *TxManager) Do(ctx context.Context, fn func(ctx context.Context) error, _ ...company.TxOptions) error {
+	if inTx(ctx, m.store) {
+		return m.run(ctx, fn)
+	}
+
+	t := &tx{store: m.store}
+	err := func() error {
+		m.store.mu.Lock()
+		defer m.store.mu.Unlock()
+		return m.run(context.WithValue(ctx, txKey, t), fn)
+	}()
+	if err != nil {
+		return err
+	}
+
+	for _, f := range t.afterCommit {
+		f()
+	}
+	return nil
+}
+
+// run calls fn and restores the state it started from when fn fails.
+func (m *TxManager) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	snap := m.store.snapshot()
+	if err := fn(ctx); err != nil {
+		m.store.restore(snap)
+		return err
+	}
+	return nil
+}
+
+// InTx reports whether ctx carries a transaction of this store.
+func (m *TxManager) InTx(ctx context.Context) bool {
+	return inTx(ctx, m.store)
+}
+
+// AfterCommit calls fn once the outermost transaction of ctx commits, after
+// the store lock is released, or right away when ctx carries none. fn is not
+// called when the transaction rolls back.
+func (m *TxManager) AfterCommit(ctx context.Context, fn func()) {
+	t, ok := ctx.Value(txKey).(*tx)
+	if !ok || t.store != m.store {
+		fn()
+		return
+	}
+	t.afterCommit = append(t.afterCommit, fn)
+}
@@ -0,0 +1,50 @@
+//go:build unit
+
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxManager_AfterCommit(t *testing.T) {
+	store := NewStore()
+	txManager := NewTxManager(store)
+	ctx := context.Background()
+
+	var calls []string
+	txManager.AfterCommit(ctx, func() { calls = append(calls, "no tx") })
+	assert.Equal(t, []string{"no tx"}, calls, "without a transaction fn is called at once")
+	assert.False(t, txManager.InTx(ctx))
+
+	err := txManager.Do(ctx, func(ctx context.Context) error {
+		assert.True(t, txManager.InTx(ctx))
+		txManager.AfterCommit(ctx, func() {
+			// The store lock is released by now
+			require.NoError(t, store.withLock(context.Background(), func() error { return nil }))
+			calls = append(calls, "outer")
+		})
+
+		_ = txManager.Do(ctx, func(ctx context.Context) error {
+			txManager.AfterCommit(ctx, func() { calls = append(calls, "nested") })
+			return nil
+		})
+
+		assert.Equal(t, []string{"no tx"}, calls, "nothing is called before the commit")
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"no tx", "outer", "nested"}, calls)
+
+	calls = nil
+	err = txManager.Do(ctx, func(ctx context.Context) error {
+		txManager.AfterCommit(ctx, func() { calls = append(calls, "rolled back") })
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+	assert.Empty(t, calls)
+}
@@ -0,0 +1,80 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/delivery/http/handler"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/pkg/jobs"
+)
+
+// adminJobTTL is how long a finished replay or snapshot job can still be
+// looked up.
+const adminJobTTL = 24 * time.Hour
+
+// Admin starts replay and snapshot runs as background jobs for the admin API.
+type Admin struct {
+	replayer *Replayer
+	jobs     *jobs.Registry[Progress]
+}
+
+var _ handler.OutboxAdmin = (*Admin)(nil)
+
+func NewAdmin(ctx context.Context, replayer *Replayer) *Admin {
+	return &Admin{replayer: replayer, jobs: jobs.NewRegistry[Progress](ctx, adminJobTTL)}
+}
+
+func (a *Admin) StartReplay(params handler.ReplayParams) handler.OutboxJob {
+	opts := ReplayOptions{
+		Filter: postgres.OutboxFilter{
+			AggregateID: params.AggregateID,
+			EventType:   params.EventType,
+			CreatedFrom: unixOrZero(params.From),
+			CreatedTo:   unixOrZero(params.To),
+			FromID:      params.FromID,
+			ToID:        params.ToID,
+		},
+		Topic:         params.Topic,
+		RatePerSecond: params.RatePerSecond,
+		BatchSize:     params.BatchSize,
+	}
+
+	return toHandlerJob(a.jobs.Start("replay", func(ctx context.Context, onProgress func(Progress)) (Progress, error) {
+		return a.replayer.Replay(ctx, opts, onProgress)
+	}))
+}
+
+func (a *Admin) StartSnapshot(params handler.SnapshotParams) handler.OutboxJob {
+	opts := SnapshotOptions(params)
+
+	return toHandlerJob(a.jobs.Start("snapshot", func(ctx context.Context, onProgress func(Progress)) (Progress, error) {
+		return a.replayer.Snapshot(ctx, opts, onProgress)
+	}))
+}
+
+func (a *Admin) Job(id string) (handler.OutboxJob, bool) {
+	job, ok := a.jobs.Get(id)
+	return toHandlerJob(job), ok
+}
+
+func toHandlerJob(job jobs.Job[Progress]) handler.OutboxJob {
+	return handler.OutboxJob{
+		ID:         job.ID,
+		Kind:       job.Kind,
+		Status:     string(job.Status),
+		Total:      job.Progress.Total,
+		Published:  job.Progress.Published,
+		LastID:     job.Progress.LastID,
+		Error:      job.Error,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
@@ -2,40 +2,116 @@ package outbox
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/dubininme/xm-assessment/internal/infra/kafka"
 	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/internal/infra/schemaregistry"
 	"github.com/dubininme/xm-assessment/pkg/logger"
 	kafkago "github.com/segmentio/kafka-go"
 )
 
+// MessageProducer delivers a batch of outbox messages. *kafka.Producer
+// implements it; tests substitute an in-process fake.
+type MessageProducer interface {
+	PublishBatch(ctx context.Context, messages []kafkago.Message) error
+	Close() error
+}
+
+var _ MessageProducer = (*kafka.Producer)(nil)
+
+// ValueSerializer encodes the JSON payload stored in the outbox as the Kafka
+// message value.
+type ValueSerializer interface {
+	Serialize(ctx context.Context, eventType string, payload []byte) ([]byte, error)
+	ContentType() string
+}
+
+var _ ValueSerializer = (*schemaregistry.Serializer)(nil)
+
+// Locker holds exclusive partition locks shared by all processor replicas.
+// The locks are tied to the locker: once it is closed or its session is lost,
+// other replicas can take them over.
+type Locker interface {
+	TryLock(ctx context.Context, key int32) (bool, error)
+	Ping(ctx context.Context) error
+	Close(ctx context.Context) error
+}
+
+var _ Locker = (*postgres.LockSession)(nil)
+
+// Partitioning spreads the outbox across processor replicas. Events are
+// assigned to Partitions by aggregate, and a replica only publishes the
+// partitions it holds the lock for, which keeps every company's events in
+// order. With a single partition this is leader election: one replica
+// publishes and the others stand by.
+//
+// The lock is only checked once per interval, so a batch can outlive it, e.g.
+// when the session times out while a sink is slow. Every batch therefore also
+// holds a transaction-level lock of its partition, and a replica that took the
+// partition over waits for the batch in flight instead of delivering the
+// events behind the ones it has locked.
+type Partitioning struct {
+	Partitions int
+	// MaxOwned caps the partitions one replica takes; 0 takes every free one
+	MaxOwned int
+	// NewLocker opens a lock session; nil runs without coordination
+	NewLocker func(ctx context.Context) (Locker, error)
+}
+
+// Processor delivers outbox events to its sinks. With a single sink an event
+// is processed once the sink has it; with several, each sink's deliveries are
+// tracked on their own and an event is processed once every sink has it.
 type Processor struct {
-	outboxRepo     *postgres.OutboxRepo
-	producer       *kafka.Producer
-	txManager      *postgres.TxManager
-	batchSize      int
-	interval       time.Duration
-	publishTimeout time.Duration
+	outboxRepo   *postgres.OutboxRepo
+	sinks        []Sink
+	sinkNames    []string
+	txManager    *postgres.TxManager
+	batchSize    int
+	interval     time.Duration
+	partitioning Partitioning
+
+	locker Locker
+	owned  map[int]bool
+
+	// failed counts the events each sink gave up on
+	failed map[string]*atomic.Int64
 }
 
 func NewProcessor(
 	outboxRepo *postgres.OutboxRepo,
-	producer *kafka.Producer,
+	sinks []Sink,
 	txManager *postgres.TxManager,
 	batchSize int,
 	interval time.Duration,
-	publishTimeout time.Duration,
+	partitioning Partitioning,
 ) *Processor {
+	if partitioning.Partitions < 1 {
+		partitioning.Partitions = 1
+	}
+
+	names := make([]string, len(sinks))
+	failed := make(map[string]*atomic.Int64, len(sinks))
+	for i, s := range sinks {
+		names[i] = s.Name()
+		failed[s.Name()] = new(atomic.Int64)
+	}
+
 	return &Processor{
-		outboxRepo:     outboxRepo,
-		producer:       producer,
-		txManager:      txManager,
-		batchSize:      batchSize,
-		interval:       interval,
-		publishTimeout: publishTimeout,
+		outboxRepo:   outboxRepo,
+		sinks:        sinks,
+		sinkNames:    names,
+		txManager:    txManager,
+		batchSize:    batchSize,
+		interval:     interval,
+		partitioning: partitioning,
+		owned:        make(map[int]bool),
+		failed:       failed,
 	}
 }
 
@@ -46,62 +122,259 @@ func (p *Processor) Start(ctx context.Context) error {
 
 	log.Info("outbox processor started",
 		"batch_size", p.batchSize,
-		"interval", p.interval)
+		"interval", p.interval,
+		"partitions", p.partitioning.Partitions,
+		"sinks", p.sinkNames)
 
 	for {
 		select {
 		case <-ctx.Done():
 			log.Info("outbox processor stopping")
-			return p.producer.Close()
+			return errors.Join(p.releaseLocks(), p.closeSinks())
 		case <-ticker.C:
-			if err := p.processBatch(ctx); err != nil {
-				log.Error("error processing outbox batch", "error", err)
+			owned, err := p.claim(ctx)
+			if err != nil {
+				log.Error("error claiming outbox partitions", "error", err)
+			}
+
+			for _, partition := range owned {
+				if err := p.processBatch(ctx, partition, p.partitioning.Partitions); err != nil {
+					log.Error("error processing outbox batch", "partition", partition, "error", err)
+				}
 			}
 		}
 	}
 }
 
-func (p *Processor) processBatch(ctx context.Context) error {
+// claim returns the partitions this replica may publish, first checking that
+// the locks it holds are still alive and then trying to take free ones.
+func (p *Processor) claim(ctx context.Context) ([]int, error) {
+	if p.partitioning.NewLocker == nil {
+		all := make([]int, p.partitioning.Partitions)
+		for i := range all {
+			all[i] = i
+		}
+		return all, nil
+	}
+
 	log := logger.FromContext(ctx)
 
-	return p.txManager.Do(ctx, func(txCtx context.Context) error {
-		events, err := p.outboxRepo.GetUnprocessed(txCtx, p.batchSize)
+	if p.locker != nil {
+		if err := p.locker.Ping(ctx); err != nil {
+			log.Warn("outbox lock session lost, releasing partitions", "error", err)
+			_ = p.locker.Close(ctx)
+			p.locker = nil
+			clear(p.owned)
+		}
+	}
+
+	if p.locker == nil {
+		locker, err := p.partitioning.NewLocker(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to get unprocessed events: %w", err)
+			return nil, fmt.Errorf("failed to open lock session: %w", err)
 		}
+		p.locker = locker
+	}
 
-		if len(events) == 0 {
-			return nil
+	var err error
+	for partition := range p.partitioning.Partitions {
+		if p.owned[partition] {
+			continue
 		}
+		if p.partitioning.MaxOwned > 0 && len(p.owned) >= p.partitioning.MaxOwned {
+			break
+		}
+
+		ok, lockErr := p.locker.TryLock(ctx, int32(partition)) // #nosec G115 -- partition count is a small config value
+		if lockErr != nil {
+			err = fmt.Errorf("failed to lock partition %d: %w", partition, lockErr)
+			break
+		}
+		if ok {
+			p.owned[partition] = true
+			log.Info("outbox partition acquired", "partition", partition)
+		}
+	}
+
+	owned := make([]int, 0, len(p.owned))
+	for partition := range p.owned {
+		owned = append(owned, partition)
+	}
+	slices.Sort(owned)
+	return owned, err
+}
+
+// releaseLocks closes the lock session so other replicas can take over the
+// partitions right away.
+func (p *Processor) releaseLocks() error {
+	if p.locker == nil {
+		return nil
+	}
+
+	// ctx is already cancelled on shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), p.interval)
+	defer cancel()
+
+	err := p.locker.Close(ctx)
+	p.locker = nil
+	clear(p.owned)
+	return err
+}
+
+func (p *Processor) closeSinks() error {
+	var errs []error
+	for _, s := range p.sinks {
+		errs = append(errs, s.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// ProcessBatch delivers one batch of unprocessed events from every partition
+// to every sink. It takes no locks.
+func (p *Processor) ProcessBatch(ctx context.Context) error {
+	return p.processBatch(ctx, 0, 1)
+}
 
-		messages := make([]kafkago.Message, 0, len(events))
-		ids := make([]int64, 0, len(events))
-		for _, e := range events {
-			messages = append(messages, kafkago.Message{
-				Key:   []byte(e.AggregateID),
-				Value: []byte(e.Payload),
-				Headers: []kafkago.Header{
-					{Key: "event_name", Value: []byte(e.EventType)},
-					{Key: "outbox_id", Value: []byte(strconv.FormatInt(e.ID, 10))},
-				},
-			})
-			ids = append(ids, e.ID)
+// processBatch delivers one batch of unprocessed events of a partition to
+// every sink. Start calls it on every tick for each partition the replica
+// owns. A failing sink does not hold back the others.
+func (p *Processor) processBatch(ctx context.Context, partition, partitions int) error {
+	var errs []error
+	for _, s := range p.sinks {
+		if err := p.deliver(ctx, s, partition, partitions); err != nil {
+			errs = append(errs, fmt.Errorf("sink %s: %w", s.Name(), err))
 		}
+	}
+	return errors.Join(errs...)
+}
 
-		publishCtx, cancel := context.WithTimeout(txCtx, p.publishTimeout)
-		defer cancel()
+func (p *Processor) deliver(ctx context.Context, s Sink, partition, partitions int) error {
+	log := logger.FromContext(ctx)
+	tracked := len(p.sinks) > 1
 
-		publishErr := p.producer.PublishBatch(publishCtx, messages)
-		if publishErr != nil {
-			return fmt.Errorf("failed to write messages to kafka: %w", publishErr)
+	var deliverErr error
+	err := p.txManager.Do(ctx, func(txCtx context.Context) error {
+		if p.locker != nil {
+			ok, err := p.outboxRepo.TryLockPartition(txCtx, partition)
+			if err != nil {
+				return fmt.Errorf("failed to lock partition %d for the batch: %w", partition, err)
+			}
+			if !ok {
+				log.Warn("outbox partition busy with a batch of another replica", "partition", partition, "sink", s.Name())
+				return nil
+			}
 		}
 
-		markErr := p.outboxRepo.MarkProcessed(txCtx, ids)
-		if markErr != nil {
-			return fmt.Errorf("failed to mark events as processed: %w", markErr)
+		var events []postgres.OutboxEvent
+		var err error
+		if tracked {
+			events, err = p.outboxRepo.GetUndelivered(txCtx, s.Name(), p.batchSize, partition, partitions)
+		} else {
+			events, err = p.outboxRepo.GetUnprocessedPartition(txCtx, p.batchSize, partition, partitions)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get unprocessed events: %w", err)
 		}
 
-		log.Info("processed events from outbox", "count", len(events))
+		if len(events) == 0 {
+			return nil
+		}
+
+		// The delivered prefix is committed even when the rest failed, so it
+		// is not delivered again. An event the sink can never deliver is set
+		// aside and the rest of the batch goes on without it.
+		for len(events) > 0 {
+			var delivered int
+			delivered, deliverErr = s.Deliver(txCtx, events)
+
+			if err := p.markDelivered(txCtx, s, events[:delivered], tracked); err != nil {
+				return err
+			}
+			if delivered > 0 {
+				log.Info("processed events from outbox", "count", delivered, "sink", s.Name(), "partition", partition)
+			}
+
+			var permanent *PermanentError
+			if !errors.As(deliverErr, &permanent) || delivered >= len(events) {
+				return nil
+			}
+
+			failed := events[delivered]
+			if err := p.outboxRepo.MarkFailed(txCtx, s.Name(), failed.ID, permanent.Error(), p.trackedNames(s, tracked)); err != nil {
+				return fmt.Errorf("failed to mark event %d as failed: %w", failed.ID, err)
+			}
+			p.failed[s.Name()].Add(1)
+			log.Error("outbox event cannot be delivered, setting it aside",
+				"outbox_id", failed.ID, "event_type", failed.EventType, "sink", s.Name(), "error", permanent.Err)
+
+			deliverErr = nil
+			events = events[delivered+1:]
+		}
 		return nil
 	})
+	return errors.Join(err, deliverErr)
+}
+
+func (p *Processor) markDelivered(ctx context.Context, s Sink, events []postgres.OutboxEvent, tracked bool) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+
+	var err error
+	if tracked {
+		err = p.outboxRepo.MarkDelivered(ctx, s.Name(), ids, p.sinkNames)
+	} else {
+		err = p.outboxRepo.MarkProcessed(ctx, ids)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to mark events as processed: %w", err)
+	}
+	return nil
+}
+
+// trackedNames returns the sinks that must be done with an event before it
+// is processed. With a single sink the failure is still recorded under its
+// name, so it is the only one.
+func (p *Processor) trackedNames(s Sink, tracked bool) []string {
+	if tracked {
+		return p.sinkNames
+	}
+	return []string{s.Name()}
+}
+
+// Name, Check and Stats report the processor on the health endpoint.
+func (p *Processor) Name() string { return "outbox" }
+
+func (p *Processor) Check(context.Context) error { return nil }
+
+// Stats returns how many events each sink gave up on since the start.
+func (p *Processor) Stats() map[string]any {
+	failed := make(map[string]any, len(p.failed))
+	for name, count := range p.failed {
+		failed[name] = count.Load()
+	}
+	return map[string]any{"failed_events": failed}
+}
+
+// newMessage builds the Kafka message for an outbox event.
+func newMessage(ctx context.Context, serializer ValueSerializer, e postgres.OutboxEvent) (kafkago.Message, error) {
+	value, err := serializer.Serialize(ctx, e.EventType, []byte(e.Payload))
+	if err != nil {
+		return kafkago.Message{}, fmt.Errorf("failed to serialize outbox event %d: %w", e.ID, err)
+	}
+
+	return kafkago.Message{
+		Key:   []byte(e.AggregateID),
+		Value: value,
+		Headers: []kafkago.Header{
+			{Key: "event_name", Value: []byte(e.EventType)},
+			{Key: "outbox_id", Value: []byte(strconv.FormatInt(e.ID, 10))},
+			{Key: "content_type", Value: []byte(serializer.ContentType())},
+		},
+	}, nil
 }
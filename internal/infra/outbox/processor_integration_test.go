@@ -0,0 +1,233 @@
+//go:build integration
+
+package outbox_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/infra/outbox"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/internal/infra/schemaregistry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const processorInterval = 20 * time.Millisecond
+
+// replica is one processor with its own connection pool, like a separate
+// instance of the service.
+type replica struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+// replicaOptions adjust a replica for a test.
+type replicaOptions struct {
+	// wrapSink, when set, wraps the Kafka sink of the replica
+	wrapSink func(outbox.Sink) outbox.Sink
+	// sessions, when set, receives the lock sessions the replica opens
+	sessions chan<- *postgres.LockSession
+}
+
+func startReplica(t *testing.T, producer *topicProducer, partitions, maxOwned int) *replica {
+	t.Helper()
+	return startReplicaWith(t, producer, partitions, maxOwned, replicaOptions{})
+}
+
+func startReplicaWith(t *testing.T, producer *topicProducer, partitions, maxOwned int, opts replicaOptions) *replica {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	db, err := postgres.Connect(ctx, testDbConfig)
+	require.NoError(t, err)
+
+	serializer, err := schemaregistry.NewSerializer(ctx, schemaregistry.FormatJSON, nil, "company-events")
+	require.NoError(t, err)
+
+	var sink outbox.Sink = outbox.NewKafkaSink(producer, serializer, time.Second)
+	if opts.wrapSink != nil {
+		sink = opts.wrapSink(sink)
+	}
+
+	processor := outbox.NewProcessor(
+		postgres.NewOutboxRepo(db),
+		[]outbox.Sink{sink},
+		postgres.NewTxManager(db, testDbConfig.DBTxMaxRetries),
+		2,
+		processorInterval,
+		outbox.Partitioning{
+			Partitions: partitions,
+			MaxOwned:   maxOwned,
+			NewLocker: func(ctx context.Context) (outbox.Locker, error) {
+				session, err := postgres.NewLockSession(ctx, db, postgres.OutboxLockClassID, 0)
+				if err == nil && opts.sessions != nil {
+					select {
+					case opts.sessions <- session:
+					default:
+					}
+				}
+				return session, err
+			},
+		},
+	)
+
+	r := &replica{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(r.done)
+		assert.NoError(t, processor.Start(ctx))
+		db.Close()
+	}()
+	t.Cleanup(r.stop)
+	return r
+}
+
+func (r *replica) stop() {
+	r.once.Do(func() {
+		r.cancel()
+		<-r.done
+	})
+}
+
+// addUpdates appends an update event per company, so every aggregate has two
+// events whose order must be kept.
+func addUpdates(t *testing.T, f fixture) {
+	t.Helper()
+	ctx := context.Background()
+
+	for _, c := range f.companies {
+		updated := *c
+		require.NoError(t, f.outboxRepo.Publish(ctx, company.NewCompanyUpdatedEvent(c, &updated, company.UpdateParams{})))
+	}
+}
+
+func (p *topicProducer) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.messages)
+}
+
+func assertDeliveredOnceInOrder(t *testing.T, producer *topicProducer, f fixture) {
+	t.Helper()
+	producer.mu.Lock()
+	defer producer.mu.Unlock()
+
+	seen := map[string]bool{}
+	lastByAggregate := map[string]int64{}
+	for _, msg := range producer.messages {
+		id := header(msg, "outbox_id")
+		assert.False(t, seen[id], "outbox event %s published twice", id)
+		seen[id] = true
+
+		outboxID, err := strconv.ParseInt(id, 10, 64)
+		require.NoError(t, err)
+		key := string(msg.Key)
+		assert.Greater(t, outboxID, lastByAggregate[key], "events of %s out of order", key)
+		lastByAggregate[key] = outboxID
+	}
+	assert.Len(t, seen, 2*len(f.companies))
+}
+
+func TestProcessor_PartitionedReplicas(t *testing.T) {
+	f := setup(t, 20)
+	addUpdates(t, f)
+
+	producer := &topicProducer{}
+	for range 3 {
+		startReplica(t, producer, 4, 2)
+	}
+
+	require.Eventually(t, func() bool { return producer.count() >= 40 }, 10*time.Second, processorInterval)
+	// Give a replica publishing twice the chance to show up
+	time.Sleep(5 * processorInterval)
+
+	assertDeliveredOnceInOrder(t, producer, f)
+}
+
+func TestProcessor_LeaderFailover(t *testing.T) {
+	f := setup(t, 4)
+
+	producer := &topicProducer{}
+	leader := startReplica(t, producer, 1, 0)
+	require.Eventually(t, func() bool { return producer.count() == 4 }, 10*time.Second, processorInterval)
+
+	startReplica(t, producer, 1, 0)
+	time.Sleep(5 * processorInterval)
+
+	// Stopping the leader closes its lock session and the standby takes over
+	leader.stop()
+	addUpdates(t, f)
+
+	require.Eventually(t, func() bool { return producer.count() >= 8 }, 10*time.Second, processorInterval)
+	time.Sleep(5 * processorInterval)
+
+	assertDeliveredOnceInOrder(t, producer, f)
+}
+
+// stallingSink holds its first delivery until release is closed.
+type stallingSink struct {
+	outbox.Sink
+	stalled chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (s *stallingSink) Deliver(ctx context.Context, events []postgres.OutboxEvent) (int, error) {
+	s.once.Do(func() {
+		close(s.stalled)
+		<-s.release
+	})
+	return s.Sink.Deliver(ctx, events)
+}
+
+func TestProcessor_BatchOutlivingItsPartitionLock(t *testing.T) {
+	f := setup(t, 2)
+	addUpdates(t, f)
+
+	producer := &topicProducer{}
+	sink := &stallingSink{stalled: make(chan struct{}), release: make(chan struct{})}
+	sessions := make(chan *postgres.LockSession, 1)
+	startReplicaWith(t, producer, 1, 0, replicaOptions{
+		wrapSink: func(s outbox.Sink) outbox.Sink {
+			sink.Sink = s
+			return sink
+		},
+		sessions: sessions,
+	})
+	// Cleanups run last first, so a failing test releases the batch before
+	// the replica is stopped
+	var releaseOnce sync.Once
+	releaseBatch := func() { releaseOnce.Do(func() { close(sink.release) }) }
+	t.Cleanup(releaseBatch)
+
+	var session *postgres.LockSession
+	select {
+	case session = <-sessions:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the first replica never opened a lock session")
+	}
+	select {
+	case <-sink.stalled:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the first replica never started a batch")
+	}
+
+	// The first replica loses its lock in the middle of a batch of the first
+	// two events, as it would to idle_session_timeout, and a second one takes
+	// the partition over
+	require.NoError(t, session.Close(context.Background()))
+	startReplica(t, producer, 1, 0)
+	time.Sleep(10 * processorInterval)
+	assert.Zero(t, producer.count(), "the events behind the batch in flight wait for it")
+
+	releaseBatch()
+	require.Eventually(t, func() bool { return producer.count() >= 4 }, 10*time.Second, processorInterval)
+	time.Sleep(5 * processorInterval)
+
+	assertDeliveredOnceInOrder(t, producer, f)
+}
@@ -0,0 +1,144 @@
+//go:build unit
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lockTable stands in for the advisory locks shared by all replicas.
+type lockTable struct {
+	mu     sync.Mutex
+	owners map[int32]*fakeLocker
+}
+
+func newLockTable() *lockTable {
+	return &lockTable{owners: make(map[int32]*fakeLocker)}
+}
+
+func (t *lockTable) newLocker(context.Context) (Locker, error) {
+	return &fakeLocker{table: t}, nil
+}
+
+type fakeLocker struct {
+	table *lockTable
+	lost  bool
+}
+
+func (l *fakeLocker) TryLock(_ context.Context, key int32) (bool, error) {
+	l.table.mu.Lock()
+	defer l.table.mu.Unlock()
+
+	if owner, ok := l.table.owners[key]; ok && owner != l {
+		return false, nil
+	}
+	l.table.owners[key] = l
+	return true, nil
+}
+
+func (l *fakeLocker) Ping(context.Context) error {
+	if l.lost {
+		return errors.New("connection lost")
+	}
+	return nil
+}
+
+// Close releases the locks like a closed connection does.
+func (l *fakeLocker) Close(context.Context) error {
+	l.table.mu.Lock()
+	defer l.table.mu.Unlock()
+
+	for key, owner := range l.table.owners {
+		if owner == l {
+			delete(l.table.owners, key)
+		}
+	}
+	return nil
+}
+
+func newPartitionedProcessors(n int, partitioning Partitioning) []*Processor {
+	processors := make([]*Processor, n)
+	for i := range processors {
+		processors[i] = NewProcessor(nil, nil, nil, 100, 0, partitioning)
+	}
+	return processors
+}
+
+func TestProcessor_Claim_LeaderElection(t *testing.T) {
+	ctx := context.Background()
+	table := newLockTable()
+	processors := newPartitionedProcessors(3, Partitioning{Partitions: 1, NewLocker: table.newLocker})
+
+	var claimed [][]int
+	for _, p := range processors {
+		owned, err := p.claim(ctx)
+		require.NoError(t, err)
+		claimed = append(claimed, owned)
+	}
+	assert.Equal(t, [][]int{{0}, {}, {}}, claimed, "only the first replica leads")
+
+	// The leader keeps its partition on the next tick
+	owned, err := processors[0].claim(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0}, owned)
+
+	// Once the leader shuts down a standby takes over
+	require.NoError(t, processors[0].releaseLocks())
+	owned, err = processors[1].claim(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0}, owned)
+}
+
+func TestProcessor_Claim_SplitsPartitions(t *testing.T) {
+	ctx := context.Background()
+	table := newLockTable()
+	processors := newPartitionedProcessors(3, Partitioning{Partitions: 6, MaxOwned: 2, NewLocker: table.newLocker})
+
+	var claimed [][]int
+	for _, p := range processors {
+		owned, err := p.claim(ctx)
+		require.NoError(t, err)
+		claimed = append(claimed, owned)
+	}
+	assert.Equal(t, [][]int{{0, 1}, {2, 3}, {4, 5}}, claimed)
+}
+
+func TestProcessor_Claim_TakesOverLostSession(t *testing.T) {
+	ctx := context.Background()
+	table := newLockTable()
+	processors := newPartitionedProcessors(2, Partitioning{Partitions: 4, NewLocker: table.newLocker})
+
+	owned, err := processors[0].claim(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3}, owned)
+
+	owned, err = processors[1].claim(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, owned)
+
+	// The first replica notices its session is gone and stops publishing
+	processors[0].locker.(*fakeLocker).lost = true
+	owned, err = processors[0].claim(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3}, owned, "reopens a session and reclaims the free partitions")
+
+	// Had the second replica ticked first it would have taken them instead
+	require.NoError(t, processors[0].releaseLocks())
+	owned, err = processors[1].claim(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3}, owned)
+}
+
+func TestProcessor_Claim_WithoutLocker(t *testing.T) {
+	p := NewProcessor(nil, nil, nil, 100, 0, Partitioning{Partitions: 3})
+
+	owned, err := p.claim(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2}, owned)
+}
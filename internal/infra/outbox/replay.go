@@ -0,0 +1,213 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/pkg/logger"
+	kafkago "github.com/segmentio/kafka-go"
+	"golang.org/x/time/rate"
+)
+
+// PublisherFactory opens a producer and the matching value serializer for
+// topic. The replayer closes the producer when a run ends.
+type PublisherFactory func(ctx context.Context, topic string) (MessageProducer, ValueSerializer, error)
+
+type ReplayOptions struct {
+	Filter postgres.OutboxFilter
+	// Topic overrides the configured topic
+	Topic string
+	// RatePerSecond caps published messages per second; 0 means no limit
+	RatePerSecond float64
+	BatchSize     int
+}
+
+type SnapshotOptions struct {
+	Topic         string
+	RatePerSecond float64
+	BatchSize     int
+}
+
+// Progress of a replay or snapshot run. LastID is the last outbox ID or
+// company ID published; a failed run can be resumed after it.
+type Progress struct {
+	Total     int64  `json:"total"`
+	Published int64  `json:"published"`
+	LastID    string `json:"last_id,omitempty"`
+}
+
+// Replayer re-publishes outbox events straight to Kafka, leaving their
+// processed state alone, and publishes snapshots of the current companies.
+type Replayer struct {
+	outboxRepo   *postgres.OutboxRepo
+	companyRepo  *postgres.CompanyRepo
+	newPublisher PublisherFactory
+	topic        string
+	batchSize    int
+}
+
+func NewReplayer(
+	outboxRepo *postgres.OutboxRepo,
+	companyRepo *postgres.CompanyRepo,
+	newPublisher PublisherFactory,
+	topic string,
+	batchSize int,
+) *Replayer {
+	return &Replayer{
+		outboxRepo:   outboxRepo,
+		companyRepo:  companyRepo,
+		newPublisher: newPublisher,
+		topic:        topic,
+		batchSize:    batchSize,
+	}
+}
+
+// batchFunc returns the next page of messages and the ID of its last entry;
+// an empty page ends the run.
+type batchFunc func(ctx context.Context, serializer ValueSerializer, afterID string, limit int) ([]kafkago.Message, string, error)
+
+// Replay publishes the outbox events matching opts.Filter in ID order. The
+// messages carry a "replay" header so consumers can tell them apart.
+func (r *Replayer) Replay(ctx context.Context, opts ReplayOptions, onProgress func(Progress)) (Progress, error) {
+	total, err := r.outboxRepo.Count(ctx, opts.Filter)
+	if err != nil {
+		return Progress{}, fmt.Errorf("failed to count outbox events: %w", err)
+	}
+
+	next := func(ctx context.Context, serializer ValueSerializer, afterID string, limit int) ([]kafkago.Message, string, error) {
+		var after int64
+		if afterID != "" {
+			after, _ = strconv.ParseInt(afterID, 10, 64)
+		}
+
+		events, err := r.outboxRepo.List(ctx, opts.Filter, after, limit)
+		if err != nil || len(events) == 0 {
+			return nil, "", err
+		}
+
+		messages := make([]kafkago.Message, 0, len(events))
+		for _, e := range events {
+			msg, err := newMessage(ctx, serializer, e)
+			if err != nil {
+				return nil, "", err
+			}
+			msg.Headers = append(msg.Headers, kafkago.Header{Key: "replay", Value: []byte("true")})
+			messages = append(messages, msg)
+		}
+		return messages, strconv.FormatInt(events[len(events)-1].ID, 10), nil
+	}
+
+	return r.run(ctx, "replay", opts.Topic, opts.RatePerSecond, opts.BatchSize, total, next, onProgress)
+}
+
+// Snapshot publishes a synthetic CompanySnapshot event for every company.
+func (r *Replayer) Snapshot(ctx context.Context, opts SnapshotOptions, onProgress func(Progress)) (Progress, error) {
+	total, err := r.companyRepo.Count(ctx)
+	if err != nil {
+		return Progress{}, fmt.Errorf("failed to count companies: %w", err)
+	}
+
+	next := func(ctx context.Context, serializer ValueSerializer, afterID string, limit int) ([]kafkago.Message, string, error) {
+		companies, err := r.companyRepo.List(ctx, company.ListFilter{}, afterID, limit)
+		if err != nil || len(companies) == 0 {
+			return nil, "", err
+		}
+
+		messages := make([]kafkago.Message, 0, len(companies))
+		for _, c := range companies {
+			event := company.NewCompanySnapshotEvent(c)
+
+			payload, err := json.Marshal(event.Payload())
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to marshal snapshot of %s: %w", event.AggregateID(), err)
+			}
+
+			value, err := serializer.Serialize(ctx, event.EventName(), payload)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to serialize snapshot of %s: %w", event.AggregateID(), err)
+			}
+
+			messages = append(messages, kafkago.Message{
+				Key:   []byte(event.AggregateID()),
+				Value: value,
+				Headers: []kafkago.Header{
+					{Key: "event_name", Value: []byte(event.EventName())},
+					{Key: "content_type", Value: []byte(serializer.ContentType())},
+				},
+			})
+		}
+		return messages, companies[len(companies)-1].ID().String(), nil
+	}
+
+	return r.run(ctx, "snapshot", opts.Topic, opts.RatePerSecond, opts.BatchSize, total, next, onProgress)
+}
+
+func (r *Replayer) run(
+	ctx context.Context,
+	kind string,
+	topic string,
+	ratePerSecond float64,
+	batchSize int,
+	total int64,
+	next batchFunc,
+	onProgress func(Progress),
+) (progress Progress, err error) {
+	if topic == "" {
+		topic = r.topic
+	}
+	if batchSize <= 0 {
+		batchSize = r.batchSize
+	}
+
+	limit := rate.Inf
+	if ratePerSecond > 0 {
+		limit = rate.Limit(ratePerSecond)
+		// Keep batches no larger than one second worth of messages so the
+		// rate is spread evenly instead of bursting
+		batchSize = min(batchSize, int(math.Ceil(ratePerSecond)))
+	}
+	limiter := rate.NewLimiter(limit, batchSize)
+
+	producer, serializer, err := r.newPublisher(ctx, topic)
+	if err != nil {
+		return Progress{}, fmt.Errorf("failed to open publisher for %s: %w", topic, err)
+	}
+	defer func() { err = errors.Join(err, producer.Close()) }()
+
+	log := logger.FromContext(ctx).With("run", kind, "topic", topic)
+	log.Info("outbox "+kind+" started", "total", total, "batch_size", batchSize, "rate", ratePerSecond)
+
+	progress = Progress{Total: total}
+	for {
+		messages, lastID, err := next(ctx, serializer, progress.LastID, batchSize)
+		if err != nil {
+			return progress, err
+		}
+		if len(messages) == 0 {
+			break
+		}
+
+		if err := limiter.WaitN(ctx, len(messages)); err != nil {
+			return progress, err
+		}
+
+		if err := producer.PublishBatch(ctx, messages); err != nil {
+			return progress, fmt.Errorf("failed to write messages to kafka: %w", err)
+		}
+
+		progress.Published += int64(len(messages))
+		progress.LastID = lastID
+		if onProgress != nil {
+			onProgress(progress)
+		}
+	}
+
+	log.Info("outbox "+kind+" finished", "published", progress.Published)
+	return progress, nil
+}
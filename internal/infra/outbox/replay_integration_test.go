@@ -0,0 +1,168 @@
+//go:build integration
+
+package outbox_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/dubininme/xm-assessment/internal/config"
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/infra/outbox"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres/pgtest"
+	"github.com/dubininme/xm-assessment/internal/infra/schemaregistry"
+	"github.com/google/uuid"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testDbConfig config.DbConfig
+
+func TestMain(m *testing.M) {
+	cfg, stop, err := pgtest.Start(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to start test database:", err)
+		os.Exit(1)
+	}
+	testDbConfig = cfg
+
+	code := m.Run()
+	if err := stop(); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to stop test database:", err)
+	}
+	os.Exit(code)
+}
+
+// topicProducer records what each replay run published and to which topic.
+type topicProducer struct {
+	mu       sync.Mutex
+	topics   []string
+	messages []kafkago.Message
+}
+
+func (p *topicProducer) factory(ctx context.Context, topic string) (outbox.MessageProducer, outbox.ValueSerializer, error) {
+	p.mu.Lock()
+	p.topics = append(p.topics, topic)
+	p.mu.Unlock()
+
+	serializer, err := schemaregistry.NewSerializer(ctx, schemaregistry.FormatJSON, nil, topic)
+	return p, serializer, err
+}
+
+func (p *topicProducer) PublishBatch(_ context.Context, messages []kafkago.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, messages...)
+	return nil
+}
+
+func (p *topicProducer) Close() error { return nil }
+
+func header(msg kafkago.Message, key string) string {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+type fixture struct {
+	outboxRepo  *postgres.OutboxRepo
+	companyRepo *postgres.CompanyRepo
+	companies   []*company.Company
+}
+
+func setup(t *testing.T, n int) fixture {
+	t.Helper()
+	ctx := context.Background()
+
+	db, err := postgres.Connect(ctx, testDbConfig)
+	require.NoError(t, err)
+	t.Cleanup(db.Close)
+	require.NoError(t, pgtest.Reset(ctx, db))
+
+	f := fixture{outboxRepo: postgres.NewOutboxRepo(db), companyRepo: postgres.NewCompanyRepo(db)}
+	for i := range n {
+		c, err := company.NewCompany(uuid.New(), fmt.Sprintf("replay-%d", i), "", i+1, company.CorporationsType.String())
+		require.NoError(t, err)
+		require.NoError(t, f.companyRepo.Create(ctx, *c))
+		require.NoError(t, f.outboxRepo.Publish(ctx, company.NewCompanyCreatedEvent(c)))
+		f.companies = append(f.companies, c)
+	}
+	return f
+}
+
+func TestReplay_Filters(t *testing.T) {
+	ctx := context.Background()
+	f := setup(t, 3)
+
+	// Mark everything processed: replay ignores the processed state
+	events, err := f.outboxRepo.GetUnprocessed(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	require.NoError(t, f.outboxRepo.MarkProcessed(ctx, []int64{events[0].ID, events[1].ID, events[2].ID}))
+
+	producer := &topicProducer{}
+	replayer := outbox.NewReplayer(f.outboxRepo, f.companyRepo, producer.factory, "company-events", 100)
+
+	target := f.companies[1].ID().String()
+	progress, err := replayer.Replay(ctx, outbox.ReplayOptions{
+		Filter: postgres.OutboxFilter{AggregateID: target, EventType: "CompanyCreated"},
+		Topic:  "company-events-replay",
+	}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, outbox.Progress{Total: 1, Published: 1, LastID: fmt.Sprint(events[1].ID)}, progress)
+	assert.Equal(t, []string{"company-events-replay"}, producer.topics)
+	require.Len(t, producer.messages, 1)
+	assert.Equal(t, target, string(producer.messages[0].Key))
+	assert.Equal(t, "true", header(producer.messages[0], "replay"))
+	assert.Equal(t, "CompanyCreated", header(producer.messages[0], "event_name"))
+
+	// ID range, in pages of one
+	producer = &topicProducer{}
+	replayer = outbox.NewReplayer(f.outboxRepo, f.companyRepo, producer.factory, "company-events", 100)
+
+	var reports []outbox.Progress
+	progress, err = replayer.Replay(ctx, outbox.ReplayOptions{
+		Filter:    postgres.OutboxFilter{FromID: events[1].ID, ToID: events[2].ID},
+		BatchSize: 1,
+	}, func(p outbox.Progress) { reports = append(reports, p) })
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 2, progress.Published)
+	assert.Len(t, reports, 2)
+	assert.Equal(t, []string{"company-events"}, producer.topics)
+}
+
+func TestSnapshot_PublishesEveryCompany(t *testing.T) {
+	ctx := context.Background()
+	f := setup(t, 5)
+
+	producer := &topicProducer{}
+	replayer := outbox.NewReplayer(f.outboxRepo, f.companyRepo, producer.factory, "company-events", 2)
+
+	var reports []outbox.Progress
+	progress, err := replayer.Snapshot(ctx, outbox.SnapshotOptions{RatePerSecond: 1000},
+		func(p outbox.Progress) { reports = append(reports, p) })
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 5, progress.Total)
+	assert.EqualValues(t, 5, progress.Published)
+	assert.Len(t, reports, 3, "batches of 2")
+
+	keys := map[string]bool{}
+	for _, msg := range producer.messages {
+		assert.Equal(t, "CompanySnapshot", header(msg, "event_name"))
+		keys[string(msg.Key)] = true
+	}
+	for _, c := range f.companies {
+		assert.True(t, keys[c.ID().String()], "snapshot of %s", c.ID())
+	}
+}
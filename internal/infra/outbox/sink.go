@@ -0,0 +1,81 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/internal/infra/schemaregistry"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Sink is a destination the processor delivers outbox events to. Deliver
+// receives events in outbox ID order and returns how many of them, from the
+// start, were delivered; the rest are retried on the next run, unless the
+// error is a PermanentError. The name keys the sink's delivery tracking, so it
+// must stay stable across deployments.
+type Sink interface {
+	Name() string
+	Deliver(ctx context.Context, events []postgres.OutboxEvent) (int, error)
+	Close() error
+}
+
+// PermanentError is returned by Deliver when the event after the delivered
+// ones can never be delivered, e.g. because its destination rejects it. The
+// processor records the event as failed for the sink and goes on with the
+// rest, instead of retrying it ahead of them forever.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// KafkaSink publishes events to Kafka as a single batch, keyed by aggregate.
+type KafkaSink struct {
+	producer       MessageProducer
+	serializer     ValueSerializer
+	publishTimeout time.Duration
+}
+
+var _ Sink = (*KafkaSink)(nil)
+
+func NewKafkaSink(producer MessageProducer, serializer ValueSerializer, publishTimeout time.Duration) *KafkaSink {
+	return &KafkaSink{producer: producer, serializer: serializer, publishTimeout: publishTimeout}
+}
+
+func (s *KafkaSink) Name() string { return "kafka" }
+
+func (s *KafkaSink) Deliver(ctx context.Context, events []postgres.OutboxEvent) (int, error) {
+	// A payload its schema rejects ends the batch: the events before it are
+	// published and it is reported as failed for good
+	messages := make([]kafkago.Message, 0, len(events))
+	var serializeErr error
+	for _, e := range events {
+		msg, err := newMessage(ctx, s.serializer, e)
+		if err != nil {
+			if !errors.Is(err, schemaregistry.ErrInvalidPayload) {
+				return 0, err
+			}
+			serializeErr = &PermanentError{Err: err}
+			break
+		}
+		messages = append(messages, msg)
+	}
+
+	if len(messages) > 0 {
+		publishCtx, cancel := context.WithTimeout(ctx, s.publishTimeout)
+		defer cancel()
+
+		if err := s.producer.PublishBatch(publishCtx, messages); err != nil {
+			return 0, fmt.Errorf("failed to write messages to kafka: %w", err)
+		}
+	}
+	return len(messages), serializeErr
+}
+
+func (s *KafkaSink) Close() error {
+	return s.producer.Close()
+}
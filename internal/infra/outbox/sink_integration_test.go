@@ -0,0 +1,144 @@
+//go:build integration
+
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/infra/outbox"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/internal/infra/schemaregistry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSink delivers up to accept events per call, failing on the rest;
+// a negative accept takes everything.
+type recordingSink struct {
+	name      string
+	accept    int
+	delivered []int64
+}
+
+func (s *recordingSink) Name() string { return s.name }
+
+func (s *recordingSink) Deliver(_ context.Context, events []postgres.OutboxEvent) (int, error) {
+	n := len(events)
+	var err error
+	if s.accept >= 0 && s.accept < n {
+		n, err = s.accept, errors.New(s.name+" is down")
+	}
+
+	for _, e := range events[:n] {
+		s.delivered = append(s.delivered, e.ID)
+	}
+	return n, err
+}
+
+func (s *recordingSink) Close() error { return nil }
+
+func TestProcessor_FanOutTracksSinksIndependently(t *testing.T) {
+	ctx := context.Background()
+	f := setup(t, 3)
+
+	db, err := postgres.Connect(ctx, testDbConfig)
+	require.NoError(t, err)
+	t.Cleanup(db.Close)
+
+	healthy := &recordingSink{name: "healthy", accept: -1}
+	flaky := &recordingSink{name: "flaky", accept: 1}
+	processor := outbox.NewProcessor(f.outboxRepo, []outbox.Sink{healthy, flaky},
+		postgres.NewTxManager(db, testDbConfig.DBTxMaxRetries), 100, processorInterval, outbox.Partitioning{})
+
+	// The flaky sink takes the first event only; the healthy one gets all
+	require.Error(t, processor.ProcessBatch(ctx))
+	assert.Equal(t, []int64{1, 2, 3}, healthy.delivered)
+	assert.Equal(t, []int64{1}, flaky.delivered)
+
+	pending, err := f.outboxRepo.GetUnprocessed(ctx, 10)
+	require.NoError(t, err)
+	assert.Len(t, pending, 2, "the first event reached every sink")
+
+	// Retrying only resends what the flaky sink is missing
+	flaky.accept = -1
+	require.NoError(t, processor.ProcessBatch(ctx))
+	assert.Equal(t, []int64{1, 2, 3}, healthy.delivered)
+	assert.Equal(t, []int64{1, 2, 3}, flaky.delivered)
+
+	pending, err = f.outboxRepo.GetUnprocessed(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	var deliveries int
+	require.NoError(t, db.QueryRow(ctx, `SELECT count(*) FROM outbox_deliveries`).Scan(&deliveries))
+	assert.Equal(t, 6, deliveries)
+}
+
+// renameRejecting fails to encode CompanyRenamed, which has no schema.
+type renameRejecting struct{}
+
+func (renameRejecting) Serialize(_ context.Context, eventType string, payload []byte) ([]byte, error) {
+	if eventType == "CompanyRenamed" {
+		return nil, fmt.Errorf("%w: no schema for event type %q", schemaregistry.ErrInvalidPayload, eventType)
+	}
+	return payload, nil
+}
+
+func (renameRejecting) ContentType() string { return "application/json" }
+
+func TestProcessor_SetsAsideEventsASinkCannotDeliver(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		sinks func(kafka outbox.Sink) []outbox.Sink
+	}{
+		{"single sink", func(kafka outbox.Sink) []outbox.Sink { return []outbox.Sink{kafka} }},
+		{"fan-out", func(kafka outbox.Sink) []outbox.Sink {
+			return []outbox.Sink{kafka, &recordingSink{name: "healthy", accept: -1}}
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := setup(t, 1)
+
+			db, err := postgres.Connect(ctx, testDbConfig)
+			require.NoError(t, err)
+			t.Cleanup(db.Close)
+
+			// A bad event between two good ones of the same company
+			_, err = db.Exec(ctx, `INSERT INTO outbox (event_type, aggregate_id, payload, created_at)
+			                       VALUES ('CompanyRenamed', $1, '{}', 0)`, f.companies[0].ID())
+			require.NoError(t, err)
+			require.NoError(t, f.outboxRepo.Publish(ctx, company.NewCompanyDeletedEvent(f.companies[0])))
+
+			producer := &topicProducer{}
+			processor := outbox.NewProcessor(f.outboxRepo, tc.sinks(outbox.NewKafkaSink(producer, renameRejecting{}, time.Second)),
+				postgres.NewTxManager(db, testDbConfig.DBTxMaxRetries), 100, processorInterval, outbox.Partitioning{})
+
+			require.NoError(t, processor.ProcessBatch(ctx))
+			require.Len(t, producer.messages, 2)
+			assert.Equal(t, "1", header(producer.messages[0], "outbox_id"))
+			assert.Equal(t, "3", header(producer.messages[1], "outbox_id"))
+
+			pending, err := f.outboxRepo.GetUnprocessed(ctx, 10)
+			require.NoError(t, err)
+			assert.Empty(t, pending, "the bad event does not hold back the others")
+
+			var failedID int64
+			var reason string
+			require.NoError(t, db.QueryRow(ctx, `SELECT outbox_id, error FROM outbox_deliveries
+			                                     WHERE sink = 'kafka' AND error IS NOT NULL`).Scan(&failedID, &reason))
+			assert.Equal(t, int64(2), failedID)
+			assert.Contains(t, reason, "CompanyRenamed")
+			assert.Equal(t, int64(1), processor.Stats()["failed_events"].(map[string]any)["kafka"])
+
+			// Nothing is left to retry
+			require.NoError(t, processor.ProcessBatch(ctx))
+			assert.Len(t, producer.messages, 2)
+		})
+	}
+}
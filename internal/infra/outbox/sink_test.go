@@ -0,0 +1,85 @@
+//go:build unit
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/internal/infra/schemaregistry"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rejectingSerializer passes payloads through, failing with err on the event
+// types in reject.
+type rejectingSerializer struct {
+	reject map[string]error
+}
+
+func (s rejectingSerializer) Serialize(_ context.Context, eventType string, payload []byte) ([]byte, error) {
+	if err := s.reject[eventType]; err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func (s rejectingSerializer) ContentType() string { return "application/json" }
+
+type batchProducer struct {
+	batches [][]kafkago.Message
+}
+
+func (p *batchProducer) PublishBatch(_ context.Context, messages []kafkago.Message) error {
+	p.batches = append(p.batches, messages)
+	return nil
+}
+
+func (p *batchProducer) Close() error { return nil }
+
+func kafkaEvents(types ...string) []postgres.OutboxEvent {
+	events := make([]postgres.OutboxEvent, len(types))
+	for i, typ := range types {
+		events[i] = postgres.OutboxEvent{ID: int64(i + 1), EventType: typ, Payload: []byte(`{}`)}
+	}
+	return events
+}
+
+func TestKafkaSink_PublishesUpToInvalidPayload(t *testing.T) {
+	producer := &batchProducer{}
+	serializer := rejectingSerializer{reject: map[string]error{
+		"Bad": fmt.Errorf("%w: no schema", schemaregistry.ErrInvalidPayload),
+	}}
+	s := NewKafkaSink(producer, serializer, time.Second)
+
+	delivered, err := s.Deliver(context.Background(), kafkaEvents("Good", "Good", "Bad", "Good"))
+	assert.Equal(t, 2, delivered)
+	var permanent *PermanentError
+	require.ErrorAs(t, err, &permanent)
+	require.Len(t, producer.batches, 1)
+	assert.Len(t, producer.batches[0], 2)
+
+	// Nothing to publish before the invalid payload
+	delivered, err = s.Deliver(context.Background(), kafkaEvents("Bad", "Good"))
+	assert.Zero(t, delivered)
+	require.ErrorAs(t, err, &permanent)
+	assert.Len(t, producer.batches, 1)
+}
+
+func TestKafkaSink_RegistryFailureIsRetried(t *testing.T) {
+	producer := &batchProducer{}
+	serializer := rejectingSerializer{reject: map[string]error{"Bad": errors.New("schema registry request failed")}}
+	s := NewKafkaSink(producer, serializer, time.Second)
+
+	delivered, err := s.Deliver(context.Background(), kafkaEvents("Good", "Bad"))
+	assert.Zero(t, delivered)
+	require.Error(t, err)
+	var permanent *PermanentError
+	assert.False(t, errors.As(err, &permanent))
+	assert.Empty(t, producer.batches)
+}
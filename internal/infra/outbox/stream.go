@@ -0,0 +1,295 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/delivery/http/handler"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/pkg/logger"
+)
+
+const (
+	// pendingIDs buffers notified IDs while a fetch is running
+	pendingIDs = 4096
+	// maxFetch caps the IDs loaded by one query
+	maxFetch = 500
+	// recentIDs is how many broadcast IDs are remembered to drop duplicates
+	recentIDs = 4096
+)
+
+// StreamRepo reads the outbox for a Stream. *postgres.OutboxRepo implements
+// it; tests substitute an in-process fake.
+type StreamRepo interface {
+	List(ctx context.Context, f postgres.OutboxFilter, afterID int64, limit int) ([]postgres.OutboxEvent, error)
+	ListByIDs(ctx context.Context, ids []int64) ([]postgres.OutboxEvent, error)
+	LatestID(ctx context.Context) (int64, error)
+}
+
+var _ StreamRepo = (*postgres.OutboxRepo)(nil)
+
+// ListenFunc runs a LISTEN session on the outbox channel, see
+// postgres.ListenOutbox.
+type ListenFunc func(ctx context.Context, onListen func(ctx context.Context) error, onID func(id int64)) error
+
+// Stream fans new outbox events out to live subscribers. Every replica runs
+// its own Stream fed by Postgres notifications, so a client sees the writes
+// of all replicas whichever one it is connected to.
+//
+// Each subscriber has a bounded buffer. One that falls behind is dropped
+// rather than slowing everyone down or growing without limit; it reconnects
+// and resumes from the outbox with the last ID it got.
+type Stream struct {
+	repo       StreamRepo
+	listen     ListenFunc
+	buffer     int
+	retryDelay time.Duration
+
+	mu      sync.Mutex
+	subs    map[*subscriber]struct{}
+	stopped bool
+	// lastID is the highest ID broadcast, to catch up from after a reconnect
+	lastID int64
+	recent map[int64]struct{}
+	order  []int64
+}
+
+type subscriber struct {
+	filter handler.StreamFilter
+	ch     chan handler.StreamEvent
+}
+
+var _ handler.CompanyEventStream = (*Stream)(nil)
+
+// NewStream gives each subscriber a buffer of buffer events and waits
+// retryDelay before listening again after the connection failed.
+func NewStream(repo StreamRepo, listen ListenFunc, buffer int, retryDelay time.Duration) *Stream {
+	return &Stream{
+		repo:       repo,
+		listen:     listen,
+		buffer:     max(buffer, 1),
+		retryDelay: retryDelay,
+		subs:       make(map[*subscriber]struct{}),
+		recent:     make(map[int64]struct{}),
+	}
+}
+
+// Start listens for new events until ctx is done, reconnecting after
+// failures. Subscribers are closed when it returns.
+func (s *Stream) Start(ctx context.Context) error {
+	log := logger.FromContext(ctx)
+	defer s.stop()
+
+	ids := make(chan int64, pendingIDs)
+	go s.fetch(ctx, ids)
+
+	onID := func(id int64) {
+		select {
+		case ids <- id:
+		case <-ctx.Done():
+		}
+	}
+
+	log.Info("company event stream started")
+	started := false
+	for {
+		err := s.listen(ctx, func(ctx context.Context) error {
+			if !started {
+				started = true
+				return s.startAtLatest(ctx)
+			}
+			return s.catchUp(ctx)
+		}, onID)
+
+		if ctx.Err() != nil {
+			log.Info("company event stream stopping")
+			return nil
+		}
+
+		log.Warn("company event stream lost its listener, reconnecting", "error", err, "retry_in", s.retryDelay)
+		select {
+		case <-ctx.Done():
+			log.Info("company event stream stopping")
+			return nil
+		case <-time.After(s.retryDelay):
+		}
+	}
+}
+
+// Subscribe returns live events matching filter; the channel is closed when
+// the subscriber falls behind or the stream stops.
+func (s *Stream) Subscribe(filter handler.StreamFilter) (<-chan handler.StreamEvent, func()) {
+	sub := &subscriber{filter: filter, ch: make(chan handler.StreamEvent, s.buffer)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		close(sub.ch)
+		return sub.ch, func() {}
+	}
+	s.subs[sub] = struct{}{}
+
+	return sub.ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.drop(sub)
+	}
+}
+
+func (s *Stream) Since(ctx context.Context, filter handler.StreamFilter, afterID int64, limit int) ([]handler.StreamEvent, error) {
+	events, err := s.repo.List(ctx, postgres.OutboxFilter{
+		AggregateIDs: filter.CompanyIDs,
+		EventTypes:   filter.EventTypes,
+	}, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]handler.StreamEvent, len(events))
+	for i, e := range events {
+		out[i] = toStreamEvent(e)
+	}
+	return out, nil
+}
+
+// fetch loads notified events, batching the IDs that queue up meanwhile.
+func (s *Stream) fetch(ctx context.Context, ids <-chan int64) {
+	for {
+		var batch []int64
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-ids:
+			batch = append(batch, id)
+		}
+
+	drain:
+		for len(batch) < maxFetch {
+			select {
+			case id := <-ids:
+				batch = append(batch, id)
+			default:
+				break drain
+			}
+		}
+
+		events, err := s.repo.ListByIDs(ctx, batch)
+		if err != nil {
+			if ctx.Err() == nil {
+				// The batch is lost to the live stream; the subscribers
+				// reconnect and read it back from the outbox instead
+				logger.FromContext(ctx).Error("failed to load streamed outbox events, closing subscribers",
+					"error", err, "count", len(batch))
+				s.mu.Lock()
+				s.dropAll()
+				s.mu.Unlock()
+			}
+			continue
+		}
+		s.broadcast(events)
+	}
+}
+
+// startAtLatest makes the first session stream only what comes next rather
+// than the whole outbox.
+func (s *Stream) startAtLatest(ctx context.Context) error {
+	latest, err := s.repo.LatestID(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastID = max(s.lastID, latest)
+	return nil
+}
+
+// catchUp broadcasts what was written while no listener was connected.
+func (s *Stream) catchUp(ctx context.Context) error {
+	s.mu.Lock()
+	afterID := s.lastID
+	s.mu.Unlock()
+
+	for {
+		events, err := s.repo.List(ctx, postgres.OutboxFilter{}, afterID, maxFetch)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+		s.broadcast(events)
+		afterID = events[len(events)-1].ID
+	}
+}
+
+func (s *Stream) broadcast(events []postgres.OutboxEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range events {
+		if !s.remember(e.ID) {
+			continue
+		}
+		s.lastID = max(s.lastID, e.ID)
+
+		event := toStreamEvent(e)
+		for sub := range s.subs {
+			if !sub.filter.Match(event) {
+				continue
+			}
+			select {
+			case sub.ch <- event:
+			default:
+				s.drop(sub)
+			}
+		}
+	}
+}
+
+// remember reports whether id is new. Catching up after a reconnect can load
+// an event that is also notified.
+func (s *Stream) remember(id int64) bool {
+	if _, ok := s.recent[id]; ok {
+		return false
+	}
+
+	s.recent[id] = struct{}{}
+	s.order = append(s.order, id)
+	if len(s.order) > recentIDs {
+		delete(s.recent, s.order[0])
+		s.order = s.order[1:]
+	}
+	return true
+}
+
+// drop closes the channel of sub; s.mu must be held.
+func (s *Stream) drop(sub *subscriber) {
+	if _, ok := s.subs[sub]; ok {
+		delete(s.subs, sub)
+		close(sub.ch)
+	}
+}
+
+func (s *Stream) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopped = true
+	s.dropAll()
+}
+
+// dropAll closes the channels of every subscriber; s.mu must be held.
+func (s *Stream) dropAll() {
+	for sub := range s.subs {
+		s.drop(sub)
+	}
+}
+
+func toStreamEvent(e postgres.OutboxEvent) handler.StreamEvent {
+	return handler.StreamEvent{
+		ID:        e.ID,
+		EventType: e.EventType,
+		CompanyID: e.AggregateID,
+		Payload:   e.Payload,
+		CreatedAt: e.CreatedAt,
+	}
+}
@@ -0,0 +1,108 @@
+//go:build integration
+
+package outbox_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/delivery/http/handler"
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/infra/outbox"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startStream runs a Stream on its own connection pool, like another replica,
+// and returns once it is listening.
+func startStream(t *testing.T) *outbox.Stream {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	db, err := postgres.Connect(ctx, testDbConfig)
+	require.NoError(t, err)
+
+	listening := make(chan struct{}, 1)
+	s := outbox.NewStream(postgres.NewOutboxRepo(db), func(ctx context.Context, onListen func(ctx context.Context) error, onID func(id int64)) error {
+		return postgres.ListenOutbox(ctx, db, func(ctx context.Context) error {
+			defer func() { listening <- struct{}{} }()
+			return onListen(ctx)
+		}, onID)
+	}, 16, 10*time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = s.Start(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		db.Close()
+	})
+
+	select {
+	case <-listening:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not start listening")
+	}
+	return s
+}
+
+func nextEvent(t *testing.T, ch <-chan handler.StreamEvent) handler.StreamEvent {
+	t.Helper()
+	select {
+	case e, ok := <-ch:
+		require.True(t, ok, "stream closed")
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event streamed")
+		return handler.StreamEvent{}
+	}
+}
+
+func TestStream_DeliversCommittedEventsToEveryReplica(t *testing.T) {
+	ctx := context.Background()
+	f := setup(t, 1)
+	existing := f.companies[0]
+
+	first, second := startStream(t), startStream(t)
+	all, cancelAll := first.Subscribe(handler.StreamFilter{})
+	defer cancelAll()
+	filtered, cancelFiltered := second.Subscribe(handler.StreamFilter{EventTypes: []string{company.EventCompanyDeleted}})
+	defer cancelFiltered()
+
+	db, err := postgres.Connect(ctx, testDbConfig)
+	require.NoError(t, err)
+	t.Cleanup(db.Close)
+	txManager := postgres.NewTxManager(db, testDbConfig.DBTxMaxRetries)
+
+	created, err := company.NewCompany(uuid.New(), "streamed", "", 3, company.CorporationsType.String())
+	require.NoError(t, err)
+	require.NoError(t, txManager.Do(ctx, func(ctx context.Context) error {
+		if err := f.outboxRepo.Publish(ctx, company.NewCompanyCreatedEvent(created)); err != nil {
+			return err
+		}
+		return f.outboxRepo.Publish(ctx, company.NewCompanyDeletedEvent(existing))
+	}))
+
+	// The event created before the streams started is not sent
+	e := nextEvent(t, all)
+	assert.EqualValues(t, 2, e.ID)
+	assert.Equal(t, company.EventCompanyCreated, e.EventType)
+	assert.Equal(t, created.ID().String(), e.CompanyID)
+	assert.EqualValues(t, 3, nextEvent(t, all).ID)
+
+	e = nextEvent(t, filtered)
+	assert.EqualValues(t, 3, e.ID)
+	assert.Equal(t, existing.ID().String(), e.CompanyID)
+
+	// Resuming reads the same events back from the outbox
+	missed, err := first.Since(ctx, handler.StreamFilter{CompanyIDs: []string{created.ID().String()}}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, missed, 1)
+	assert.EqualValues(t, 2, missed[0].ID)
+}
@@ -0,0 +1,139 @@
+//go:build unit
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/delivery/http/handler"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	streamCompanyA = "5a64d5ec-e77a-4afe-9d89-8790c85ede68"
+	streamCompanyB = "0f8fad5b-d9cb-469f-a165-70867728950e"
+)
+
+func outboxEvents(aggregateID string, ids ...int64) []postgres.OutboxEvent {
+	events := make([]postgres.OutboxEvent, len(ids))
+	for i, id := range ids {
+		events[i] = postgres.OutboxEvent{ID: id, EventType: "CompanyUpdated", AggregateID: aggregateID}
+	}
+	return events
+}
+
+// received drains ch and returns the IDs it held and whether it is closed.
+func received(ch <-chan handler.StreamEvent) ([]int64, bool) {
+	var ids []int64
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return ids, true
+			}
+			ids = append(ids, e.ID)
+		default:
+			return ids, false
+		}
+	}
+}
+
+func TestStream_FansOutMatchingEventsOnce(t *testing.T) {
+	s := NewStream(nil, nil, 10, 0)
+	all, cancelAll := s.Subscribe(handler.StreamFilter{})
+	defer cancelAll()
+	onlyB, cancelB := s.Subscribe(handler.StreamFilter{CompanyIDs: []string{streamCompanyB}})
+	defer cancelB()
+
+	s.broadcast(outboxEvents(streamCompanyA, 1, 2))
+	s.broadcast(outboxEvents(streamCompanyB, 2, 3))
+
+	ids, closed := received(all)
+	assert.Equal(t, []int64{1, 2, 3}, ids, "2 was loaded twice but is sent once")
+	assert.False(t, closed)
+
+	ids, _ = received(onlyB)
+	assert.Equal(t, []int64{3}, ids)
+	assert.EqualValues(t, 3, s.lastID)
+}
+
+func TestStream_DropsSubscriberThatFallsBehind(t *testing.T) {
+	s := NewStream(nil, nil, 2, 0)
+	slow, cancelSlow := s.Subscribe(handler.StreamFilter{})
+	defer cancelSlow()
+	fast, cancelFast := s.Subscribe(handler.StreamFilter{})
+	defer cancelFast()
+
+	s.broadcast(outboxEvents(streamCompanyA, 1, 2))
+	ids, _ := received(fast)
+	require.Equal(t, []int64{1, 2}, ids)
+
+	s.broadcast(outboxEvents(streamCompanyA, 3))
+
+	ids, closed := received(slow)
+	assert.Equal(t, []int64{1, 2}, ids)
+	assert.True(t, closed, "the slow subscriber resumes from the outbox instead")
+
+	ids, closed = received(fast)
+	assert.Equal(t, []int64{3}, ids)
+	assert.False(t, closed)
+}
+
+func TestStream_StopClosesSubscribers(t *testing.T) {
+	s := NewStream(nil, nil, 1, 0)
+	ch, cancel := s.Subscribe(handler.StreamFilter{})
+	cancel()
+	cancel()
+
+	_, closed := received(ch)
+	assert.True(t, closed, "cancel closes the channel once")
+
+	ch, _ = s.Subscribe(handler.StreamFilter{})
+	s.stop()
+	_, closed = received(ch)
+	assert.True(t, closed)
+
+	ch, _ = s.Subscribe(handler.StreamFilter{})
+	_, closed = received(ch)
+	assert.True(t, closed, "subscribing after the stream stopped")
+}
+
+// failingRepo fails to load notified events.
+type failingRepo struct {
+	StreamRepo
+}
+
+func (failingRepo) ListByIDs(context.Context, []int64) ([]postgres.OutboxEvent, error) {
+	return nil, errors.New("connection reset")
+}
+
+func TestStream_ClosesSubscribersWhenEventsCannotBeLoaded(t *testing.T) {
+	s := NewStream(failingRepo{}, nil, 10, 0)
+	ch, cancel := s.Subscribe(handler.StreamFilter{})
+	defer cancel()
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	ids := make(chan int64, 1)
+	go s.fetch(ctx, ids)
+	ids <- 1
+
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok, "the subscriber resumes from the outbox instead of missing the event")
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscriber was not closed")
+	}
+
+	// The stream goes on for new subscribers
+	_, cancel = s.Subscribe(handler.StreamFilter{})
+	defer cancel()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	assert.Len(t, s.subs, 1)
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// OutboxLockClassID namespaces the advisory locks of the outbox partitions;
+// the partition number is the second key.
+const OutboxLockClassID int32 = 0x0b0c5001
+
+// OutboxBatchLockClassID namespaces the transaction-level advisory locks that
+// keep two batches of one outbox partition from running at once; see
+// OutboxRepo.TryLockPartition.
+const OutboxBatchLockClassID int32 = 0x0b0c5002
+
+// LockSession holds session-level advisory locks on a dedicated connection.
+// The locks live as long as the connection: closing the session, a crash of
+// the process or a dropped connection releases all of them.
+type LockSession struct {
+	conn    *pgx.Conn
+	classID int32
+}
+
+// NewLockSession takes a connection out of the pool for good. With a
+// positive idleTimeout the server closes the session once it has been idle
+// that long, so the locks of a hung or partitioned replica are freed quickly;
+// the owner must Ping more often than that.
+func NewLockSession(ctx context.Context, db *Db, classID int32, idleTimeout time.Duration) (*LockSession, error) {
+	pooled, err := db.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock connection: %w", err)
+	}
+	conn := pooled.Hijack()
+
+	if idleTimeout > 0 {
+		_, err = conn.Exec(ctx, "SELECT set_config('idle_session_timeout', $1, false)",
+			fmt.Sprintf("%dms", idleTimeout.Milliseconds()))
+		if err != nil {
+			_ = conn.Close(ctx)
+			return nil, fmt.Errorf("failed to set idle_session_timeout: %w", err)
+		}
+	}
+
+	return &LockSession{conn: conn, classID: classID}, nil
+}
+
+// TryLock takes the lock for key without waiting. Taking a lock the session
+// already holds succeeds again and must be released as many times.
+func (s *LockSession) TryLock(ctx context.Context, key int32) (bool, error) {
+	var ok bool
+	err := s.conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1, $2)", s.classID, key).Scan(&ok)
+	return ok, err
+}
+
+func (s *LockSession) Unlock(ctx context.Context, key int32) error {
+	_, err := s.conn.Exec(ctx, "SELECT pg_advisory_unlock($1, $2)", s.classID, key)
+	return err
+}
+
+// Ping fails once the connection, and with it every lock, is gone.
+func (s *LockSession) Ping(ctx context.Context) error {
+	return s.conn.Ping(ctx)
+}
+
+func (s *LockSession) Close(ctx context.Context) error {
+	return s.conn.Close(ctx)
+}
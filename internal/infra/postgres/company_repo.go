@@ -2,17 +2,22 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/dubininme/xm-assessment/internal/domain/company"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
 const ErrUniqueViolationCode = "23505"
 
+// companiesPrimaryKey is the constraint violated by a duplicate company ID
+const companiesPrimaryKey = "companies_pkey"
+
 type CompanyRepo struct {
 	db *Db
 }
@@ -25,15 +30,18 @@ func NewCompanyRepo(db *Db) *CompanyRepo {
 
 func (r *CompanyRepo) Create(ctx context.Context, c company.Company) error {
 	exec := ExtractExecutor(ctx, r.db)
-	_, err := exec.ExecContext(ctx, `
-		INSERT INTO companies (id, name, description, employees_count, registered, type)
-		VALUES ($1, $2, $3, $4, $5, $6)`,
-		c.ID().String(), c.Name().String(), c.Description().String(), c.EmployeesCount().Int(), c.IsRegistered(), c.CompanyType().Int())
+	_, err := exec.Exec(ctx, `
+		INSERT INTO companies (id, name, description, employees_count, registered, type, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		c.ID().String(), c.Name().String(), c.Description().String(), c.EmployeesCount().Int(), c.IsRegistered(), c.CompanyType().Int(), c.UpdatedAt())
 
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == ErrUniqueViolationCode {
+				if pgErr.ConstraintName == companiesPrimaryKey {
+					return company.ErrCompanyAlreadyExists
+				}
 				return company.ErrCompanyNameAlreadyExists
 			}
 		}
@@ -45,10 +53,10 @@ func (r *CompanyRepo) Create(ctx context.Context, c company.Company) error {
 
 func (r *CompanyRepo) Update(ctx context.Context, c company.Company) error {
 	exec := ExtractExecutor(ctx, r.db)
-	res, err := exec.ExecContext(ctx, `
-		UPDATE companies SET name = $2, description = $3, employees_count = $4, registered = $5, type = $6
+	res, err := exec.Exec(ctx, `
+		UPDATE companies SET name = $2, description = $3, employees_count = $4, registered = $5, type = $6, updated_at = $7
 		WHERE id = $1`,
-		c.ID().String(), c.Name().String(), c.Description().String(), c.EmployeesCount().Int(), c.IsRegistered(), c.CompanyType().Int(),
+		c.ID().String(), c.Name().String(), c.Description().String(), c.EmployeesCount().Int(), c.IsRegistered(), c.CompanyType().Int(), c.UpdatedAt(),
 	)
 	if err != nil {
 		var pgErr *pgconn.PgError
@@ -61,12 +69,7 @@ func (r *CompanyRepo) Update(ctx context.Context, c company.Company) error {
 		return err
 	}
 
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		return err
-	}
-
-	if rowsAffected == 0 {
+	if res.RowsAffected() == 0 {
 		return company.ErrCompanyNotFound
 	}
 
@@ -75,14 +78,14 @@ func (r *CompanyRepo) Update(ctx context.Context, c company.Company) error {
 
 func (r *CompanyRepo) GetByID(ctx context.Context, companyID string) (*company.Company, error) {
 	exec := ExtractExecutor(ctx, r.db)
-	row := exec.QueryRowContext(ctx, `
-		SELECT id, name, description, employees_count, registered, type
+	row := exec.QueryRow(ctx, `
+		SELECT id, name, description, employees_count, registered, type, updated_at
 		FROM companies WHERE id = $1`, companyID)
 
 	var queryResult CompanyRowDto
-	err := row.Scan(&queryResult.ID, &queryResult.Name, &queryResult.Description, &queryResult.EmployeesCount, &queryResult.Registered, &queryResult.Type)
+	err := row.Scan(&queryResult.ID, &queryResult.Name, &queryResult.Description, &queryResult.EmployeesCount, &queryResult.Registered, &queryResult.Type, &queryResult.UpdatedAt)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, company.ErrCompanyNotFound
 		}
 
@@ -92,25 +95,109 @@ func (r *CompanyRepo) GetByID(ctx context.Context, companyID string) (*company.C
 	return queryResult.ToEntity()
 }
 
-func (r *CompanyRepo) Delete(ctx context.Context, companyID string) error {
+func (r *CompanyRepo) GetByName(ctx context.Context, name string) (*company.Company, error) {
 	exec := ExtractExecutor(ctx, r.db)
-	res, err := exec.ExecContext(ctx, `DELETE FROM companies WHERE id = $1`, companyID)
+	row := exec.QueryRow(ctx, `
+		SELECT id, name, description, employees_count, registered, type, updated_at
+		FROM companies WHERE name = $1`, name)
+
+	var queryResult CompanyRowDto
+	err := row.Scan(&queryResult.ID, &queryResult.Name, &queryResult.Description, &queryResult.EmployeesCount, &queryResult.Registered, &queryResult.Type, &queryResult.UpdatedAt)
 	if err != nil {
-		return err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, company.ErrCompanyNotFound
+		}
+
+		return nil, err
 	}
 
-	rowsAffected, err := res.RowsAffected()
+	return queryResult.ToEntity()
+}
+
+func (r *CompanyRepo) Delete(ctx context.Context, companyID string) error {
+	exec := ExtractExecutor(ctx, r.db)
+	res, err := exec.Exec(ctx, `DELETE FROM companies WHERE id = $1`, companyID)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
+	if res.RowsAffected() == 0 {
 		return company.ErrCompanyNotFound
 	}
 
 	return nil
 }
 
+// Count returns the number of companies.
+func (r *CompanyRepo) Count(ctx context.Context) (int64, error) {
+	exec := ExtractExecutor(ctx, r.db)
+
+	var count int64
+	err := exec.QueryRow(ctx, `SELECT count(*) FROM companies`).Scan(&count)
+	return count, err
+}
+
+func (r *CompanyRepo) List(ctx context.Context, filter company.ListFilter, afterID string, limit int) ([]*company.Company, error) {
+	exec := ExtractExecutor(ctx, r.db)
+	if afterID == "" {
+		afterID = uuid.Nil.String()
+	}
+
+	where, args := companyWhere(filter, []any{afterID, limit})
+	rows, err := exec.Query(ctx, `
+		SELECT id, name, description, employees_count, registered, type, updated_at
+		FROM companies WHERE id > $1 AND `+where+`
+		ORDER BY id ASC
+		LIMIT $2`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var companies []*company.Company
+	for rows.Next() {
+		var dto CompanyRowDto
+		if err := rows.Scan(&dto.ID, &dto.Name, &dto.Description, &dto.EmployeesCount, &dto.Registered, &dto.Type, &dto.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		c, err := dto.ToEntity()
+		if err != nil {
+			return nil, err
+		}
+		companies = append(companies, c)
+	}
+
+	return companies, rows.Err()
+}
+
+func companyWhere(f company.ListFilter, args []any) (string, []any) {
+	var conds []string
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if len(f.IDs) > 0 {
+		add("id = ANY($%d::uuid[])", f.IDs)
+	}
+	if f.NameContains != "" {
+		// strpos rather than LIKE, so that % and _ in the filter are literal
+		add("strpos(lower(name), lower($%d)) > 0", f.NameContains)
+	}
+	if f.Type != nil {
+		add("type = $%d", f.Type.Int())
+	}
+	if f.Registered != nil {
+		add("registered = $%d", *f.Registered)
+	}
+
+	if len(conds) == 0 {
+		return "TRUE", args
+	}
+	return strings.Join(conds, " AND "), args
+}
+
 type CompanyRowDto struct {
 	ID             string
 	Name           string
@@ -118,6 +205,7 @@ type CompanyRowDto struct {
 	EmployeesCount int
 	Registered     bool
 	Type           int16
+	UpdatedAt      time.Time
 }
 
 func (r *CompanyRowDto) ToEntity() (*company.Company, error) {
@@ -140,6 +228,8 @@ func (r *CompanyRowDto) ToEntity() (*company.Company, error) {
 	if r.Registered {
 		c.Register()
 	}
+	c.SetUpdatedAt(r.UpdatedAt.UTC())
+	c.ClearDirty()
 
 	return c, nil
 }
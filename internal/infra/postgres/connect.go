@@ -4,53 +4,185 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dubininme/xm-assessment/internal/config"
 	"github.com/dubininme/xm-assessment/internal/delivery/http/handler"
 	"github.com/dubininme/xm-assessment/pkg/logger"
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
 type Db struct {
-	*sql.DB
+	*pgxpool.Pool
 }
 
 func Connect(ctx context.Context, cfg config.DbConfig) (*Db, error) {
 	log := logger.FromContext(ctx)
 
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		cfg.DBHost,
-		cfg.DBPort,
-		cfg.DBUser,
-		cfg.DBPassword,
-		cfg.DBName,
-	)
+	poolConfig, err := ParseConnConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse db config: %w", err)
+	}
+
+	poolConfig.MaxConns = int32(max(cfg.DBMaxOpenConns, 1))                      // #nosec G115 -- small config value
+	poolConfig.MinConns = int32(max(min(cfg.DBMinConns, cfg.DBMaxOpenConns), 0)) // #nosec G115 -- small config value
+	poolConfig.MaxConnLifetime = time.Duration(cfg.DBConnMaxLifetime) * time.Second
+
+	// The password is resolved on every new connection so that a rotated
+	// DB_PASSWORD_FILE is picked up without a restart.
+	poolConfig.BeforeConnect = passwordHook(cfg)
 
-	db, err := sql.Open("pgx", dsn)
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open db: %w", err)
+		return nil, fmt.Errorf("failed to create pool: %w", err)
 	}
 
-	err = db.PingContext(ctx)
+	err = pingWithRetry(ctx, pool.Ping, cfg)
 	if err != nil {
-		_ = db.Close()
+		pool.Close()
 		return nil, fmt.Errorf("failed to ping db: %w", err)
 	}
 
-	db.SetMaxOpenConns(cfg.DBMaxOpenConns)
-	db.SetMaxIdleConns(cfg.DBMaxIdleConns)
-	db.SetConnMaxLifetime(time.Duration(cfg.DBConnMaxLifetime) * time.Second)
-
+	connConfig := poolConfig.ConnConfig
 	log.Info("connected to database successfully",
-		"host", cfg.DBHost,
-		"port", cfg.DBPort,
-		"database", cfg.DBName)
+		"host", connConfig.Host,
+		"port", connConfig.Port,
+		"database", connConfig.Database,
+		"tls", connConfig.TLSConfig != nil,
+		"max_conns", poolConfig.MaxConns)
+
+	return &Db{pool}, nil
+}
 
-	return &Db{db}, nil
+// openSQLDB opens a database/sql handle over the pgx driver for libraries that
+// need one (golang-migrate). Application code uses the native pool.
+func openSQLDB(cfg config.DbConfig) (*sql.DB, error) {
+	poolConfig, err := ParseConnConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse db config: %w", err)
+	}
+
+	return stdlib.OpenDB(*poolConfig.ConnConfig, stdlib.OptionBeforeConnect(passwordHook(cfg))), nil
+}
+
+func passwordHook(cfg config.DbConfig) func(context.Context, *pgx.ConnConfig) error {
+	password := cfg.Password()
+	return func(_ context.Context, cc *pgx.ConnConfig) error {
+		if p := password.Value(); len(p) > 0 {
+			cc.Password = p
+		}
+		return nil
+	}
+}
+
+// ParseConnConfig builds the pgx pool config either from DATABASE_URL or from
+// the individual DB_* settings. Statement timeout is applied in both cases.
+func ParseConnConfig(cfg config.DbConfig) (*pgxpool.Config, error) {
+	dsn := cfg.DatabaseURL
+	if len(dsn) == 0 {
+		dsn = buildDSN(cfg)
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.DBStatementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.DBStatementTimeout.Milliseconds(), 10)
+	}
+
+	return poolConfig, nil
+}
+
+func buildDSN(cfg config.DbConfig) string {
+	sslMode := cfg.DBSSLMode
+	if len(sslMode) == 0 {
+		sslMode = "disable"
+	}
+
+	params := []struct{ key, value string }{
+		{"host", cfg.DBHost},
+		{"port", cfg.DBPort},
+		{"user", cfg.DBUser},
+		{"dbname", cfg.DBName},
+		{"sslmode", sslMode},
+		{"sslrootcert", cfg.DBSSLRootCert},
+		{"sslcert", cfg.DBSSLCert},
+		{"sslkey", cfg.DBSSLKey},
+		{"application_name", cfg.DBApplicationName},
+	}
+
+	if cfg.DBConnectTimeout > 0 {
+		// connect_timeout is whole seconds; round up so sub-second values are not lost
+		seconds := int64((cfg.DBConnectTimeout + time.Second - 1) / time.Second)
+		params = append(params, struct{ key, value string }{"connect_timeout", strconv.FormatInt(seconds, 10)})
+	}
+
+	parts := make([]string, 0, len(params))
+	for _, p := range params {
+		if len(p.value) == 0 {
+			continue
+		}
+		parts = append(parts, p.key+"="+quoteDSNValue(p.value))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// quoteDSNValue quotes a keyword/value DSN value as described in
+// https://www.postgresql.org/docs/current/libpq-connect.html#LIBPQ-CONNSTRING
+func quoteDSNValue(v string) string {
+	if !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
+
+// pingWithRetry retries the initial ping with exponential backoff, so the app
+// survives starting before the database is reachable.
+func pingWithRetry(ctx context.Context, ping func(context.Context) error, cfg config.DbConfig) error {
+	log := logger.FromContext(ctx)
+
+	attempts := max(cfg.DBConnectRetries, 1)
+	maxBackoff := cfg.DBConnectMaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 10 * time.Second
+	}
+
+	backoff := 500 * time.Millisecond
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = ping(ctx)
+		if err == nil || attempt == attempts {
+			break
+		}
+
+		log.Warn("database not ready, retrying",
+			"attempt", attempt,
+			"backoff", backoff,
+			"error", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, maxBackoff)
+	}
+
+	return err
 }
 
 var _ handler.HealthChecker = (*DBHealthChecker)(nil)
+var _ handler.StatsReporter = (*DBHealthChecker)(nil)
 
 type DBHealthChecker struct {
 	db *Db
@@ -61,7 +193,24 @@ func NewDBHealthChecker(db *Db) *DBHealthChecker {
 }
 
 func (c *DBHealthChecker) Check(ctx context.Context) error {
-	return c.db.PingContext(ctx)
+	return c.db.Ping(ctx)
+}
+
+// Stats reports pool usage for the health endpoint.
+func (c *DBHealthChecker) Stats() map[string]any {
+	stat := c.db.Stat()
+	return map[string]any{
+		"total_conns":            stat.TotalConns(),
+		"acquired_conns":         stat.AcquiredConns(),
+		"idle_conns":             stat.IdleConns(),
+		"constructing_conns":     stat.ConstructingConns(),
+		"max_conns":              stat.MaxConns(),
+		"acquire_count":          stat.AcquireCount(),
+		"empty_acquire_count":    stat.EmptyAcquireCount(),
+		"canceled_acquire_count": stat.CanceledAcquireCount(),
+		"acquire_duration_ms":    stat.AcquireDuration().Milliseconds(),
+		"new_conns_count":        stat.NewConnsCount(),
+	}
 }
 
 func (c *DBHealthChecker) Name() string {
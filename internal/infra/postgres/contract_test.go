@@ -0,0 +1,57 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/dubininme/xm-assessment/internal/config"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres/pgtest"
+	"github.com/dubininme/xm-assessment/internal/infra/repotest"
+	"github.com/stretchr/testify/require"
+)
+
+var testDbConfig config.DbConfig
+
+func TestMain(m *testing.M) {
+	cfg, stop, err := pgtest.Start(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to start test database:", err)
+		os.Exit(1)
+	}
+	testDbConfig = cfg
+
+	code := m.Run()
+	if err := stop(); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to stop test database:", err)
+	}
+	os.Exit(code)
+}
+
+func TestContract(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := postgres.Connect(ctx, testDbConfig)
+	require.NoError(t, err)
+	t.Cleanup(db.Close)
+
+	repotest.RunContract(t, func(t *testing.T) repotest.Harness {
+		require.NoError(t, pgtest.Reset(ctx, db))
+
+		return repotest.Harness{
+			Repo:      postgres.NewCompanyRepo(db),
+			Publisher: postgres.NewOutboxRepo(db),
+			TxManager: postgres.NewTxManager(db, testDbConfig.DBTxMaxRetries),
+			EventCount: func(t *testing.T, aggregateID string) int {
+				var count int
+				err := db.QueryRow(ctx, `SELECT count(*) FROM outbox WHERE aggregate_id = $1`, aggregateID).Scan(&count)
+				require.NoError(t, err)
+				return count
+			},
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// OutboxChannel is notified with the ID of every outbox row once the
+// transaction that inserted it commits (see migration 000005).
+const OutboxChannel = "outbox_events"
+
+// ListenOutbox calls onID with every outbox ID notified on OutboxChannel
+// until ctx is done or the connection fails; notifications sent while no
+// listener is connected are lost. It runs on a dedicated connection taken
+// out of the pool. onListen is called once LISTEN is in place, before the
+// first notification, so the caller can catch up on what it missed without
+// a gap.
+func ListenOutbox(ctx context.Context, db *Db, onListen func(ctx context.Context) error, onID func(id int64)) error {
+	return listen(ctx, db, OutboxChannel, onListen, func(payload string) error {
+		id, err := strconv.ParseInt(payload, 10, 64)
+		if err != nil {
+			return fmt.Errorf("unexpected %s payload %q: %w", OutboxChannel, payload, err)
+		}
+		onID(id)
+		return nil
+	})
+}
+
+// CompanyChannel is notified with the ID of every company created, updated
+// or deleted once the transaction that wrote it commits (see migration
+// 000007).
+const CompanyChannel = "company_changes"
+
+// ListenCompanies calls onID with every company ID notified on
+// CompanyChannel, like ListenOutbox does for the outbox.
+func ListenCompanies(ctx context.Context, db *Db, onListen func(ctx context.Context) error, onID func(id string)) error {
+	return listen(ctx, db, CompanyChannel, onListen, func(payload string) error {
+		onID(payload)
+		return nil
+	})
+}
+
+// listen runs LISTEN on channel on a dedicated connection and calls onPayload
+// with every notification until ctx is done, the connection fails or
+// onPayload returns an error.
+func listen(ctx context.Context, db *Db, channel string, onListen func(ctx context.Context) error, onPayload func(payload string) error) error {
+	pooled, err := db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire listen connection: %w", err)
+	}
+	conn := pooled.Hijack()
+	defer func() { _ = conn.Close(context.Background()) }()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", channel, err)
+	}
+
+	if err := onListen(ctx); err != nil {
+		return err
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		if err := onPayload(n.Payload); err != nil {
+			return err
+		}
+	}
+}
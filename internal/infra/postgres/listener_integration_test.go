@@ -0,0 +1,119 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres/pgtest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxManager_AfterCommit(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := postgres.Connect(ctx, testDbConfig)
+	require.NoError(t, err)
+	t.Cleanup(db.Close)
+	txManager := postgres.NewTxManager(db, testDbConfig.DBTxMaxRetries)
+
+	var calls []string
+	err = txManager.Do(ctx, func(ctx context.Context) error {
+		require.True(t, txManager.InTx(ctx))
+		txManager.AfterCommit(ctx, func() { calls = append(calls, "outer") })
+		_ = txManager.Do(ctx, func(ctx context.Context) error {
+			txManager.AfterCommit(ctx, func() { calls = append(calls, "nested") })
+			return nil
+		})
+		assert.Empty(t, calls, "nothing is called before the commit")
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"outer", "nested"}, calls)
+
+	calls = nil
+	err = txManager.Do(ctx, func(ctx context.Context) error {
+		txManager.AfterCommit(ctx, func() { calls = append(calls, "rolled back") })
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+	assert.Empty(t, calls)
+}
+
+func TestListenCompanies_NotifiesCommittedWrites(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, err := postgres.Connect(ctx, testDbConfig)
+	require.NoError(t, err)
+	t.Cleanup(db.Close)
+	require.NoError(t, pgtest.Reset(ctx, db))
+
+	listening := make(chan struct{})
+	ids := make(chan string, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- postgres.ListenCompanies(ctx, db, func(context.Context) error {
+			close(listening)
+			return nil
+		}, func(id string) { ids <- id })
+	}()
+	select {
+	case <-listening:
+	case <-time.After(5 * time.Second):
+		t.Fatal("did not start listening")
+	}
+
+	next := func() string {
+		t.Helper()
+		select {
+		case id := <-ids:
+			return id
+		case <-time.After(5 * time.Second):
+			t.Fatal("no company notified")
+			return ""
+		}
+	}
+
+	repo := postgres.NewCompanyRepo(db)
+	txManager := postgres.NewTxManager(db, testDbConfig.DBTxMaxRetries)
+	c, err := company.NewCompany(uuid.New(), "Listened", "", 10, company.CorporationsType.String())
+	require.NoError(t, err)
+
+	// A rolled back write is not notified
+	err = txManager.Do(ctx, func(ctx context.Context) error {
+		require.NoError(t, repo.Create(ctx, *c))
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+
+	require.NoError(t, repo.Create(ctx, *c))
+	assert.Equal(t, c.ID().String(), next())
+
+	require.NoError(t, c.SetDescription("changed"))
+	require.NoError(t, repo.Update(ctx, *c))
+	assert.Equal(t, c.ID().String(), next())
+
+	require.NoError(t, repo.Delete(ctx, c.ID().String()))
+	assert.Equal(t, c.ID().String(), next())
+
+	select {
+	case id := <-ids:
+		t.Fatalf("unexpected notification of %s", id)
+	default:
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("listener did not stop")
+	}
+}
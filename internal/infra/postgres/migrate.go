@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dubininme/xm-assessment/internal/config"
+	"github.com/dubininme/xm-assessment/migrations"
+	"github.com/golang-migrate/migrate/v4"
+	pgxmigrate "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// Migrator applies the embedded migrations. It keeps the golang-migrate
+// schema_migrations table, so databases migrated by the migrate/migrate
+// container stay compatible. Every operation holds a Postgres advisory lock,
+// which makes concurrent runs from several replicas wait for each other
+// instead of racing.
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+// NewMigrator opens a dedicated connection for migrations; it is released by Close.
+func NewMigrator(ctx context.Context, cfg config.DbConfig) (*Migrator, error) {
+	db, err := openSQLDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pingWithRetry(ctx, db.PingContext, cfg); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to ping db: %w", err)
+	}
+
+	driver, err := pgxmigrate.WithInstance(db, &pgxmigrate.Config{})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to init migrate driver: %w", err)
+	}
+
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		_ = driver.Close()
+		return nil, fmt.Errorf("failed to open embedded migrations: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "pgx5", driver)
+	if err != nil {
+		_ = driver.Close()
+		return nil, fmt.Errorf("failed to init migrate: %w", err)
+	}
+
+	return &Migrator{m: m}, nil
+}
+
+// Up applies all pending migrations. It is a no-op when the schema is current.
+func (m *Migrator) Up() error {
+	err := m.m.Up()
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// Down rolls back the given number of migrations, or all of them when steps <= 0.
+func (m *Migrator) Down(steps int) error {
+	var err error
+	if steps <= 0 {
+		err = m.m.Down()
+	} else {
+		err = m.m.Steps(-steps)
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// Version returns the current schema version; 0 means no migration was applied.
+func (m *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// Force sets the version without running migrations, to recover from a dirty state.
+func (m *Migrator) Force(version int) error {
+	return m.m.Force(version)
+}
+
+func (m *Migrator) Close() error {
+	sourceErr, dbErr := m.m.Close()
+	return errors.Join(sourceErr, dbErr)
+}
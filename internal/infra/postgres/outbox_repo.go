@@ -3,10 +3,12 @@ package postgres
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/dubininme/xm-assessment/internal/domain/events"
-	"github.com/lib/pq"
+	"github.com/jackc/pgx/v5"
 )
 
 var _ events.EventsPublisher = (*OutboxRepo)(nil)
@@ -29,7 +31,7 @@ func (r *OutboxRepo) Publish(ctx context.Context, event events.Event) error {
 		return err
 	}
 
-	_, err = exec.ExecContext(ctx, query,
+	_, err = exec.Exec(ctx, query,
 		event.EventName(),
 		event.AggregateID(),
 		payload,
@@ -45,24 +47,172 @@ func (r *OutboxRepo) MarkProcessed(ctx context.Context, ids []int64) error {
 
 	exec := ExtractExecutor(ctx, r.db)
 	query := `UPDATE outbox SET is_processed = true, processed_at = $1 WHERE id = ANY($2)`
-	_, err := exec.ExecContext(ctx, query, time.Now().Unix(), pq.Array(ids))
+	_, err := exec.Exec(ctx, query, time.Now().Unix(), ids)
 	return err
 }
 
 func (r *OutboxRepo) GetUnprocessed(ctx context.Context, limit int) ([]OutboxEvent, error) {
+	return r.GetUnprocessedPartition(ctx, limit, 0, 1)
+}
+
+// GetUnprocessedPartition is GetUnprocessed restricted to the events whose
+// aggregate hashes to partition out of partitions, so all events of one
+// company always land in the same partition.
+func (r *OutboxRepo) GetUnprocessedPartition(ctx context.Context, limit, partition, partitions int) ([]OutboxEvent, error) {
 	exec := ExtractExecutor(ctx, r.db)
 	query := `SELECT id, event_type, aggregate_id, payload, created_at
 	          FROM outbox
 	          WHERE is_processed = false
+	            AND ($2 <= 1 OR ((hashtext(aggregate_id::text)::bigint % $2) + $2) % $2 = $3)
 	          ORDER BY id ASC
 	          LIMIT $1
 	          FOR UPDATE SKIP LOCKED`
 
-	rows, err := exec.QueryContext(ctx, query, limit)
+	rows, err := exec.Query(ctx, query, limit, partitions, partition)
 	if err != nil {
 		return nil, err
 	}
-	defer func() { _ = rows.Close() }()
+	return scanOutboxEvents(rows)
+}
+
+// TryLockPartition takes the lock of partition for the rest of the
+// transaction in ctx, without waiting. A batch that holds it is the only one
+// delivering from the partition, even if the replica lost its session lock on
+// the partition in the meantime.
+func (r *OutboxRepo) TryLockPartition(ctx context.Context, partition int) (bool, error) {
+	exec := ExtractExecutor(ctx, r.db)
+
+	var ok bool
+	err := exec.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1, $2)`, OutboxBatchLockClassID, partition).Scan(&ok)
+	return ok, err
+}
+
+// GetUndelivered is GetUnprocessedPartition for one of several sinks: it
+// skips the events already delivered to sink, so a failing sink neither
+// blocks nor duplicates deliveries to the others.
+func (r *OutboxRepo) GetUndelivered(ctx context.Context, sink string, limit, partition, partitions int) ([]OutboxEvent, error) {
+	exec := ExtractExecutor(ctx, r.db)
+	query := `SELECT id, event_type, aggregate_id, payload, created_at
+	          FROM outbox o
+	          WHERE is_processed = false
+	            AND ($2 <= 1 OR ((hashtext(aggregate_id::text)::bigint % $2) + $2) % $2 = $3)
+	            AND NOT EXISTS (SELECT 1 FROM outbox_deliveries d
+	                            WHERE d.outbox_id = o.id AND d.sink = $4 AND d.delivered_at IS NOT NULL)
+	          ORDER BY id ASC
+	          LIMIT $1
+	          FOR UPDATE SKIP LOCKED`
+
+	rows, err := exec.Query(ctx, query, limit, partitions, partition, sink)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutboxEvents(rows)
+}
+
+// MarkDelivered records the delivery of ids to sink and marks the events
+// every one of sinks is done with as processed.
+func (r *OutboxRepo) MarkDelivered(ctx context.Context, sink string, ids []int64, sinks []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	exec := ExtractExecutor(ctx, r.db)
+	now := time.Now().Unix()
+
+	_, err := exec.Exec(ctx, `INSERT INTO outbox_deliveries (outbox_id, sink, delivered_at)
+	                          SELECT unnest($1::bigint[]), $2, $3
+	                          ON CONFLICT (outbox_id, sink) DO UPDATE SET delivered_at = EXCLUDED.delivered_at
+	                          WHERE outbox_deliveries.delivered_at IS NULL`, ids, sink, now)
+	if err != nil {
+		return err
+	}
+
+	return markProcessedBySinks(ctx, exec, now, ids, sinks)
+}
+
+// MarkFailed records that sink gave up on the event id for reason, so it is
+// not delivered to sink again, and marks the event processed once every one
+// of sinks is done with it, like MarkDelivered.
+func (r *OutboxRepo) MarkFailed(ctx context.Context, sink string, id int64, reason string, sinks []string) error {
+	exec := ExtractExecutor(ctx, r.db)
+	now := time.Now().Unix()
+
+	_, err := exec.Exec(ctx, `INSERT INTO outbox_deliveries (outbox_id, sink, delivered_at, error)
+	                          VALUES ($1, $2, $3, $4)
+	                          ON CONFLICT (outbox_id, sink) DO UPDATE SET delivered_at = EXCLUDED.delivered_at, error = EXCLUDED.error
+	                          WHERE outbox_deliveries.delivered_at IS NULL`, id, sink, now, reason)
+	if err != nil {
+		return err
+	}
+
+	return markProcessedBySinks(ctx, exec, now, []int64{id}, sinks)
+}
+
+// markProcessedBySinks marks the events of ids that every one of sinks is
+// done with as processed.
+func markProcessedBySinks(ctx context.Context, exec Executor, now int64, ids []int64, sinks []string) error {
+	_, err := exec.Exec(ctx, `UPDATE outbox o SET is_processed = true, processed_at = $1
+	                         WHERE id = ANY($2)
+	                           AND (SELECT count(*) FROM outbox_deliveries d
+	                                WHERE d.outbox_id = o.id AND d.sink = ANY($3) AND d.delivered_at IS NOT NULL) = cardinality($3::text[])`,
+		now, ids, sinks)
+	return err
+}
+
+// OutboxRetry is how many attempts a sink made at an event it is still
+// retrying, and when it may make the next one.
+type OutboxRetry struct {
+	Attempts      int
+	NextAttemptAt time.Time
+}
+
+// GetRetries returns the retries sink has scheduled for any of ids, by ID.
+func (r *OutboxRepo) GetRetries(ctx context.Context, sink string, ids []int64) (map[int64]OutboxRetry, error) {
+	exec := ExtractExecutor(ctx, r.db)
+	rows, err := exec.Query(ctx, `SELECT outbox_id, attempts, next_attempt_at
+	                             FROM outbox_deliveries
+	                             WHERE sink = $1 AND outbox_id = ANY($2) AND delivered_at IS NULL`, sink, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	retries := make(map[int64]OutboxRetry)
+	for rows.Next() {
+		var id, next int64
+		var retry OutboxRetry
+		if err := rows.Scan(&id, &retry.Attempts, &next); err != nil {
+			return nil, err
+		}
+		retry.NextAttemptAt = time.Unix(next, 0)
+		retries[id] = retry
+	}
+	return retries, rows.Err()
+}
+
+// ScheduleRetry records the retry of the event id by sink. It is stored with
+// a precision of seconds, rounded up so the event is never retried early.
+func (r *OutboxRepo) ScheduleRetry(ctx context.Context, sink string, id int64, retry OutboxRetry) error {
+	exec := ExtractExecutor(ctx, r.db)
+	next := retry.NextAttemptAt.Add(time.Second - 1).Unix()
+	_, err := exec.Exec(ctx, `INSERT INTO outbox_deliveries (outbox_id, sink, attempts, next_attempt_at)
+	                          VALUES ($1, $2, $3, $4)
+	                          ON CONFLICT (outbox_id, sink) DO UPDATE
+	                          SET attempts = EXCLUDED.attempts, next_attempt_at = EXCLUDED.next_attempt_at
+	                          WHERE outbox_deliveries.delivered_at IS NULL`, id, sink, retry.Attempts, next)
+	return err
+}
+
+// ClearRetry forgets the retry of the event id by sink once it is delivered.
+func (r *OutboxRepo) ClearRetry(ctx context.Context, sink string, id int64) error {
+	exec := ExtractExecutor(ctx, r.db)
+	_, err := exec.Exec(ctx, `DELETE FROM outbox_deliveries
+	                          WHERE outbox_id = $1 AND sink = $2 AND delivered_at IS NULL`, id, sink)
+	return err
+}
+
+func scanOutboxEvents(rows pgx.Rows) ([]OutboxEvent, error) {
+	defer rows.Close()
 
 	var events []OutboxEvent
 	for rows.Next() {
@@ -76,6 +226,110 @@ func (r *OutboxRepo) GetUnprocessed(ctx context.Context, limit int) ([]OutboxEve
 	return events, rows.Err()
 }
 
+// OutboxFilter selects outbox events regardless of their processed state.
+// Zero values leave a bound open; time bounds are unix seconds and all bounds
+// are inclusive.
+type OutboxFilter struct {
+	AggregateID string
+	EventType   string
+	// AggregateIDs and EventTypes match any of their values
+	AggregateIDs []string
+	EventTypes   []string
+	CreatedFrom  int64
+	CreatedTo    int64
+	FromID       int64
+	ToID         int64
+}
+
+func (f OutboxFilter) where(args []any) (string, []any) {
+	var conds []string
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if f.AggregateID != "" {
+		add("aggregate_id = $%d", f.AggregateID)
+	}
+	if f.EventType != "" {
+		add("event_type = $%d", f.EventType)
+	}
+	if len(f.AggregateIDs) > 0 {
+		add("aggregate_id = ANY($%d::uuid[])", f.AggregateIDs)
+	}
+	if len(f.EventTypes) > 0 {
+		add("event_type = ANY($%d)", f.EventTypes)
+	}
+	if f.CreatedFrom != 0 {
+		add("created_at >= $%d", f.CreatedFrom)
+	}
+	if f.CreatedTo != 0 {
+		add("created_at <= $%d", f.CreatedTo)
+	}
+	if f.FromID != 0 {
+		add("id >= $%d", f.FromID)
+	}
+	if f.ToID != 0 {
+		add("id <= $%d", f.ToID)
+	}
+
+	if len(conds) == 0 {
+		return "TRUE", args
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// Count returns how many events match f.
+func (r *OutboxRepo) Count(ctx context.Context, f OutboxFilter) (int64, error) {
+	exec := ExtractExecutor(ctx, r.db)
+	where, args := f.where(nil)
+
+	var count int64
+	err := exec.QueryRow(ctx, `SELECT count(*) FROM outbox WHERE `+where, args...).Scan(&count)
+	return count, err
+}
+
+// List returns up to limit events matching f with an ID above afterID, in ID
+// order, so callers can page through with the last ID they saw.
+func (r *OutboxRepo) List(ctx context.Context, f OutboxFilter, afterID int64, limit int) ([]OutboxEvent, error) {
+	exec := ExtractExecutor(ctx, r.db)
+	where, args := f.where([]any{afterID, limit})
+	query := `SELECT id, event_type, aggregate_id, payload, created_at
+	          FROM outbox
+	          WHERE id > $1 AND ` + where + `
+	          ORDER BY id ASC
+	          LIMIT $2`
+
+	rows, err := exec.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutboxEvents(rows)
+}
+
+// ListByIDs returns the events with the given IDs, in ID order.
+func (r *OutboxRepo) ListByIDs(ctx context.Context, ids []int64) ([]OutboxEvent, error) {
+	exec := ExtractExecutor(ctx, r.db)
+	rows, err := exec.Query(ctx, `
+		SELECT id, event_type, aggregate_id, payload, created_at
+		FROM outbox
+		WHERE id = ANY($1)
+		ORDER BY id ASC`, ids)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutboxEvents(rows)
+}
+
+// LatestID returns the highest outbox ID, or 0 when the outbox is empty.
+func (r *OutboxRepo) LatestID(ctx context.Context) (int64, error) {
+	exec := ExtractExecutor(ctx, r.db)
+
+	var id int64
+	err := exec.QueryRow(ctx, `SELECT COALESCE(MAX(id), 0) FROM outbox`).Scan(&id)
+	return id, err
+}
+
 type OutboxEvent struct {
 	ID          int64
 	EventType   string
@@ -2,56 +2,214 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"time"
 
 	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type ctxKey string
 
 const txKey ctxKey = "tx"
 
+const (
+	ErrSerializationFailureCode = "40001"
+	ErrDeadlockDetectedCode     = "40P01"
+
+	baseRetryBackoff = 10 * time.Millisecond
+	maxRetryBackoff  = 500 * time.Millisecond
+)
+
+var ErrIncompatibleTxOptions = errors.New("nested transaction options are incompatible with the outer transaction")
+
+// Executor is the query surface shared by *pgxpool.Pool and pgx.Tx.
 type Executor interface {
-	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
-	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
-	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// txState is stored in the context while a transaction is open.
+type txState struct {
+	tx   pgx.Tx
+	opts company.TxOptions
+
+	// root is the outermost transaction, nil for the outermost itself
+	root *txState
+	// afterCommit is called once the outermost transaction commits
+	afterCommit []func()
+}
+
+func (s *txState) outermost() *txState {
+	if s.root != nil {
+		return s.root
+	}
+	return s
 }
 
 var _ company.TxManager = (*TxManager)(nil)
 
 type TxManager struct {
-	db *Db
+	db         *Db
+	maxRetries int
 }
 
-func NewTxManager(db *Db) *TxManager {
-	return &TxManager{db: db}
+// NewTxManager creates a TxManager that retries a transaction up to
+// maxRetries times when it fails with a serialization failure or deadlock.
+func NewTxManager(db *Db, maxRetries int) *TxManager {
+	return &TxManager{db: db, maxRetries: max(maxRetries, 0)}
+}
+
+// Do runs fn in a transaction. A nested Do (ctx already carries a transaction)
+// runs fn in a savepoint of the outer transaction: an error rolls back only the
+// nested work, and retries are left to the outermost Do.
+func (m *TxManager) Do(ctx context.Context, fn func(ctx context.Context) error, opts ...company.TxOptions) error {
+	var o company.TxOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	if outer, ok := ctx.Value(txKey).(*txState); ok {
+		return m.doNested(ctx, outer, o, fn)
+	}
+
+	log := logger.FromContext(ctx)
+	backoff := baseRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := m.do(ctx, o, fn)
+		if err == nil || attempt >= m.maxRetries || !IsRetryableTxError(err) {
+			return err
+		}
+
+		// Full jitter spreads out retries of transactions that conflicted with each other
+		wait := time.Duration(rand.Int64N(int64(backoff))) // #nosec G404 -- jitter does not need crypto randomness
+		log.Warn("retrying transaction",
+			"attempt", attempt+1,
+			"isolation", o.Isolation.String(),
+			"backoff", wait,
+			"error", err)
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(wait):
+		}
+
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
 }
 
-func (m *TxManager) Do(ctx context.Context, fn func(ctx context.Context) error) error {
-	tx, err := m.db.BeginTx(ctx, nil)
+func (m *TxManager) do(ctx context.Context, o company.TxOptions, fn func(ctx context.Context) error) error {
+	tx, err := m.db.BeginTx(ctx, toPgxTxOptions(o))
 	if err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
 
-	ctxWithTx := context.WithValue(ctx, txKey, tx)
+	return runInTx(ctx, &txState{tx: tx, opts: o}, fn)
+}
+
+func (m *TxManager) doNested(ctx context.Context, outer *txState, o company.TxOptions, fn func(ctx context.Context) error) error {
+	if o.Isolation > effectiveIsolation(outer.opts.Isolation) || (outer.opts.ReadOnly && !o.ReadOnly) {
+		return fmt.Errorf("%w: outer %s read_only=%t, nested %s read_only=%t", ErrIncompatibleTxOptions,
+			outer.opts.Isolation, outer.opts.ReadOnly, o.Isolation, o.ReadOnly)
+	}
+
+	savepoint, err := outer.tx.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin savepoint: %w", err)
+	}
+
+	return runInTx(ctx, &txState{tx: savepoint, opts: outer.opts, root: outer.outermost()}, fn)
+}
+
+func runInTx(ctx context.Context, state *txState, fn func(ctx context.Context) error) error {
+	ctxWithTx := context.WithValue(ctx, txKey, state)
 	if err := fn(ctxWithTx); err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
+		if rbErr := state.tx.Rollback(ctx); rbErr != nil {
 			return fmt.Errorf("rollback failed: %v (original: %w)", rbErr, err)
 		}
 		return err
 	}
 
-	if err := tx.Commit(); err != nil {
+	if err := state.tx.Commit(ctx); err != nil {
 		return fmt.Errorf("commit tx: %w", err)
 	}
 
+	if state.root == nil {
+		for _, f := range state.afterCommit {
+			f()
+		}
+	}
 	return nil
 }
 
+// InTx reports whether ctx carries a transaction.
+func (m *TxManager) InTx(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey).(*txState)
+	return ok
+}
+
+// AfterCommit calls fn once the transaction of ctx commits, or right away
+// when ctx carries none. fn is not called when the transaction rolls back,
+// and an attempt that is retried drops the calls it registered. A call
+// registered in a savepoint that rolls back is still made when the
+// outermost transaction commits.
+func (m *TxManager) AfterCommit(ctx context.Context, fn func()) {
+	state, ok := ctx.Value(txKey).(*txState)
+	if !ok {
+		fn()
+		return
+	}
+	root := state.outermost()
+	root.afterCommit = append(root.afterCommit, fn)
+}
+
 func ExtractExecutor(ctx context.Context, db *Db) Executor {
-	if tx, ok := ctx.Value(txKey).(*sql.Tx); ok {
-		return tx
+	if state, ok := ctx.Value(txKey).(*txState); ok {
+		return state.tx
 	}
-	return db.DB
+	return db.Pool
+}
+
+// IsRetryableTxError reports whether err is a serialization failure or deadlock,
+// after which the whole transaction can be safely re-run.
+func IsRetryableTxError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == ErrSerializationFailureCode || pgErr.Code == ErrDeadlockDetectedCode
+}
+
+func effectiveIsolation(l company.IsolationLevel) company.IsolationLevel {
+	if l == company.IsolationDefault {
+		return company.IsolationReadCommitted
+	}
+	return l
+}
+
+func toPgxTxOptions(o company.TxOptions) pgx.TxOptions {
+	var opts pgx.TxOptions
+
+	switch o.Isolation {
+	case company.IsolationReadCommitted:
+		opts.IsoLevel = pgx.ReadCommitted
+	case company.IsolationRepeatableRead:
+		opts.IsoLevel = pgx.RepeatableRead
+	case company.IsolationSerializable:
+		opts.IsoLevel = pgx.Serializable
+	}
+
+	if o.ReadOnly {
+		opts.AccessMode = pgx.ReadOnly
+	}
+
+	return opts
 }
@@ -0,0 +1,73 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres/pgtest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxManager_RetriesConflictingUpdates(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := postgres.Connect(ctx, testDbConfig)
+	require.NoError(t, err)
+	t.Cleanup(db.Close)
+	require.NoError(t, pgtest.Reset(ctx, db))
+
+	repo := postgres.NewCompanyRepo(db)
+	txManager := postgres.NewTxManager(db, 3)
+
+	c, err := company.NewCompany(uuid.New(), "Contended", "", 10, company.CorporationsType.String())
+	require.NoError(t, err)
+	require.NoError(t, repo.Create(ctx, *c))
+
+	// Both transactions read the company before either writes it, so the
+	// second to write fails with a serialization failure and is retried
+	var attempts atomic.Int32
+	var read sync.WaitGroup
+	read.Add(2)
+
+	update := func(change func(c *company.Company) error) error {
+		return txManager.Do(ctx, func(ctx context.Context) error {
+			current, err := repo.GetByID(ctx, c.ID().String())
+			if err != nil {
+				return err
+			}
+			if attempts.Add(1) <= 2 {
+				read.Done()
+				read.Wait()
+			}
+			if err := change(current); err != nil {
+				return err
+			}
+			return repo.Update(ctx, *current)
+		}, company.TxOptions{Isolation: company.IsolationRepeatableRead})
+	}
+
+	errs := make(chan error, 2)
+	go func() {
+		errs <- update(func(c *company.Company) error { return c.SetDescription("changed") })
+	}()
+	go func() {
+		errs <- update(func(c *company.Company) error { return c.SetEmployeesCount(20) })
+	}()
+	require.NoError(t, <-errs)
+	require.NoError(t, <-errs)
+
+	assert.Equal(t, int32(3), attempts.Load(), "one of the updates is retried once")
+
+	got, err := repo.GetByID(ctx, c.ID().String())
+	require.NoError(t, err)
+	assert.Equal(t, "changed", got.Description().String())
+	assert.Equal(t, 20, got.EmployeesCount().Int(), "the retry sees the other update")
+}
@@ -0,0 +1,47 @@
+//go:build unit
+
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetryableTxError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"serialization_failure", &pgconn.PgError{Code: ErrSerializationFailureCode}, true},
+		{"deadlock", &pgconn.PgError{Code: ErrDeadlockDetectedCode}, true},
+		{"wrapped", fmt.Errorf("commit tx: %w", &pgconn.PgError{Code: ErrSerializationFailureCode}), true},
+		{"unique_violation", &pgconn.PgError{Code: ErrUniqueViolationCode}, false},
+		{"plain_error", errors.New("boom"), false},
+		{"nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsRetryableTxError(tt.err))
+		})
+	}
+}
+
+func TestToPgxTxOptions(t *testing.T) {
+	assert.Equal(t, pgx.TxOptions{}, toPgxTxOptions(company.TxOptions{}))
+	assert.Equal(t,
+		pgx.TxOptions{IsoLevel: pgx.Serializable, AccessMode: pgx.ReadOnly},
+		toPgxTxOptions(company.TxOptions{Isolation: company.IsolationSerializable, ReadOnly: true}),
+	)
+	assert.Equal(t,
+		pgx.TxOptions{IsoLevel: pgx.RepeatableRead},
+		toPgxTxOptions(company.TxOptions{Isolation: company.IsolationRepeatableRead}),
+	)
+}
@@ -0,0 +1,272 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/domain/webhook"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type WebhookRepo struct {
+	db *Db
+}
+
+var _ webhook.Repository = (*WebhookRepo)(nil)
+
+func NewWebhookRepo(db *Db) *WebhookRepo {
+	return &WebhookRepo{db: db}
+}
+
+func (r *WebhookRepo) Create(ctx context.Context, s webhook.Subscription) error {
+	exec := ExtractExecutor(ctx, r.db)
+	_, err := exec.Exec(ctx, `
+		INSERT INTO webhook_subscriptions (id, url, event_types, secret, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		s.ID, s.URL, eventTypes(s), s.Secret, s.Active, s.CreatedAt.Unix(), s.UpdatedAt.Unix())
+	return err
+}
+
+func (r *WebhookRepo) Update(ctx context.Context, s webhook.Subscription) error {
+	exec := ExtractExecutor(ctx, r.db)
+	res, err := exec.Exec(ctx, `
+		UPDATE webhook_subscriptions SET url = $2, event_types = $3, secret = $4, active = $5, updated_at = $6
+		WHERE id = $1`,
+		s.ID, s.URL, eventTypes(s), s.Secret, s.Active, s.UpdatedAt.Unix())
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return webhook.ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	exec := ExtractExecutor(ctx, r.db)
+	res, err := exec.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return webhook.ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+const subscriptionColumns = `id, url, event_types, secret, active, created_at, updated_at`
+
+func (r *WebhookRepo) GetByID(ctx context.Context, id uuid.UUID) (*webhook.Subscription, error) {
+	exec := ExtractExecutor(ctx, r.db)
+	row := exec.QueryRow(ctx, `SELECT `+subscriptionColumns+` FROM webhook_subscriptions WHERE id = $1`, id)
+
+	s, err := scanSubscription(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, webhook.ErrSubscriptionNotFound
+	}
+	return s, err
+}
+
+// List returns every subscription, oldest first.
+func (r *WebhookRepo) List(ctx context.Context) ([]webhook.Subscription, error) {
+	exec := ExtractExecutor(ctx, r.db)
+	rows, err := exec.Query(ctx, `SELECT `+subscriptionColumns+` FROM webhook_subscriptions ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []webhook.Subscription
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, *s)
+	}
+	return subs, rows.Err()
+}
+
+func scanSubscription(row pgx.Row) (*webhook.Subscription, error) {
+	var s webhook.Subscription
+	var createdAt, updatedAt int64
+	if err := row.Scan(&s.ID, &s.URL, &s.EventTypes, &s.Secret, &s.Active, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	s.CreatedAt = time.Unix(createdAt, 0)
+	s.UpdatedAt = time.Unix(updatedAt, 0)
+	return &s, nil
+}
+
+// eventTypes keeps an empty filter from being written as NULL.
+func eventTypes(s webhook.Subscription) []string {
+	if s.EventTypes == nil {
+		return []string{}
+	}
+	return s.EventTypes
+}
+
+const deliveryColumns = `d.id, d.subscription_id, d.outbox_id, o.event_type, d.status, d.attempts,
+	d.response_status, d.error, d.next_attempt_at, d.created_at, d.delivered_at`
+
+func (r *WebhookRepo) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]webhook.Delivery, error) {
+	exec := ExtractExecutor(ctx, r.db)
+	rows, err := exec.Query(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM webhook_deliveries d JOIN outbox o ON o.id = d.outbox_id
+		WHERE d.subscription_id = $1
+		ORDER BY d.id DESC
+		LIMIT $2`, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []webhook.Delivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *WebhookRepo) GetDelivery(ctx context.Context, subscriptionID uuid.UUID, deliveryID int64) (*webhook.Delivery, error) {
+	exec := ExtractExecutor(ctx, r.db)
+	row := exec.QueryRow(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM webhook_deliveries d JOIN outbox o ON o.id = d.outbox_id
+		WHERE d.subscription_id = $1 AND d.id = $2`, subscriptionID, deliveryID)
+
+	d, err := scanDelivery(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, webhook.ErrDeliveryNotFound
+	}
+	return d, err
+}
+
+func (r *WebhookRepo) CreateDelivery(ctx context.Context, subscriptionID uuid.UUID, outboxID int64) (*webhook.Delivery, error) {
+	exec := ExtractExecutor(ctx, r.db)
+	now := time.Now().Unix()
+
+	var id int64
+	err := exec.QueryRow(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, outbox_id, status, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $4)
+		RETURNING id`, subscriptionID, outboxID, string(webhook.DeliveryPending), now).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetDelivery(ctx, subscriptionID, id)
+}
+
+// CreateDeliveries queues one delivery per pair of subscriptionIDs[i] and
+// outboxIDs[i].
+func (r *WebhookRepo) CreateDeliveries(ctx context.Context, subscriptionIDs []uuid.UUID, outboxIDs []int64) error {
+	if len(subscriptionIDs) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(subscriptionIDs))
+	for i, id := range subscriptionIDs {
+		ids[i] = id.String()
+	}
+
+	exec := ExtractExecutor(ctx, r.db)
+	_, err := exec.Exec(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, outbox_id, status, next_attempt_at, created_at)
+		SELECT s, o, $3, $4, $4 FROM unnest($1::uuid[], $2::bigint[]) AS t(s, o)`,
+		ids, outboxIDs, string(webhook.DeliveryPending), time.Now().Unix())
+	return err
+}
+
+// DueDelivery is a pending delivery together with what it takes to send it.
+type DueDelivery struct {
+	webhook.Delivery
+	URL    string
+	Secret string
+	Event  OutboxEvent
+}
+
+// ClaimDueDeliveries locks up to limit pending deliveries of active
+// subscriptions whose next attempt is due, oldest first. Other dispatchers
+// skip the locked rows until the transaction ends.
+func (r *WebhookRepo) ClaimDueDeliveries(ctx context.Context, now time.Time, limit int) ([]DueDelivery, error) {
+	exec := ExtractExecutor(ctx, r.db)
+	rows, err := exec.Query(ctx, `
+		SELECT `+deliveryColumns+`, s.url, s.secret, o.aggregate_id, o.payload, o.created_at
+		FROM webhook_deliveries d
+		JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		JOIN outbox o ON o.id = d.outbox_id
+		WHERE d.status = $1 AND d.next_attempt_at <= $2 AND s.active
+		ORDER BY d.id ASC
+		LIMIT $3
+		FOR UPDATE OF d SKIP LOCKED`, string(webhook.DeliveryPending), now.Unix(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []DueDelivery
+	for rows.Next() {
+		var dd DueDelivery
+		var nextAttemptAt, createdAt int64
+		var deliveredAt *int64
+		err := rows.Scan(&dd.ID, &dd.SubscriptionID, &dd.OutboxID, &dd.EventType, &dd.Status, &dd.Attempts,
+			&dd.ResponseStatus, &dd.Error, &nextAttemptAt, &createdAt, &deliveredAt,
+			&dd.URL, &dd.Secret, &dd.Event.AggregateID, &dd.Event.Payload, &dd.Event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		setDeliveryTimes(&dd.Delivery, nextAttemptAt, createdAt, deliveredAt)
+		dd.Event.ID = dd.OutboxID
+		dd.Event.EventType = dd.EventType
+		due = append(due, dd)
+	}
+	return due, rows.Err()
+}
+
+// RecordAttempt stores the outcome of an attempt to send d.
+func (r *WebhookRepo) RecordAttempt(ctx context.Context, d webhook.Delivery) error {
+	exec := ExtractExecutor(ctx, r.db)
+
+	var deliveredAt *int64
+	if !d.DeliveredAt.IsZero() {
+		unix := d.DeliveredAt.Unix()
+		deliveredAt = &unix
+	}
+
+	_, err := exec.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, response_status = $4, error = $5, next_attempt_at = $6, delivered_at = $7
+		WHERE id = $1`,
+		d.ID, string(d.Status), d.Attempts, d.ResponseStatus, d.Error, d.NextAttemptAt.Unix(), deliveredAt)
+	return err
+}
+
+func scanDelivery(row pgx.Row) (*webhook.Delivery, error) {
+	var d webhook.Delivery
+	var nextAttemptAt, createdAt int64
+	var deliveredAt *int64
+	err := row.Scan(&d.ID, &d.SubscriptionID, &d.OutboxID, &d.EventType, &d.Status, &d.Attempts,
+		&d.ResponseStatus, &d.Error, &nextAttemptAt, &createdAt, &deliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	setDeliveryTimes(&d, nextAttemptAt, createdAt, deliveredAt)
+	return &d, nil
+}
+
+func setDeliveryTimes(d *webhook.Delivery, nextAttemptAt, createdAt int64, deliveredAt *int64) {
+	d.NextAttemptAt = time.Unix(nextAttemptAt, 0)
+	d.CreatedAt = time.Unix(createdAt, 0)
+	if deliveredAt != nil {
+		d.DeliveredAt = time.Unix(*deliveredAt, 0)
+	}
+}
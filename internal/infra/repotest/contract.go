@@ -0,0 +1,302 @@
+// Package repotest holds the contract test suite shared by every storage
+// implementation (memory, postgres), so they stay behaviorally identical.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/domain/events"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Harness wires one storage implementation for the contract suite.
+type Harness struct {
+	Repo      company.CompanyRepository
+	Publisher events.EventsPublisher
+	TxManager company.TxManager
+
+	// EventCount returns how many events were committed for aggregateID
+	EventCount func(t *testing.T, aggregateID string) int
+}
+
+var errRollback = errors.New("rollback")
+
+// RunContract runs the suite; newHarness is called once per subtest.
+func RunContract(t *testing.T, newHarness func(t *testing.T) Harness) {
+	t.Run("create_and_get", func(t *testing.T) {
+		h := newHarness(t)
+		ctx := context.Background()
+		c := newCompany(t)
+		c.Register()
+
+		require.NoError(t, h.Repo.Create(ctx, *c))
+
+		got, err := h.Repo.GetByID(ctx, c.ID().String())
+		require.NoError(t, err)
+		assert.Equal(t, c.ID(), got.ID())
+		assert.Equal(t, c.Name(), got.Name())
+		assert.Equal(t, c.Description(), got.Description())
+		assert.Equal(t, c.EmployeesCount(), got.EmployeesCount())
+		assert.Equal(t, c.CompanyType(), got.CompanyType())
+		assert.True(t, got.IsRegistered())
+		assert.True(t, c.UpdatedAt().Equal(got.UpdatedAt()), "updated at %s, read back %s", c.UpdatedAt(), got.UpdatedAt())
+	})
+
+	t.Run("get_not_found", func(t *testing.T) {
+		h := newHarness(t)
+
+		_, err := h.Repo.GetByID(context.Background(), uuid.NewString())
+		assert.ErrorIs(t, err, company.ErrCompanyNotFound)
+	})
+
+	t.Run("get_by_name", func(t *testing.T) {
+		h := newHarness(t)
+		ctx := context.Background()
+		c := newCompany(t)
+		require.NoError(t, h.Repo.Create(ctx, *c))
+
+		got, err := h.Repo.GetByName(ctx, c.Name().String())
+		require.NoError(t, err)
+		assert.Equal(t, c.ID(), got.ID())
+
+		_, err = h.Repo.GetByName(ctx, "ct-missing")
+		assert.ErrorIs(t, err, company.ErrCompanyNotFound)
+	})
+
+	t.Run("list_pages_in_id_order", func(t *testing.T) {
+		h := newHarness(t)
+		ctx := context.Background()
+		var ids []string
+		for range 5 {
+			c := newCompany(t)
+			require.NoError(t, h.Repo.Create(ctx, *c))
+			ids = append(ids, c.ID().String())
+		}
+		slices.Sort(ids)
+
+		var listed []string
+		afterID := ""
+		for {
+			page, err := h.Repo.List(ctx, company.ListFilter{}, afterID, 2)
+			require.NoError(t, err)
+			if len(page) == 0 {
+				break
+			}
+			require.LessOrEqual(t, len(page), 2)
+			for _, c := range page {
+				listed = append(listed, c.ID().String())
+			}
+			afterID = listed[len(listed)-1]
+		}
+		assert.Equal(t, ids, listed)
+	})
+
+	t.Run("list_filters", func(t *testing.T) {
+		h := newHarness(t)
+		ctx := context.Background()
+
+		acme, err := company.NewCompany(uuid.New(), "ct-Acme 100%", "", 10, company.CorporationsType.String())
+		require.NoError(t, err)
+		acme.Register()
+		coop, err := company.NewCompany(uuid.New(), "ct-acme_coop", "", 10, company.CooperativeType.String())
+		require.NoError(t, err)
+		other, err := company.NewCompany(uuid.New(), "ct-other", "", 10, company.CorporationsType.String())
+		require.NoError(t, err)
+		for _, c := range []*company.Company{acme, coop, other} {
+			require.NoError(t, h.Repo.Create(ctx, *c))
+		}
+
+		corporations := company.CorporationsType
+		registered := true
+		tests := []struct {
+			name   string
+			filter company.ListFilter
+			want   []*company.Company
+		}{
+			{"none", company.ListFilter{}, []*company.Company{acme, coop, other}},
+			{"ids", company.ListFilter{IDs: []string{acme.ID().String(), other.ID().String(), uuid.NewString()}}, []*company.Company{acme, other}},
+			{"name ignores case", company.ListFilter{NameContains: "ACME"}, []*company.Company{acme, coop}},
+			{"name is literal", company.ListFilter{NameContains: "0%"}, []*company.Company{acme}},
+			{"name underscore", company.ListFilter{NameContains: "e_c"}, []*company.Company{coop}},
+			{"type", company.ListFilter{Type: &corporations}, []*company.Company{acme, other}},
+			{"registered", company.ListFilter{Registered: &registered}, []*company.Company{acme}},
+			{"all", company.ListFilter{NameContains: "acme", Type: &corporations, Registered: &registered}, []*company.Company{acme}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				page, err := h.Repo.List(ctx, tt.filter, "", 10)
+				require.NoError(t, err)
+
+				var got, want []string
+				for _, c := range page {
+					got = append(got, c.ID().String())
+				}
+				for _, c := range tt.want {
+					want = append(want, c.ID().String())
+				}
+				slices.Sort(want)
+				assert.Equal(t, want, got)
+			})
+		}
+	})
+
+	t.Run("create_duplicate_name", func(t *testing.T) {
+		h := newHarness(t)
+		ctx := context.Background()
+		first := newCompany(t)
+		require.NoError(t, h.Repo.Create(ctx, *first))
+
+		second, err := company.NewCompany(uuid.New(), first.Name().String(), "", 1, company.NonProfitType.String())
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, h.Repo.Create(ctx, *second), company.ErrCompanyNameAlreadyExists)
+	})
+
+	t.Run("create_duplicate_id", func(t *testing.T) {
+		h := newHarness(t)
+		ctx := context.Background()
+		first := newCompany(t)
+		require.NoError(t, h.Repo.Create(ctx, *first))
+
+		second, err := company.NewCompany(first.ID(), "ct-other", "", 1, company.CorporationsType.String())
+		require.NoError(t, err)
+		assert.ErrorIs(t, h.Repo.Create(ctx, *second), company.ErrCompanyAlreadyExists)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		h := newHarness(t)
+		ctx := context.Background()
+		c := newCompany(t)
+		require.NoError(t, h.Repo.Create(ctx, *c))
+
+		require.NoError(t, c.SetEmployeesCount(42))
+		require.NoError(t, c.SetDescription("updated"))
+		c.SetUpdatedAt(c.UpdatedAt().Add(time.Minute))
+		require.NoError(t, h.Repo.Update(ctx, *c))
+
+		got, err := h.Repo.GetByID(ctx, c.ID().String())
+		require.NoError(t, err)
+		assert.Equal(t, 42, got.EmployeesCount().Int())
+		assert.Equal(t, "updated", got.Description().String())
+		assert.True(t, c.UpdatedAt().Equal(got.UpdatedAt()), "updated at %s, read back %s", c.UpdatedAt(), got.UpdatedAt())
+	})
+
+	t.Run("update_not_found", func(t *testing.T) {
+		h := newHarness(t)
+
+		assert.ErrorIs(t, h.Repo.Update(context.Background(), *newCompany(t)), company.ErrCompanyNotFound)
+	})
+
+	t.Run("update_to_taken_name", func(t *testing.T) {
+		h := newHarness(t)
+		ctx := context.Background()
+		first := newCompany(t)
+		second := newCompany(t)
+		require.NoError(t, h.Repo.Create(ctx, *first))
+		require.NoError(t, h.Repo.Create(ctx, *second))
+
+		require.NoError(t, second.SetName(first.Name().String()))
+		assert.ErrorIs(t, h.Repo.Update(ctx, *second), company.ErrCompanyNameAlreadyExists)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		h := newHarness(t)
+		ctx := context.Background()
+		c := newCompany(t)
+		require.NoError(t, h.Repo.Create(ctx, *c))
+
+		require.NoError(t, h.Repo.Delete(ctx, c.ID().String()))
+
+		_, err := h.Repo.GetByID(ctx, c.ID().String())
+		assert.ErrorIs(t, err, company.ErrCompanyNotFound)
+		assert.ErrorIs(t, h.Repo.Delete(ctx, c.ID().String()), company.ErrCompanyNotFound)
+	})
+
+	t.Run("tx_commit", func(t *testing.T) {
+		h := newHarness(t)
+		ctx := context.Background()
+		c := newCompany(t)
+
+		err := h.TxManager.Do(ctx, func(ctx context.Context) error {
+			if err := h.Repo.Create(ctx, *c); err != nil {
+				return err
+			}
+			return h.Publisher.Publish(ctx, company.NewCompanyCreatedEvent(c))
+		})
+		require.NoError(t, err)
+
+		_, err = h.Repo.GetByID(ctx, c.ID().String())
+		require.NoError(t, err)
+		assert.Equal(t, 1, h.EventCount(t, c.ID().String()))
+	})
+
+	t.Run("tx_rollback", func(t *testing.T) {
+		h := newHarness(t)
+		ctx := context.Background()
+		c := newCompany(t)
+
+		err := h.TxManager.Do(ctx, func(ctx context.Context) error {
+			if err := h.Repo.Create(ctx, *c); err != nil {
+				return err
+			}
+			if err := h.Publisher.Publish(ctx, company.NewCompanyCreatedEvent(c)); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		require.ErrorIs(t, err, errRollback)
+
+		_, err = h.Repo.GetByID(ctx, c.ID().String())
+		assert.ErrorIs(t, err, company.ErrCompanyNotFound)
+		assert.Equal(t, 0, h.EventCount(t, c.ID().String()))
+	})
+
+	t.Run("tx_nested_rollback", func(t *testing.T) {
+		h := newHarness(t)
+		ctx := context.Background()
+		outer := newCompany(t)
+		inner := newCompany(t)
+
+		err := h.TxManager.Do(ctx, func(ctx context.Context) error {
+			if err := h.Repo.Create(ctx, *outer); err != nil {
+				return err
+			}
+
+			innerErr := h.TxManager.Do(ctx, func(ctx context.Context) error {
+				if err := h.Repo.Create(ctx, *inner); err != nil {
+					return err
+				}
+				return errRollback
+			})
+			assert.ErrorIs(t, innerErr, errRollback)
+			return nil
+		})
+		require.NoError(t, err)
+
+		_, err = h.Repo.GetByID(ctx, outer.ID().String())
+		assert.NoError(t, err)
+		_, err = h.Repo.GetByID(ctx, inner.ID().String())
+		assert.ErrorIs(t, err, company.ErrCompanyNotFound)
+	})
+}
+
+// newCompany returns a valid company with a unique name that fits the
+// 15 character limit, stamped as written now.
+func newCompany(t *testing.T) *company.Company {
+	t.Helper()
+
+	id := uuid.New()
+	c, err := company.NewCompany(id, "ct-"+id.String()[:8], "contract test", 10, company.CorporationsType.String())
+	require.NoError(t, err)
+	// Microseconds are what Postgres keeps
+	c.SetUpdatedAt(time.Now().UTC().Truncate(time.Microsecond))
+	return c
+}
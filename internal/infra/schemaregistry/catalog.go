@@ -0,0 +1,60 @@
+package schemaregistry
+
+import (
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+)
+
+// Namespace is the Avro namespace and protobuf package of every event schema.
+const Namespace = "xm.companies.events"
+
+// record describes the schema of one payload type.
+type record struct {
+	// name of the Avro record and of the protobuf message
+	name       string
+	avroFile   string
+	newPayload func() any
+}
+
+func (r record) fullName() string {
+	return Namespace + "." + r.name
+}
+
+var (
+	companyCreated = record{
+		name:       "CompanyCreated",
+		avroFile:   "avro/company_created.avsc",
+		newPayload: func() any { return &company.CompanyCreatedPayload{} },
+	}
+	companyUpdated = record{
+		name:       "CompanyUpdated",
+		avroFile:   "avro/company_updated.avsc",
+		newPayload: func() any { return &company.CompanyUpdatedPayload{} },
+	}
+	companyDeleted = record{
+		name:       "CompanyDeleted",
+		avroFile:   "avro/company_deleted.avsc",
+		newPayload: func() any { return &company.CompanyDeletedPayload{} },
+	}
+	companySnapshot = record{
+		name:       "CompanySnapshotEvent",
+		avroFile:   "avro/company_snapshot.avsc",
+		newPayload: func() any { return &company.CompanySnapshotPayload{} },
+	}
+	companyRegistrationChanged = record{
+		name:       "CompanyRegistrationChanged",
+		avroFile:   "avro/company_registration_changed.avsc",
+		newPayload: func() any { return &company.CompanyRegistrationChangedPayload{} },
+	}
+)
+
+var records = []record{companyCreated, companyUpdated, companyDeleted, companyRegistrationChanged, companySnapshot}
+
+// catalog maps outbox event types to their schema.
+var catalog = map[string]record{
+	company.EventCompanyCreated:      companyCreated,
+	company.EventCompanyUpdated:      companyUpdated,
+	company.EventCompanyDeleted:      companyDeleted,
+	company.EventCompanyRegistered:   companyRegistrationChanged,
+	company.EventCompanyUnregistered: companyRegistrationChanged,
+	company.EventCompanySnapshot:     companySnapshot,
+}
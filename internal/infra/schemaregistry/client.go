@@ -0,0 +1,141 @@
+// Package schemaregistry serializes outbox payloads for Kafka as JSON, or as
+// Avro or Protobuf in the Confluent wire format, registering the schemas from
+// api/schemas with a Confluent compatible schema registry.
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type SchemaType string
+
+const (
+	SchemaTypeAvro     SchemaType = "AVRO"
+	SchemaTypeProtobuf SchemaType = "PROTOBUF"
+)
+
+// Schema is a schema definition as stored in the registry.
+type Schema struct {
+	Type       SchemaType
+	Definition string
+}
+
+// Registry is the subset of the Confluent schema registry API the serializer needs.
+type Registry interface {
+	// Register returns the ID of schema under subject, adding it as a new
+	// version if it is not registered yet.
+	Register(ctx context.Context, subject string, schema Schema) (int, error)
+	// CheckCompatibility reports whether schema may be registered under
+	// subject; a subject without versions accepts any schema.
+	CheckCompatibility(ctx context.Context, subject string, schema Schema) (bool, error)
+}
+
+var ErrIncompatibleSchema = errors.New("schema is incompatible with the registered versions")
+
+// Client talks to a Confluent compatible registry, such as the one built
+// into Redpanda.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+var _ Registry = (*Client)(nil)
+
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), httpClient: httpClient}
+}
+
+type schemaRequest struct {
+	Schema     string     `json:"schema"`
+	SchemaType SchemaType `json:"schemaType,omitempty"`
+}
+
+type errorResponse struct {
+	ErrorCode int    `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+func (c *Client) Register(ctx context.Context, subject string, schema Schema) (int, error) {
+	var resp struct {
+		ID int `json:"id"`
+	}
+
+	status, err := c.post(ctx, "/subjects/"+url.PathEscape(subject)+"/versions", schema, &resp)
+	if err != nil {
+		if status == http.StatusConflict {
+			return 0, fmt.Errorf("register %s: %w: %w", subject, ErrIncompatibleSchema, err)
+		}
+		return 0, fmt.Errorf("register %s: %w", subject, err)
+	}
+
+	return resp.ID, nil
+}
+
+func (c *Client) CheckCompatibility(ctx context.Context, subject string, schema Schema) (bool, error) {
+	var resp struct {
+		IsCompatible bool `json:"is_compatible"`
+	}
+
+	status, err := c.post(ctx, "/compatibility/subjects/"+url.PathEscape(subject)+"/versions/latest", schema, &resp)
+	if err != nil {
+		if status == http.StatusNotFound {
+			return true, nil
+		}
+		return false, fmt.Errorf("check compatibility of %s: %w", subject, err)
+	}
+
+	return resp.IsCompatible, nil
+}
+
+// post sends schema to path and decodes a successful response into out. The
+// status code is returned alongside errors so callers can map it.
+func (c *Client) post(ctx context.Context, path string, schema Schema, out any) (int, error) {
+	req := schemaRequest{Schema: schema.Definition}
+	// AVRO is the registry default and older registries reject it spelled out
+	if schema.Type != SchemaTypeAvro {
+		req.SchemaType = schema.Type
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return 0, fmt.Errorf("schema registry request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		var e errorResponse
+		raw, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(raw, &e) != nil || e.Message == "" {
+			e.Message = strings.TrimSpace(string(raw))
+		}
+		return resp.StatusCode, fmt.Errorf("schema registry returned %d: %s", resp.StatusCode, e.Message)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to decode schema registry response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,71 @@
+//go:build unit
+
+package schemaregistry
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchemaV1 = `{"type":"record","name":"Thing","fields":[{"name":"id","type":"string"}]}`
+
+func TestClient_Register(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestRegistry(t)
+	schema := Schema{Type: SchemaTypeAvro, Definition: testSchemaV1}
+
+	id, err := client.Register(ctx, "things-value", schema)
+	require.NoError(t, err)
+
+	again, err := client.Register(ctx, "things-value", schema)
+	require.NoError(t, err)
+	assert.Equal(t, id, again, "registering the same schema is idempotent")
+
+	other, err := client.Register(ctx, "other-value", schema)
+	require.NoError(t, err)
+	assert.Equal(t, id, other, "identical schemas share an ID across subjects")
+}
+
+func TestClient_Compatibility(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestRegistry(t)
+
+	ok, err := client.CheckCompatibility(ctx, "things-value", Schema{Type: SchemaTypeAvro, Definition: testSchemaV1})
+	require.NoError(t, err)
+	assert.True(t, ok, "a new subject accepts any schema")
+
+	_, err = client.Register(ctx, "things-value", Schema{Type: SchemaTypeAvro, Definition: testSchemaV1})
+	require.NoError(t, err)
+
+	withDefault := Schema{Type: SchemaTypeAvro, Definition: strings.Replace(testSchemaV1,
+		`]}`, `,{"name":"size","type":"int","default":0}]}`, 1)}
+	ok, err = client.CheckCompatibility(ctx, "things-value", withDefault)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	withoutDefault := Schema{Type: SchemaTypeAvro, Definition: strings.Replace(testSchemaV1,
+		`]}`, `,{"name":"size","type":"int"}]}`, 1)}
+	ok, err = client.CheckCompatibility(ctx, "things-value", withoutDefault)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = client.Register(ctx, "things-value", withoutDefault)
+	assert.ErrorIs(t, err, ErrIncompatibleSchema)
+
+	id, err := client.Register(ctx, "things-value", withDefault)
+	require.NoError(t, err)
+	assert.Equal(t, 2, id)
+}
+
+func TestClient_InvalidSchema(t *testing.T) {
+	_, client := newTestRegistry(t)
+
+	_, err := client.Register(context.Background(), "things-value", Schema{Type: SchemaTypeAvro, Definition: `{"type":"nope"}`})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrIncompatibleSchema)
+	assert.Contains(t, err.Error(), "422")
+}
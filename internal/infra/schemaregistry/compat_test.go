@@ -0,0 +1,306 @@
+//go:build unit
+
+package schemaregistry
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/dubininme/xm-assessment/api/schemas"
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/domain/events"
+	"github.com/google/uuid"
+	"github.com/hamba/avro/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// sampleEvents returns one event per catalog entry with every optional field
+// set, so no field is left out of the JSON by omitempty.
+func sampleEvents(t *testing.T) []events.Event {
+	t.Helper()
+
+	previous, err := company.NewCompany(uuid.New(), "Before", "old", 5, company.CorporationsType.String())
+	require.NoError(t, err)
+
+	current := *previous
+	require.NoError(t, current.SetName("After"))
+	require.NoError(t, current.SetDescription("new"))
+	require.NoError(t, current.SetEmployeesCount(7))
+	require.NoError(t, current.SetType(company.NonProfitType.String()))
+	current.Register()
+
+	name, description, count, registered, typ := "After", "new", 7, true, company.NonProfitType.String()
+	params := company.UpdateParams{
+		Name:           &name,
+		Description:    &description,
+		EmployeesCount: &count,
+		Registered:     &registered,
+		Type:           &typ,
+	}
+
+	unregistered := current
+	unregistered.SetRegistered(false)
+
+	return []events.Event{
+		company.NewCompanyCreatedEvent(&current),
+		company.NewCompanyUpdatedEvent(previous, &current, params),
+		company.NewCompanyDeletedEvent(&current),
+		company.NewCompanyRegistrationChangedEvent(&current),
+		company.NewCompanyRegistrationChangedEvent(&unregistered),
+		company.NewCompanySnapshotEvent(&current),
+	}
+}
+
+func TestCatalog_CoversEveryEvent(t *testing.T) {
+	for _, e := range sampleEvents(t) {
+		assert.Contains(t, catalog, e.EventName())
+	}
+}
+
+// The checks below fail when a payload struct and its schema drift apart:
+// update api/schemas together with the struct, keeping the change backward
+// compatible (new fields need a default in Avro and a fresh number in protobuf).
+
+func TestAvroSchemas_MatchPayloads(t *testing.T) {
+	avroSchemas, err := LoadAvroSchemas()
+	require.NoError(t, err)
+
+	for _, e := range sampleEvents(t) {
+		t.Run(e.EventName(), func(t *testing.T) {
+			r := catalog[e.EventName()]
+			schema := avroSchemas[r.name]
+			assert.Equal(t, r.fullName(), schema.(avro.NamedSchema).FullName())
+
+			raw, err := json.Marshal(e.Payload())
+			require.NoError(t, err)
+			assertSameFields(t, r.name, jsonFields(t, raw), avroFields(schema.(*avro.RecordSchema)))
+
+			encoded, err := avroAPI.Marshal(schema, e.Payload())
+			require.NoError(t, err)
+
+			decoded := r.newPayload()
+			require.NoError(t, avroAPI.Unmarshal(schema, encoded, decoded))
+
+			roundTrip, err := json.Marshal(decoded)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(raw), string(roundTrip))
+		})
+	}
+}
+
+func TestProtoSchema_MatchesPayloads(t *testing.T) {
+	file, err := LoadProtoFile(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Namespace, string(file.Package()))
+
+	for _, e := range sampleEvents(t) {
+		t.Run(e.EventName(), func(t *testing.T) {
+			r := catalog[e.EventName()]
+			desc := file.Messages().ByName(protoreflect.Name(r.name))
+			require.NotNil(t, desc, "message %s is missing", r.name)
+
+			raw, err := json.Marshal(e.Payload())
+			require.NoError(t, err)
+			assertSameFields(t, r.name, jsonFields(t, raw), protoFields(desc))
+
+			msg := dynamicpb.NewMessage(desc)
+			require.NoError(t, protojson.Unmarshal(raw, msg))
+
+			roundTrip, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+			require.NoError(t, err)
+			assert.JSONEq(t, normalizeJSON(t, raw), string(roundTrip))
+		})
+	}
+}
+
+// The checks below fail on a breaking change even when the payload struct
+// changed with its schema: api/schemas must stay compatible with the versions
+// in api/schemas/published, which is what consumers in production read with.
+
+func TestSchemas_CompatibleWithPublishedVersions(t *testing.T) {
+	ctx := context.Background()
+	for _, format := range []Format{FormatAvro, FormatProtobuf} {
+		t.Run(string(format), func(t *testing.T) {
+			_, client := newTestRegistry(t)
+			s, err := NewSerializer(ctx, format, client, testTopic)
+			require.NoError(t, err)
+
+			for _, r := range records {
+				current, err := s.schema(r)
+				require.NoError(t, err)
+				published, err := readSchema(current.Type, publishedFile(format, r))
+				require.NoError(t, err)
+
+				subject := s.subject(r)
+				_, err = client.Register(ctx, subject, published)
+				require.NoError(t, err)
+
+				ok, err := client.CheckCompatibility(ctx, subject, current)
+				require.NoError(t, err)
+				assert.True(t, ok, "%s breaks its published version in %s", r.name, publishedFile(format, r))
+			}
+		})
+	}
+}
+
+func TestSchemas_PublishedVersionsCatchBreakingChanges(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		format   Format
+		breaking func(schema string) string
+	}{
+		{"avro field type changed", FormatAvro, func(schema string) string {
+			return strings.Replace(schema, `{"name": "employees_count", "type": "int"}`, `{"name": "employees_count", "type": "string"}`, 1)
+		}},
+		{"protobuf field type changed", FormatProtobuf, func(schema string) string {
+			return strings.Replace(schema, "int32 employees_count = 5;", "string employees_count = 5;", 1)
+		}},
+		{"protobuf field removed without reserving it", FormatProtobuf, func(schema string) string {
+			return strings.Replace(schema, "int32 employees_count = 5;", "", 1)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, client := newTestRegistry(t)
+			s, err := NewSerializer(ctx, tt.format, client, testTopic)
+			require.NoError(t, err)
+
+			current, err := s.schema(companyCreated)
+			require.NoError(t, err)
+			published, err := readSchema(current.Type, publishedFile(tt.format, companyCreated))
+			require.NoError(t, err)
+
+			subject := s.subject(companyCreated)
+			_, err = client.Register(ctx, subject, published)
+			require.NoError(t, err)
+
+			broken := Schema{Type: current.Type, Definition: tt.breaking(current.Definition)}
+			require.NotEqual(t, current.Definition, broken.Definition)
+			ok, err := client.CheckCompatibility(ctx, subject, broken)
+			require.NoError(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
+
+// publishedFile is the path of the published schema of r in schemas.FS.
+func publishedFile(format Format, r record) string {
+	if format == FormatProtobuf {
+		return path.Join(schemas.PublishedDir, schemas.ProtoFile)
+	}
+	return path.Join(schemas.PublishedDir, r.avroFile)
+}
+
+func assertSameFields(t *testing.T, record string, payload, schema map[string][]string) {
+	t.Helper()
+	for path, fields := range payload {
+		assert.Equal(t, fields, schema[path], "fields of %s%s differ between payload and schema", record, path)
+	}
+	for path := range schema {
+		assert.Contains(t, payload, path, "%s%s is in the schema but not in the payload", record, path)
+	}
+}
+
+// jsonFields lists the keys of every object in raw by path, e.g. "" and ".company".
+func jsonFields(t *testing.T, raw []byte) map[string][]string {
+	t.Helper()
+
+	var v map[string]any
+	require.NoError(t, json.Unmarshal(raw, &v))
+
+	out := map[string][]string{}
+	var walk func(path string, obj map[string]any)
+	walk = func(path string, obj map[string]any) {
+		for k, v := range obj {
+			out[path] = append(out[path], k)
+			if child, ok := v.(map[string]any); ok {
+				walk(path+"."+k, child)
+			}
+		}
+		sort.Strings(out[path])
+	}
+	walk("", v)
+	return out
+}
+
+func avroFields(schema *avro.RecordSchema) map[string][]string {
+	out := map[string][]string{}
+	var walk func(path string, rec *avro.RecordSchema)
+	walk = func(path string, rec *avro.RecordSchema) {
+		for _, f := range rec.Fields() {
+			out[path] = append(out[path], f.Name())
+			typ := f.Type()
+			if ref, ok := typ.(*avro.RefSchema); ok {
+				typ = ref.Schema()
+			}
+			if child, ok := typ.(*avro.RecordSchema); ok {
+				walk(path+"."+f.Name(), child)
+			}
+		}
+		sort.Strings(out[path])
+	}
+	walk("", schema)
+	return out
+}
+
+func protoFields(desc protoreflect.MessageDescriptor) map[string][]string {
+	out := map[string][]string{}
+	var walk func(path string, msg protoreflect.MessageDescriptor)
+	walk = func(path string, msg protoreflect.MessageDescriptor) {
+		fields := msg.Fields()
+		for i := range fields.Len() {
+			f := fields.Get(i)
+			out[path] = append(out[path], string(f.Name()))
+			if f.Message() != nil {
+				walk(path+"."+string(f.Name()), f.Message())
+			}
+		}
+		sort.Strings(out[path])
+	}
+	walk("", desc)
+	return out
+}
+
+// normalizeJSON drops the zero values protojson leaves out of its output.
+func normalizeJSON(t *testing.T, raw []byte) string {
+	t.Helper()
+
+	var v map[string]any
+	require.NoError(t, json.Unmarshal(raw, &v))
+
+	var prune func(obj map[string]any)
+	prune = func(obj map[string]any) {
+		for k, val := range obj {
+			switch val := val.(type) {
+			case map[string]any:
+				prune(val)
+			case string:
+				if val == "" {
+					delete(obj, k)
+				}
+			case bool:
+				if !val {
+					delete(obj, k)
+				}
+			case float64:
+				if val == 0 {
+					delete(obj, k)
+				}
+			}
+		}
+	}
+	prune(v)
+
+	out, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(out)
+}
@@ -0,0 +1,262 @@
+// Package registrytest provides an in-process stand-in for a Confluent schema
+// registry, covering the endpoints schemaregistry.Client uses.
+package registrytest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/hamba/avro/v2"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type entry struct {
+	SchemaType string `json:"schemaType,omitempty"`
+	Schema     string `json:"schema"`
+}
+
+// Registry keeps subjects in memory. Subjects enforce BACKWARD compatibility
+// against the latest version, like the registry default. Avro schemas get the
+// full check; of protobuf ones only removed messages, fields whose type
+// changed and removed fields whose number is not reserved are rejected.
+type Registry struct {
+	mu       sync.Mutex
+	schemas  []entry
+	subjects map[string][]int
+	handler  http.Handler
+}
+
+func New() *Registry {
+	r := &Registry{subjects: make(map[string][]int)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /subjects/{subject}/versions", r.register)
+	mux.HandleFunc("POST /compatibility/subjects/{subject}/versions/latest", r.compatibility)
+	mux.HandleFunc("GET /schemas/ids/{id}", r.schemaByID)
+	r.handler = mux
+
+	return r
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.handler.ServeHTTP(w, req)
+}
+
+// Subjects returns the IDs registered per subject, oldest version first.
+func (r *Registry) Subjects() map[string][]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make(map[string][]int, len(r.subjects))
+	for subject, ids := range r.subjects {
+		out[subject] = append([]int(nil), ids...)
+	}
+	return out
+}
+
+func (r *Registry) register(w http.ResponseWriter, req *http.Request) {
+	e, ok := decodeEntry(w, req)
+	if !ok {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subject := req.PathValue("subject")
+	versions := r.subjects[subject]
+	for _, id := range versions {
+		if r.schemas[id-1] == e {
+			writeJSON(w, http.StatusOK, map[string]int{"id": id})
+			return
+		}
+	}
+
+	if len(versions) > 0 {
+		if err := compatible(e, r.schemas[versions[len(versions)-1]-1]); err != nil {
+			writeError(w, http.StatusConflict, 409, err.Error())
+			return
+		}
+	}
+
+	id := r.idOf(e)
+	r.subjects[subject] = append(versions, id)
+	writeJSON(w, http.StatusOK, map[string]int{"id": id})
+}
+
+func (r *Registry) compatibility(w http.ResponseWriter, req *http.Request) {
+	e, ok := decodeEntry(w, req)
+	if !ok {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	versions := r.subjects[req.PathValue("subject")]
+	if len(versions) == 0 {
+		writeError(w, http.StatusNotFound, 40401, "Subject not found")
+		return
+	}
+
+	err := compatible(e, r.schemas[versions[len(versions)-1]-1])
+	writeJSON(w, http.StatusOK, map[string]bool{"is_compatible": err == nil})
+}
+
+func (r *Registry) schemaByID(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.Atoi(req.PathValue("id"))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil || id < 1 || id > len(r.schemas) {
+		writeError(w, http.StatusNotFound, 40403, "Schema not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, r.schemas[id-1])
+}
+
+// idOf returns the global ID of e, assigning the next one to a new schema.
+func (r *Registry) idOf(e entry) int {
+	for i, s := range r.schemas {
+		if s == e {
+			return i + 1
+		}
+	}
+	r.schemas = append(r.schemas, e)
+	return len(r.schemas)
+}
+
+// compatible checks that data written with latest can be read with next.
+func compatible(next, latest entry) error {
+	if next.SchemaType != latest.SchemaType {
+		return fmt.Errorf("schema type changed from %s to %s", typeName(latest), typeName(next))
+	}
+	// decodeEntry stores Avro as the empty type
+	switch next.SchemaType {
+	case "":
+	case "PROTOBUF":
+		return compatibleProto(next.Schema, latest.Schema)
+	default:
+		return nil
+	}
+
+	reader, err := avro.ParseWithCache(next.Schema, "", &avro.SchemaCache{})
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+	writer, err := avro.ParseWithCache(latest.Schema, "", &avro.SchemaCache{})
+	if err != nil {
+		return fmt.Errorf("invalid registered schema: %w", err)
+	}
+	return avro.NewSchemaCompatibility().Compatible(reader, writer)
+}
+
+func compatibleProto(next, latest string) error {
+	reader, err := compileProto(next)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+	writer, err := compileProto(latest)
+	if err != nil {
+		return fmt.Errorf("invalid registered schema: %w", err)
+	}
+	return compatibleMessages(reader.Messages(), writer.Messages())
+}
+
+func compatibleMessages(next, latest protoreflect.MessageDescriptors) error {
+	for i := range latest.Len() {
+		old := latest.Get(i)
+		msg := next.ByName(old.Name())
+		if msg == nil {
+			return fmt.Errorf("message %s was removed", old.FullName())
+		}
+
+		fields := old.Fields()
+		for j := range fields.Len() {
+			f := fields.Get(j)
+			g := msg.Fields().ByNumber(f.Number())
+			switch {
+			case g == nil && !msg.ReservedRanges().Has(f.Number()):
+				return fmt.Errorf("field %d of %s was removed without reserving it", f.Number(), old.FullName())
+			case g != nil && fieldType(g) != fieldType(f):
+				return fmt.Errorf("field %d of %s changed from %s to %s", f.Number(), old.FullName(), fieldType(f), fieldType(g))
+			}
+		}
+
+		if err := compatibleMessages(msg.Messages(), old.Messages()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldType describes the type of f as written in the schema, e.g.
+// "repeated string" or "optional xm.Thing".
+func fieldType(f protoreflect.FieldDescriptor) string {
+	typ := f.Kind().String()
+	switch {
+	case f.Message() != nil:
+		typ = string(f.Message().FullName())
+	case f.Enum() != nil:
+		typ = string(f.Enum().FullName())
+	}
+	return f.Cardinality().String() + " " + typ
+}
+
+// compileProto compiles a self-contained protobuf schema.
+func compileProto(schema string) (protoreflect.FileDescriptor, error) {
+	const path = "schema.proto"
+	compiler := protocompile.Compiler{
+		Resolver: &protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{path: schema}),
+		},
+	}
+
+	files, err := compiler.Compile(context.Background(), path)
+	if err != nil {
+		return nil, err
+	}
+	return files[0], nil
+}
+
+func typeName(e entry) string {
+	if e.SchemaType == "" {
+		return "AVRO"
+	}
+	return e.SchemaType
+}
+
+func decodeEntry(w http.ResponseWriter, req *http.Request) (entry, bool) {
+	var e entry
+	if err := json.NewDecoder(req.Body).Decode(&e); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, 42201, "Invalid schema")
+		return entry{}, false
+	}
+	if e.SchemaType == "AVRO" {
+		e.SchemaType = ""
+	}
+
+	if e.SchemaType == "" {
+		if _, err := avro.ParseWithCache(e.Schema, "", &avro.SchemaCache{}); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, 42201, "Invalid schema: "+err.Error())
+			return entry{}, false
+		}
+	}
+	return e, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status, code int, message string) {
+	writeJSON(w, status, map[string]any{"error_code": code, "message": message})
+}
@@ -0,0 +1,261 @@
+package schemaregistry
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/dubininme/xm-assessment/api/schemas"
+	"github.com/hamba/avro/v2"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type Format string
+
+const (
+	FormatJSON     Format = "json"
+	FormatAvro     Format = "avro"
+	FormatProtobuf Format = "protobuf"
+)
+
+// magicByte starts every message in the Confluent wire format; it is followed
+// by the big endian schema ID.
+const magicByte = 0
+
+// avroAPI matches payload fields by their json tags, so the structs need no
+// second set of tags.
+var avroAPI = avro.Config{TagKey: "json"}.Freeze()
+
+// ErrInvalidPayload is returned by Serialize for a payload that no schema
+// can encode. Unlike a failure to reach the registry, retrying will not help.
+var ErrInvalidPayload = errors.New("payload does not match its schema")
+
+// Serializer turns outbox payloads, which are stored as JSON, into Kafka
+// message values. Schemas are registered under the TopicRecordNameStrategy
+// subject "<topic>-<record full name>" on first use.
+type Serializer struct {
+	format   Format
+	registry Registry
+	topic    string
+
+	avroSchemas map[string]avro.Schema
+	protoFile   protoreflect.FileDescriptor
+
+	mu  sync.Mutex
+	ids map[string]int
+}
+
+// NewSerializer loads the embedded schemas for format. registry may be nil
+// for FormatJSON.
+func NewSerializer(ctx context.Context, format Format, registry Registry, topic string) (*Serializer, error) {
+	s := &Serializer{
+		format:   format,
+		registry: registry,
+		topic:    topic,
+		ids:      make(map[string]int),
+	}
+
+	switch format {
+	case FormatJSON:
+		return s, nil
+	case FormatAvro:
+		avroSchemas, err := LoadAvroSchemas()
+		if err != nil {
+			return nil, err
+		}
+		s.avroSchemas = avroSchemas
+	case FormatProtobuf:
+		protoFile, err := LoadProtoFile(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.protoFile = protoFile
+	default:
+		return nil, fmt.Errorf("unknown value format %q", format)
+	}
+
+	if registry == nil {
+		return nil, fmt.Errorf("%s values need a schema registry", format)
+	}
+	return s, nil
+}
+
+// LoadAvroSchemas parses every Avro schema in api/schemas, keyed by record name.
+func LoadAvroSchemas() (map[string]avro.Schema, error) {
+	out := make(map[string]avro.Schema, len(records))
+	for _, r := range records {
+		raw, err := schemas.FS.ReadFile(r.avroFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", r.avroFile, err)
+		}
+
+		schema, err := avro.ParseBytes(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", r.avroFile, err)
+		}
+		out[r.name] = schema
+	}
+	return out, nil
+}
+
+// LoadProtoFile compiles the protobuf schema in api/schemas.
+func LoadProtoFile(ctx context.Context) (protoreflect.FileDescriptor, error) {
+	compiler := protocompile.Compiler{
+		Resolver: &protocompile.SourceResolver{
+			Accessor: func(path string) (io.ReadCloser, error) {
+				return schemas.FS.Open(path)
+			},
+		},
+	}
+
+	files, err := compiler.Compile(ctx, schemas.ProtoFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile %s: %w", schemas.ProtoFile, err)
+	}
+	return files[0], nil
+}
+
+// Schemas returns the schema of every record by subject.
+func (s *Serializer) Schemas() (map[string]Schema, error) {
+	out := make(map[string]Schema, len(records))
+	for _, r := range records {
+		schema, err := s.schema(r)
+		if err != nil {
+			return nil, err
+		}
+		out[s.subject(r)] = schema
+	}
+	return out, nil
+}
+
+// ContentType describes the message value; it is sent as a message header.
+func (s *Serializer) ContentType() string {
+	switch s.format {
+	case FormatAvro:
+		return "application/vnd.apache.avro+binary"
+	case FormatProtobuf:
+		return "application/x-protobuf"
+	default:
+		return "application/json"
+	}
+}
+
+// Serialize encodes the JSON payload of an outbox event of type eventType.
+func (s *Serializer) Serialize(ctx context.Context, eventType string, payload []byte) ([]byte, error) {
+	if s.format == FormatJSON {
+		return payload, nil
+	}
+
+	r, ok := catalog[eventType]
+	if !ok {
+		return nil, fmt.Errorf("%w: no schema for event type %q", ErrInvalidPayload, eventType)
+	}
+
+	schema, err := s.schema(r)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.schemaID(ctx, s.subject(r), schema)
+	if err != nil {
+		return nil, err
+	}
+
+	out := binary.BigEndian.AppendUint32([]byte{magicByte}, uint32(id))
+
+	switch s.format {
+	case FormatAvro:
+		body, err := s.encodeAvro(r, payload)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to encode %s as avro: %w", ErrInvalidPayload, eventType, err)
+		}
+		return append(out, body...), nil
+	default:
+		desc := s.protoFile.Messages().ByName(protoreflect.Name(r.name))
+		body, err := encodeProto(desc, payload)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to encode %s as protobuf: %w", ErrInvalidPayload, eventType, err)
+		}
+		return append(appendMessageIndexes(out, desc.Index()), body...), nil
+	}
+}
+
+func (s *Serializer) subject(r record) string {
+	return s.topic + "-" + r.fullName()
+}
+
+func (s *Serializer) schema(r record) (Schema, error) {
+	switch s.format {
+	case FormatAvro:
+		return readSchema(SchemaTypeAvro, r.avroFile)
+	case FormatProtobuf:
+		return readSchema(SchemaTypeProtobuf, schemas.ProtoFile)
+	default:
+		return Schema{}, fmt.Errorf("%s values have no schema", s.format)
+	}
+}
+
+// readSchema returns a schema file as written, since the parsed forms drop
+// docs and defaults the registry needs for compatibility checks.
+func readSchema(typ SchemaType, path string) (Schema, error) {
+	raw, err := schemas.FS.ReadFile(path)
+	if err != nil {
+		return Schema{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return Schema{Type: typ, Definition: string(raw)}, nil
+}
+
+// schemaID registers schema once per subject and caches its ID.
+func (s *Serializer) schemaID(ctx context.Context, subject string, schema Schema) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id, ok := s.ids[subject]; ok {
+		return id, nil
+	}
+
+	id, err := s.registry.Register(ctx, subject, schema)
+	if err != nil {
+		return 0, err
+	}
+
+	s.ids[subject] = id
+	return id, nil
+}
+
+func (s *Serializer) encodeAvro(r record, payload []byte) ([]byte, error) {
+	v := r.newPayload()
+	if err := json.Unmarshal(payload, v); err != nil {
+		return nil, err
+	}
+	return avroAPI.Marshal(s.avroSchemas[r.name], v)
+}
+
+// encodeProto maps the JSON payload onto desc by field name. Fields missing
+// from the schema are an error rather than being dropped silently.
+func encodeProto(desc protoreflect.MessageDescriptor, payload []byte) ([]byte, error) {
+	msg := dynamicpb.NewMessage(desc)
+	if err := protojson.Unmarshal(payload, msg); err != nil {
+		return nil, err
+	}
+	return proto.Marshal(msg)
+}
+
+// appendMessageIndexes writes the path of a top-level message in the Confluent
+// protobuf framing: a zigzag varint count followed by the indexes, with the
+// first message shortened to a single 0.
+func appendMessageIndexes(b []byte, index int) []byte {
+	if index == 0 {
+		return append(b, 0)
+	}
+	b = binary.AppendVarint(b, 1)
+	return binary.AppendVarint(b, int64(index))
+}
@@ -0,0 +1,140 @@
+//go:build unit
+
+package schemaregistry
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/infra/schemaregistry/registrytest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const testTopic = "company-events"
+
+func newTestRegistry(t *testing.T) (*registrytest.Registry, *Client) {
+	t.Helper()
+
+	registry := registrytest.New()
+	srv := httptest.NewServer(registry)
+	t.Cleanup(srv.Close)
+
+	return registry, NewClient(srv.URL, srv.Client())
+}
+
+func TestSerializer_JSONPassesPayloadThrough(t *testing.T) {
+	s, err := NewSerializer(context.Background(), FormatJSON, nil, testTopic)
+	require.NoError(t, err)
+
+	out, err := s.Serialize(context.Background(), "CompanyDeleted", []byte(`{"company_id":"x"}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"company_id":"x"}`, string(out))
+	assert.Equal(t, "application/json", s.ContentType())
+}
+
+func TestSerializer_RequiresRegistry(t *testing.T) {
+	_, err := NewSerializer(context.Background(), FormatAvro, nil, testTopic)
+	assert.Error(t, err)
+
+	_, err = NewSerializer(context.Background(), Format("xml"), nil, testTopic)
+	assert.Error(t, err)
+}
+
+func TestSerializer_Avro(t *testing.T) {
+	ctx := context.Background()
+	registry, client := newTestRegistry(t)
+
+	s, err := NewSerializer(ctx, FormatAvro, client, testTopic)
+	require.NoError(t, err)
+
+	for _, e := range sampleEvents(t) {
+		payload, err := json.Marshal(e.Payload())
+		require.NoError(t, err)
+
+		out, err := s.Serialize(ctx, e.EventName(), payload)
+		require.NoError(t, err)
+
+		require.Equal(t, byte(magicByte), out[0])
+		id := int(binary.BigEndian.Uint32(out[1:5]))
+		r := catalog[e.EventName()]
+		assert.Equal(t, []int{id}, registry.Subjects()[testTopic+"-"+r.fullName()])
+
+		decoded := r.newPayload()
+		require.NoError(t, avroAPI.Unmarshal(s.avroSchemas[r.name], out[5:], decoded))
+		roundTrip, err := json.Marshal(decoded)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(payload), string(roundTrip))
+	}
+
+	// CompanyRegistered and CompanyUnregistered share one subject
+	assert.Len(t, registry.Subjects(), len(records))
+}
+
+func TestSerializer_Protobuf(t *testing.T) {
+	ctx := context.Background()
+	registry, client := newTestRegistry(t)
+
+	s, err := NewSerializer(ctx, FormatProtobuf, client, testTopic)
+	require.NoError(t, err)
+
+	c, err := company.NewCompany([16]byte{1}, "Proto", "", 3, company.CooperativeType.String())
+	require.NoError(t, err)
+	payload, err := json.Marshal(company.NewCompanyDeletedEvent(c).Payload())
+	require.NoError(t, err)
+
+	out, err := s.Serialize(ctx, "CompanyDeleted", payload)
+	require.NoError(t, err)
+
+	desc := s.protoFile.Messages().ByName("CompanyDeleted")
+	id := int(binary.BigEndian.Uint32(out[1:5]))
+	assert.Equal(t, []int{id}, registry.Subjects()[testTopic+"-"+Namespace+".CompanyDeleted"])
+
+	// Message index path [3], as zigzag varints: count 1, index 3
+	require.Equal(t, []byte{2, 6}, out[5:7])
+	assert.Equal(t, 3, desc.Index())
+
+	msg := dynamicpb.NewMessage(desc)
+	require.NoError(t, proto.Unmarshal(out[7:], msg))
+	snapshot := msg.Get(desc.Fields().ByName("company")).Message()
+	assert.Equal(t, "Proto", snapshot.Get(snapshot.Descriptor().Fields().ByName(protoreflect.Name("name"))).String())
+}
+
+func TestSerializer_UnknownEventType(t *testing.T) {
+	_, client := newTestRegistry(t)
+
+	s, err := NewSerializer(context.Background(), FormatProtobuf, client, testTopic)
+	require.NoError(t, err)
+
+	_, err = s.Serialize(context.Background(), "CompanyRenamed", []byte(`{}`))
+	assert.ErrorIs(t, err, ErrInvalidPayload)
+}
+
+func TestSerializer_RejectsFieldsMissingFromSchema(t *testing.T) {
+	_, client := newTestRegistry(t)
+
+	s, err := NewSerializer(context.Background(), FormatProtobuf, client, testTopic)
+	require.NoError(t, err)
+
+	_, err = s.Serialize(context.Background(), "CompanyDeleted", []byte(`{"company_id":"x","reason":"gone"}`))
+	assert.ErrorIs(t, err, ErrInvalidPayload)
+}
+
+func TestAppendMessageIndexes(t *testing.T) {
+	assert.Equal(t, []byte{0}, appendMessageIndexes(nil, 0))
+	assert.Equal(t, []byte{2, 2}, appendMessageIndexes(nil, 1))
+
+	// protojson must accept the proto field names the JSON payloads use
+	file, err := LoadProtoFile(context.Background())
+	require.NoError(t, err)
+	msg := dynamicpb.NewMessage(file.Messages().ByName("CompanyDeleted"))
+	assert.NoError(t, protojson.Unmarshal([]byte(`{"schema_version":2}`), msg))
+}
@@ -0,0 +1,41 @@
+package sink
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/domain/webhook"
+)
+
+// NewDeliveryClient returns the client for subscription deliveries. It checks
+// every address it connects to against targets after the host name is
+// resolved, so that neither a literal address nor a name that resolves to
+// one, possibly only after the subscription was created, nor a redirect lets a
+// partner reach this service's own network. It ignores proxy settings, which
+// would hide the final address.
+func NewDeliveryClient(targets webhook.TargetPolicy) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !targets.Permits(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", webhook.ErrURLNotAllowed, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Transport: transport}
+}
@@ -0,0 +1,37 @@
+//go:build unit
+
+package sink
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/dubininme/xm-assessment/internal/domain/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryClient_RefusesAddressesThePolicyDoesNotPermit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+
+	_, err := NewDeliveryClient(webhook.TargetPolicy{}).Get(srv.URL)
+	assert.ErrorIs(t, err, webhook.ErrURLNotAllowed)
+
+	// a name is checked once it is resolved
+	named := fmt.Sprintf("http://localhost:%d", srv.Listener.Addr().(*net.TCPAddr).Port)
+	_, err = NewDeliveryClient(webhook.TargetPolicy{}).Get(named)
+	assert.ErrorIs(t, err, webhook.ErrURLNotAllowed)
+
+	loopback := webhook.TargetPolicy{Allowed: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}}
+	resp, err := NewDeliveryClient(loopback).Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
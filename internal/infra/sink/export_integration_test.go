@@ -0,0 +1,8 @@
+//go:build integration
+
+package sink
+
+import "time"
+
+// SetNow replaces the clock the dispatcher schedules retries with.
+func (d *Dispatcher) SetNow(now func() time.Time) { d.now = now }
@@ -0,0 +1,58 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/dubininme/xm-assessment/internal/infra/outbox"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+)
+
+// File appends every event as one JSON line, to a file or to stdout. It is
+// meant for local development and debugging.
+type File struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+var _ outbox.Sink = (*File)(nil)
+
+// NewFile opens path for appending; "" or "-" writes to stdout.
+func NewFile(path string) (*File, error) {
+	if path == "" || path == "-" {
+		return &File{w: os.Stdout}, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644) // #nosec G302 G304 -- path comes from config
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sink file: %w", err)
+	}
+	return &File{w: f, closer: f}, nil
+}
+
+func (f *File) Name() string { return "file" }
+
+func (f *File) Deliver(_ context.Context, events []postgres.OutboxEvent) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	enc := json.NewEncoder(f.w)
+	for i, e := range events {
+		if err := enc.Encode(newEnvelope(e)); err != nil {
+			return i, fmt.Errorf("failed to write outbox event %d: %w", e.ID, err)
+		}
+	}
+	return len(events), nil
+}
+
+func (f *File) Close() error {
+	if f.closer == nil {
+		return nil
+	}
+	return f.closer.Close()
+}
@@ -0,0 +1,45 @@
+//go:build unit
+
+package sink
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile_AppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	// Each event is written by a new session appending to the same file
+	for i := range testEvents {
+		f, err := NewFile(path)
+		require.NoError(t, err)
+
+		n, err := f.Deliver(context.Background(), testEvents[i:i+1])
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		require.NoError(t, f.Close())
+	}
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var got []Envelope
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var env Envelope
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &env))
+		got = append(got, env)
+	}
+	require.NoError(t, scanner.Err())
+
+	assert.Equal(t, []Envelope{newEnvelope(testEvents[0]), newEnvelope(testEvents[1])}, got)
+}
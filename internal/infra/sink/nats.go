@@ -0,0 +1,75 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/dubininme/xm-assessment/internal/infra/outbox"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/nats-io/nats.go"
+)
+
+// NATSConn is the part of *nats.Conn the sink uses.
+type NATSConn interface {
+	PublishMsg(msg *nats.Msg) error
+	FlushWithContext(ctx context.Context) error
+	Drain() error
+}
+
+var _ NATSConn = (*nats.Conn)(nil)
+
+// NATS publishes every event as an Envelope to "<prefix>.<event type>". The
+// Nats-Msg-Id header carries the outbox ID, so a JetStream stream on those
+// subjects drops the duplicates of a redelivered batch.
+type NATS struct {
+	conn          NATSConn
+	subjectPrefix string
+}
+
+var _ outbox.Sink = (*NATS)(nil)
+
+func NewNATS(conn NATSConn, subjectPrefix string) *NATS {
+	return &NATS{conn: conn, subjectPrefix: subjectPrefix}
+}
+
+// DialNATS connects to the NATS server at url.
+func DialNATS(url, subjectPrefix string) (*NATS, error) {
+	conn, err := nats.Connect(url, nats.Name("xm-companies-outbox"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+	return NewNATS(conn, subjectPrefix), nil
+}
+
+func (n *NATS) Name() string { return "nats" }
+
+// Deliver publishes the whole batch and flushes it; the events only count as
+// delivered once the server has acknowledged the flush.
+func (n *NATS) Deliver(ctx context.Context, events []postgres.OutboxEvent) (int, error) {
+	for _, e := range events {
+		data, err := json.Marshal(newEnvelope(e))
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal outbox event %d: %w", e.ID, err)
+		}
+
+		msg := nats.NewMsg(n.subjectPrefix + "." + e.EventType)
+		msg.Data = data
+		msg.Header.Set(nats.MsgIdHdr, strconv.FormatInt(e.ID, 10))
+		msg.Header.Set("event_name", e.EventType)
+
+		if err := n.conn.PublishMsg(msg); err != nil {
+			return 0, fmt.Errorf("failed to publish outbox event %d: %w", e.ID, err)
+		}
+	}
+
+	if err := n.conn.FlushWithContext(ctx); err != nil {
+		return 0, fmt.Errorf("failed to flush nats connection: %w", err)
+	}
+	return len(events), nil
+}
+
+func (n *NATS) Close() error {
+	return n.conn.Drain()
+}
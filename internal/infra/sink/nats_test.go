@@ -0,0 +1,51 @@
+//go:build unit
+
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeNATSConn struct {
+	published []*nats.Msg
+	flushErr  error
+}
+
+func (c *fakeNATSConn) PublishMsg(msg *nats.Msg) error {
+	c.published = append(c.published, msg)
+	return nil
+}
+
+func (c *fakeNATSConn) FlushWithContext(context.Context) error { return c.flushErr }
+
+func (c *fakeNATSConn) Drain() error { return nil }
+
+func TestNATS_PublishesPerEventType(t *testing.T) {
+	conn := &fakeNATSConn{}
+	n, err := NewNATS(conn, "companies.events").Deliver(context.Background(), testEvents)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	require.Len(t, conn.published, 2)
+	msg := conn.published[1]
+	assert.Equal(t, "companies.events.CompanyDeleted", msg.Subject)
+	assert.Equal(t, "2", msg.Header.Get(nats.MsgIdHdr))
+
+	var env Envelope
+	require.NoError(t, json.Unmarshal(msg.Data, &env))
+	assert.Equal(t, newEnvelope(testEvents[1]), env)
+}
+
+func TestNATS_FailedFlushDeliversNothing(t *testing.T) {
+	conn := &fakeNATSConn{flushErr: errors.New("connection closed")}
+	n, err := NewNATS(conn, "companies.events").Deliver(context.Background(), testEvents)
+	require.Error(t, err)
+	assert.Equal(t, 0, n)
+}
@@ -0,0 +1,29 @@
+// Package sink holds the outbox sinks other than Kafka: HTTP webhooks, NATS
+// and a JSON lines file. They all carry the same JSON envelope.
+package sink
+
+import (
+	"encoding/json"
+
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+)
+
+// Envelope is the JSON document delivered for an outbox event. Payload is the
+// event payload as stored in the outbox.
+type Envelope struct {
+	ID          int64           `json:"id"`
+	EventType   string          `json:"event_type"`
+	AggregateID string          `json:"aggregate_id"`
+	CreatedAt   int64           `json:"created_at"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+func newEnvelope(e postgres.OutboxEvent) Envelope {
+	return Envelope{
+		ID:          e.ID,
+		EventType:   e.EventType,
+		AggregateID: e.AggregateID,
+		CreatedAt:   e.CreatedAt,
+		Payload:     e.Payload,
+	}
+}
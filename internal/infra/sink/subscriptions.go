@@ -0,0 +1,212 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/domain/webhook"
+	"github.com/dubininme/xm-assessment/internal/infra/outbox"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// HeaderDeliveryID identifies a subscription delivery; a redelivery gets a
+// new one while X-Webhook-Id stays the outbox ID.
+const HeaderDeliveryID = "X-Webhook-Delivery"
+
+// Subscriptions queues a delivery for every webhook subscription that wants
+// an event. The outbox processor calls Deliver inside its transaction, so the
+// deliveries are queued exactly when the events are marked as handed over;
+// the Dispatcher sends them.
+type Subscriptions struct {
+	repo *postgres.WebhookRepo
+}
+
+var _ outbox.Sink = (*Subscriptions)(nil)
+
+func NewSubscriptions(repo *postgres.WebhookRepo) *Subscriptions {
+	return &Subscriptions{repo: repo}
+}
+
+func (s *Subscriptions) Name() string { return "subscriptions" }
+
+func (s *Subscriptions) Deliver(ctx context.Context, events []postgres.OutboxEvent) (int, error) {
+	subs, err := s.repo.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+
+	var subscriptionIDs []uuid.UUID
+	var outboxIDs []int64
+	for _, e := range events {
+		for _, sub := range subs {
+			if sub.Wants(e.EventType) {
+				subscriptionIDs = append(subscriptionIDs, sub.ID)
+				outboxIDs = append(outboxIDs, e.ID)
+			}
+		}
+	}
+
+	if err := s.repo.CreateDeliveries(ctx, subscriptionIDs, outboxIDs); err != nil {
+		return 0, fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+	return len(events), nil
+}
+
+func (s *Subscriptions) Close() error { return nil }
+
+type DispatcherConfig struct {
+	BatchSize int
+	Interval  time.Duration
+	// Timeout bounds a single attempt
+	Timeout     time.Duration
+	MaxAttempts int
+	// Backoff is the delay before the second attempt; it doubles after every
+	// failure up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Dispatcher sends the queued subscription deliveries. Every attempt is
+// recorded on the delivery; a failed one is retried with exponential backoff
+// until MaxAttempts, after which the delivery is marked failed and can only
+// be redelivered through the API. Deliveries to one subscription may arrive
+// out of order.
+type Dispatcher struct {
+	repo      *postgres.WebhookRepo
+	txManager *postgres.TxManager
+	client    *http.Client
+	cfg       DispatcherConfig
+	now       func() time.Time
+}
+
+func NewDispatcher(repo *postgres.WebhookRepo, txManager *postgres.TxManager, client *http.Client, cfg DispatcherConfig) *Dispatcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	cfg.MaxAttempts = max(cfg.MaxAttempts, 1)
+	return &Dispatcher{repo: repo, txManager: txManager, client: client, cfg: cfg, now: time.Now}
+}
+
+func (d *Dispatcher) Start(ctx context.Context) error {
+	log := logger.FromContext(ctx)
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+
+	log.Info("webhook dispatcher started", "batch_size", d.cfg.BatchSize, "interval", d.cfg.Interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("webhook dispatcher stopping")
+			return nil
+		case <-ticker.C:
+			if _, err := d.DispatchBatch(ctx); err != nil {
+				log.Error("error dispatching webhook deliveries", "error", err)
+			}
+		}
+	}
+}
+
+// DispatchBatch makes one attempt at up to BatchSize due deliveries, in
+// parallel, and returns how many it attempted.
+func (d *Dispatcher) DispatchBatch(ctx context.Context) (int, error) {
+	var attempted int
+	err := d.txManager.Do(ctx, func(txCtx context.Context) error {
+		due, err := d.repo.ClaimDueDeliveries(txCtx, d.now(), d.cfg.BatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to claim webhook deliveries: %w", err)
+		}
+		attempted = len(due)
+
+		var wg sync.WaitGroup
+		for i := range due {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d.attempt(ctx, &due[i])
+			}()
+		}
+		wg.Wait()
+
+		var errs []error
+		for _, dd := range due {
+			errs = append(errs, d.repo.RecordAttempt(txCtx, dd.Delivery))
+		}
+		return errors.Join(errs...)
+	})
+	return attempted, err
+}
+
+// attempt sends dd once and updates it with the outcome.
+func (d *Dispatcher) attempt(ctx context.Context, dd *postgres.DueDelivery) {
+	status, err := d.send(ctx, dd)
+
+	now := d.now()
+	dd.Attempts++
+	dd.ResponseStatus = status
+	dd.Error = ""
+
+	if err == nil {
+		dd.Status = webhook.DeliverySucceeded
+		dd.DeliveredAt = now
+		return
+	}
+
+	dd.Error = err.Error()
+	if dd.Attempts >= d.cfg.MaxAttempts {
+		dd.Status = webhook.DeliveryFailed
+		logger.FromContext(ctx).Warn("webhook delivery failed for good",
+			"delivery_id", dd.ID, "subscription_id", dd.SubscriptionID, "attempts", dd.Attempts, "error", err)
+		return
+	}
+	dd.NextAttemptAt = now.Add(d.backoff(dd.Attempts))
+}
+
+// backoff returns the delay after the given number of failed attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := float64(d.cfg.Backoff) * math.Pow(2, float64(attempts-1))
+	if d.cfg.MaxBackoff > 0 && delay > float64(d.cfg.MaxBackoff) {
+		return d.cfg.MaxBackoff
+	}
+	return time.Duration(delay)
+}
+
+func (d *Dispatcher) send(ctx context.Context, dd *postgres.DueDelivery) (int, error) {
+	if d.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.cfg.Timeout)
+		defer cancel()
+	}
+
+	body, err := json.Marshal(newEnvelope(dd.Event))
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := newSignedRequest(ctx, dd.URL, dd.Secret, dd.Event, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set(HeaderDeliveryID, fmt.Sprint(dd.ID))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,175 @@
+//go:build integration
+
+package sink_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/config"
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/domain/webhook"
+	"github.com/dubininme/xm-assessment/internal/infra/outbox"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres/pgtest"
+	"github.com/dubininme/xm-assessment/internal/infra/sink"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testDbConfig config.DbConfig
+
+func TestMain(m *testing.M) {
+	cfg, stop, err := pgtest.Start(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to start test database:", err)
+		os.Exit(1)
+	}
+	testDbConfig = cfg
+
+	code := m.Run()
+	if err := stop(); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to stop test database:", err)
+	}
+	os.Exit(code)
+}
+
+// loopback lets subscriptions target the httptest servers of the endpoints.
+var loopback = webhook.TargetPolicy{Allowed: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}}
+
+// endpoint is a partner receiver answering every request with status.
+type endpoint struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests = append(e.requests, r)
+	e.bodies = append(e.bodies, body)
+	w.WriteHeader(e.status)
+}
+
+func (e *endpoint) setStatus(status int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.status = status
+}
+
+func (e *endpoint) url(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(e)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestSubscriptions_DeliverRetryAndRedeliver(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := postgres.Connect(ctx, testDbConfig)
+	require.NoError(t, err)
+	t.Cleanup(db.Close)
+	require.NoError(t, pgtest.Reset(ctx, db))
+
+	txManager := postgres.NewTxManager(db, testDbConfig.DBTxMaxRetries)
+	outboxRepo := postgres.NewOutboxRepo(db)
+	webhookRepo := postgres.NewWebhookRepo(db)
+	service := webhook.NewService(webhookRepo, txManager, loopback)
+
+	healthy := &endpoint{status: http.StatusNoContent}
+	broken := &endpoint{status: http.StatusInternalServerError}
+	createdOnly, err := service.Create(ctx, webhook.CreateParams{
+		URL:        healthy.url(t),
+		EventTypes: []string{company.EventCompanyCreated},
+		Secret:     "healthy-secret-0001",
+	})
+	require.NoError(t, err)
+	everything, err := service.Create(ctx, webhook.CreateParams{URL: broken.url(t), Secret: "broken-secret-0001"})
+	require.NoError(t, err)
+
+	c, err := company.NewCompany(uuid.New(), "hooked", "", 5, company.CorporationsType.String())
+	require.NoError(t, err)
+	require.NoError(t, outboxRepo.Publish(ctx, company.NewCompanyCreatedEvent(c)))
+	require.NoError(t, outboxRepo.Publish(ctx, company.NewCompanyDeletedEvent(c)))
+
+	processor := outbox.NewProcessor(outboxRepo, []outbox.Sink{sink.NewSubscriptions(webhookRepo)},
+		txManager, 100, time.Second, outbox.Partitioning{})
+	require.NoError(t, processor.ProcessBatch(ctx))
+
+	now := time.Unix(1700000000, 0)
+	dispatcher := sink.NewDispatcher(webhookRepo, txManager, sink.NewDeliveryClient(loopback), sink.DispatcherConfig{
+		BatchSize:   10,
+		Timeout:     time.Second,
+		MaxAttempts: 2,
+		Backoff:     time.Minute,
+	})
+	dispatcher.SetNow(func() time.Time { return now })
+
+	// The filtered subscription gets the created event only
+	attempted, err := dispatcher.DispatchBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempted)
+
+	require.Len(t, healthy.requests, 1)
+	req, body := healthy.requests[0], healthy.bodies[0]
+	assert.Equal(t, company.EventCompanyCreated, req.Header.Get(sink.HeaderEventName))
+	assert.NotEmpty(t, req.Header.Get(sink.HeaderDeliveryID))
+	assert.Equal(t, sink.Sign("healthy-secret-0001", req.Header.Get(sink.HeaderTimestamp), body), req.Header.Get(sink.HeaderSignature))
+
+	// Failed deliveries wait for their backoff, then run out of attempts
+	attempted, err = dispatcher.DispatchBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, attempted)
+
+	now = now.Add(time.Minute)
+	attempted, err = dispatcher.DispatchBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempted)
+	assert.Len(t, broken.requests, 4)
+
+	deliveries, err := service.Deliveries(ctx, everything.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	for _, d := range deliveries {
+		assert.Equal(t, webhook.DeliveryFailed, d.Status)
+		assert.Equal(t, 2, d.Attempts)
+		assert.Equal(t, http.StatusInternalServerError, d.ResponseStatus)
+	}
+
+	delivered, err := service.Deliveries(ctx, createdOnly.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, delivered, 1)
+	assert.Equal(t, webhook.DeliverySucceeded, delivered[0].Status)
+
+	// A redelivery is a new delivery of the same event
+	broken.setStatus(http.StatusOK)
+	redelivery, err := service.Redeliver(ctx, everything.ID, deliveries[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, webhook.DeliveryPending, redelivery.Status)
+	assert.Equal(t, deliveries[0].OutboxID, redelivery.OutboxID)
+
+	now = time.Now()
+	attempted, err = dispatcher.DispatchBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+
+	deliveries, err = service.Deliveries(ctx, everything.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 3)
+	assert.Equal(t, redelivery.ID, deliveries[0].ID, "newest first")
+	assert.Equal(t, webhook.DeliverySucceeded, deliveries[0].Status)
+}
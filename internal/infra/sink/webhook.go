@@ -0,0 +1,224 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/infra/outbox"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/pkg/logger"
+)
+
+// Headers set on every webhook request.
+const (
+	HeaderEventID   = "X-Webhook-Id"
+	HeaderEventName = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+type WebhookConfig struct {
+	URL string
+	// Secret signs the requests; empty sends them unsigned
+	Secret string
+	// Timeout bounds a single attempt
+	Timeout     time.Duration
+	MaxAttempts int
+	// Backoff is the delay before the second attempt; it doubles after every
+	// failure up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Webhook POSTs every event as an Envelope to a URL, one request per event.
+//
+// An event gets at most one attempt per run of the processor, so its
+// transaction never waits out a backoff. After a network error, 429 or 5xx
+// the event is retried on a later run once its backoff has passed, and the
+// events after it wait to keep the order. After MaxAttempts, or right away on
+// any other non-2xx response, the event is given up with an
+// outbox.PermanentError and the processor sets it aside.
+//
+// The attempts are kept in WebhookRetries within the transaction of the
+// processor, so they survive a restart and follow the partition to whichever
+// replica takes it over.
+type Webhook struct {
+	cfg     WebhookConfig
+	client  *http.Client
+	retries WebhookRetries
+	now     func() time.Time
+}
+
+// WebhookRetries stores the attempts at the events a sink is still retrying;
+// postgres.OutboxRepo implements it on outbox_deliveries.
+type WebhookRetries interface {
+	GetRetries(ctx context.Context, sink string, ids []int64) (map[int64]postgres.OutboxRetry, error)
+	ScheduleRetry(ctx context.Context, sink string, id int64, retry postgres.OutboxRetry) error
+	ClearRetry(ctx context.Context, sink string, id int64) error
+}
+
+var _ outbox.Sink = (*Webhook)(nil)
+
+func NewWebhook(cfg WebhookConfig, client *http.Client, retries WebhookRetries) *Webhook {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	cfg.MaxAttempts = max(cfg.MaxAttempts, 1)
+	return &Webhook{cfg: cfg, client: client, retries: retries, now: time.Now}
+}
+
+func (w *Webhook) Name() string { return "webhook" }
+
+func (w *Webhook) Deliver(ctx context.Context, events []postgres.OutboxEvent) (int, error) {
+	ids := make([]int64, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	retries, err := w.retries.GetRetries(ctx, w.Name(), ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get webhook retries: %w", err)
+	}
+
+	for i, e := range events {
+		r := retries[e.ID]
+		if w.now().Before(r.NextAttemptAt) {
+			return i, nil
+		}
+		if err := w.attempt(ctx, e, r); err != nil {
+			return i, err
+		}
+	}
+	return len(events), nil
+}
+
+// attempt sends e once after the attempts of r, and schedules its retry when
+// it fails.
+func (w *Webhook) attempt(ctx context.Context, e postgres.OutboxEvent, r postgres.OutboxRetry) error {
+	body, err := json.Marshal(newEnvelope(e))
+	if err != nil {
+		return &outbox.PermanentError{Err: fmt.Errorf("failed to marshal outbox event %d: %w", e.ID, err)}
+	}
+
+	retryAfter, err := w.post(ctx, e, body)
+	if err == nil {
+		if r.Attempts == 0 {
+			return nil
+		}
+		if err := w.retries.ClearRetry(ctx, w.Name(), e.ID); err != nil {
+			return fmt.Errorf("failed to clear retry of outbox event %d: %w", e.ID, err)
+		}
+		return nil
+	}
+	err = fmt.Errorf("failed to deliver outbox event %d: %w", e.ID, err)
+	if ctx.Err() != nil {
+		// Shutting down is not the receiver's fault
+		return err
+	}
+
+	r.Attempts++
+
+	var permanent *permanentError
+	if errors.As(err, &permanent) || r.Attempts >= w.cfg.MaxAttempts {
+		return &outbox.PermanentError{Err: fmt.Errorf("%w (attempt %d)", err, r.Attempts)}
+	}
+
+	wait := max(w.backoff(r.Attempts), retryAfter)
+	r.NextAttemptAt = w.now().Add(wait)
+	if err := w.retries.ScheduleRetry(ctx, w.Name(), e.ID, r); err != nil {
+		return fmt.Errorf("failed to schedule retry of outbox event %d: %w", e.ID, err)
+	}
+	logger.FromContext(ctx).Warn("webhook delivery failed, retrying",
+		"outbox_id", e.ID, "attempt", r.Attempts, "retry_in", wait, "error", err)
+	return err
+}
+
+// backoff returns the delay after the given number of failed attempts.
+func (w *Webhook) backoff(attempts int) time.Duration {
+	delay := float64(w.cfg.Backoff) * math.Pow(2, float64(attempts-1))
+	if w.cfg.MaxBackoff > 0 && delay > float64(w.cfg.MaxBackoff) {
+		return w.cfg.MaxBackoff
+	}
+	return time.Duration(delay)
+}
+
+// permanentError is a response that retrying will not fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// post makes one attempt and returns the delay the receiver asked for, if any.
+func (w *Webhook) post(ctx context.Context, e postgres.OutboxEvent, body []byte) (time.Duration, error) {
+	if w.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.cfg.Timeout)
+		defer cancel()
+	}
+
+	req, err := newSignedRequest(ctx, w.cfg.URL, w.cfg.Secret, e, body)
+	if err != nil {
+		return 0, &permanentError{err: err}
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return 0, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return time.Duration(seconds) * time.Second, fmt.Errorf("webhook responded with %d", resp.StatusCode)
+	default:
+		return 0, &permanentError{err: fmt.Errorf("webhook responded with %d", resp.StatusCode)}
+	}
+}
+
+func (w *Webhook) Close() error { return nil }
+
+// newSignedRequest builds the POST of body, the envelope of e, and signs it
+// when secret is set.
+func newSignedRequest(ctx context.Context, url, secret string, e postgres.OutboxEvent, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventID, strconv.FormatInt(e.ID, 10))
+	req.Header.Set(HeaderEventName, e.EventType)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	if secret != "" {
+		req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+	}
+	return req, nil
+}
+
+// Sign returns the signature header value for a request body: the hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with secret. Receivers recompute
+// it and reject stale timestamps to stop replays.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,70 @@
+//go:build integration
+
+package sink_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/infra/outbox"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres/pgtest"
+	"github.com/dubininme/xm-assessment/internal/infra/sink"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_RetriesSurviveARestart(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := postgres.Connect(ctx, testDbConfig)
+	require.NoError(t, err)
+	t.Cleanup(db.Close)
+	require.NoError(t, pgtest.Reset(ctx, db))
+
+	txManager := postgres.NewTxManager(db, testDbConfig.DBTxMaxRetries)
+	outboxRepo := postgres.NewOutboxRepo(db)
+
+	c, err := company.NewCompany(uuid.New(), "retried", "", 5, company.CorporationsType.String())
+	require.NoError(t, err)
+	require.NoError(t, outboxRepo.Publish(ctx, company.NewCompanyCreatedEvent(c)))
+
+	receiver := &endpoint{status: http.StatusServiceUnavailable}
+	cfg := sink.WebhookConfig{URL: receiver.url(t), MaxAttempts: 5, Backoff: 2 * time.Second}
+	start := func() *outbox.Processor {
+		return outbox.NewProcessor(outboxRepo, []outbox.Sink{sink.NewWebhook(cfg, nil, outboxRepo)},
+			txManager, 100, time.Second, outbox.Partitioning{})
+	}
+
+	require.Error(t, start().ProcessBatch(ctx))
+	require.Len(t, receiver.requests, 1)
+
+	var attempts int
+	var deliveredAt *int64
+	require.NoError(t, db.QueryRow(ctx, `SELECT attempts, delivered_at FROM outbox_deliveries WHERE sink = 'webhook'`).
+		Scan(&attempts, &deliveredAt))
+	assert.Equal(t, 1, attempts)
+	assert.Nil(t, deliveredAt, "a retry is not a delivery")
+
+	// A restarted processor keeps to the backoff of the attempt before
+	receiver.setStatus(http.StatusNoContent)
+	restarted := start()
+	require.NoError(t, restarted.ProcessBatch(ctx))
+	assert.Len(t, receiver.requests, 1)
+
+	require.Eventually(t, func() bool {
+		require.NoError(t, restarted.ProcessBatch(ctx))
+		pending, err := outboxRepo.GetUnprocessed(ctx, 10)
+		require.NoError(t, err)
+		return len(pending) == 0
+	}, 5*time.Second, 100*time.Millisecond)
+	assert.Len(t, receiver.requests, 2)
+
+	var retries int
+	require.NoError(t, db.QueryRow(ctx, `SELECT count(*) FROM outbox_deliveries`).Scan(&retries))
+	assert.Zero(t, retries, "the retry is cleared once delivered")
+}
@@ -0,0 +1,247 @@
+//go:build unit
+
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dubininme/xm-assessment/internal/infra/outbox"
+	"github.com/dubininme/xm-assessment/internal/infra/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testEvents = []postgres.OutboxEvent{
+	{ID: 1, EventType: "CompanyCreated", AggregateID: "5a64d5ec-e77a-4afe-9d89-8790c85ede68", Payload: json.RawMessage(`{"name":"acme"}`), CreatedAt: 1700000000},
+	{ID: 2, EventType: "CompanyDeleted", AggregateID: "5a64d5ec-e77a-4afe-9d89-8790c85ede68", Payload: json.RawMessage(`{"id":"5a64d5ec-e77a-4afe-9d89-8790c85ede68"}`), CreatedAt: 1700000001},
+}
+
+// receiver records webhook requests and answers with the queued statuses,
+// then with 204.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+
+	status := http.StatusNoContent
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+// memoryRetries keeps WebhookRetries in a map, as outbox_deliveries does for
+// one sink.
+type memoryRetries map[int64]postgres.OutboxRetry
+
+func (m memoryRetries) GetRetries(_ context.Context, _ string, ids []int64) (map[int64]postgres.OutboxRetry, error) {
+	retries := make(map[int64]postgres.OutboxRetry)
+	for _, id := range ids {
+		if r, ok := m[id]; ok {
+			retries[id] = r
+		}
+	}
+	return retries, nil
+}
+
+func (m memoryRetries) ScheduleRetry(_ context.Context, _ string, id int64, retry postgres.OutboxRetry) error {
+	m[id] = retry
+	return nil
+}
+
+func (m memoryRetries) ClearRetry(_ context.Context, _ string, id int64) error {
+	delete(m, id)
+	return nil
+}
+
+func newTestWebhook(t *testing.T, rc *receiver, retries memoryRetries) *Webhook {
+	t.Helper()
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+
+	return NewWebhook(WebhookConfig{
+		URL:         srv.URL,
+		Secret:      "s3cret",
+		Timeout:     time.Second,
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+	}, srv.Client(), retries)
+}
+
+func TestWebhook_DeliversSignedEnvelopes(t *testing.T) {
+	rc := &receiver{}
+	w := newTestWebhook(t, rc, memoryRetries{})
+
+	n, err := w.Deliver(context.Background(), testEvents)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	require.Len(t, rc.requests, 2)
+	req, body := rc.requests[0], rc.bodies[0]
+	assert.Equal(t, "1", req.Header.Get(HeaderEventID))
+	assert.Equal(t, "CompanyCreated", req.Header.Get(HeaderEventName))
+	assert.Equal(t, Sign("s3cret", req.Header.Get(HeaderTimestamp), body), req.Header.Get(HeaderSignature))
+
+	var env Envelope
+	require.NoError(t, json.Unmarshal(body, &env))
+	assert.Equal(t, newEnvelope(testEvents[0]), env)
+}
+
+// clock is a settable time for the backoff of a Webhook.
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time { return c.t }
+
+func withClock(w *Webhook) *clock {
+	c := &clock{t: time.Now()}
+	w.now = c.now
+	return c
+}
+
+func TestWebhook_RetriesTransientFailuresOnLaterRuns(t *testing.T) {
+	rc := &receiver{statuses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}}
+	w := newTestWebhook(t, rc, memoryRetries{})
+	c := withClock(w)
+	ctx := context.Background()
+
+	n, err := w.Deliver(ctx, testEvents)
+	require.Error(t, err)
+	var permanent *outbox.PermanentError
+	assert.False(t, errors.As(err, &permanent))
+	assert.Zero(t, n)
+	assert.Len(t, rc.requests, 1, "one attempt per run")
+
+	// Until the backoff has passed nothing is sent, and the next event waits
+	n, err = w.Deliver(ctx, testEvents)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Len(t, rc.requests, 1)
+
+	c.t = c.t.Add(time.Millisecond)
+	n, err = w.Deliver(ctx, testEvents)
+	require.Error(t, err)
+	assert.Zero(t, n)
+	assert.Len(t, rc.requests, 2)
+
+	// The backoff doubles
+	c.t = c.t.Add(time.Millisecond)
+	n, _ = w.Deliver(ctx, testEvents)
+	assert.Zero(t, n)
+	c.t = c.t.Add(time.Millisecond)
+	n, err = w.Deliver(ctx, testEvents)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Len(t, rc.requests, 4)
+}
+
+func TestWebhook_KeepsRetriesAcrossRestarts(t *testing.T) {
+	rc := &receiver{statuses: []int{http.StatusServiceUnavailable}}
+	retries := memoryRetries{}
+	w := newTestWebhook(t, rc, retries)
+	c := withClock(w)
+	ctx := context.Background()
+
+	_, err := w.Deliver(ctx, testEvents)
+	require.Error(t, err)
+	require.Contains(t, retries, testEvents[0].ID)
+	assert.Equal(t, 1, retries[testEvents[0].ID].Attempts)
+
+	// A new sink, e.g. on the replica that took the partition over, keeps
+	// to the backoff and then clears the retry
+	restarted := NewWebhook(w.cfg, w.client, retries)
+	restarted.now = c.now
+	n, err := restarted.Deliver(ctx, testEvents)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Len(t, rc.requests, 1)
+
+	c.t = c.t.Add(time.Millisecond)
+	n, err = restarted.Deliver(ctx, testEvents)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Empty(t, retries)
+}
+
+func TestWebhook_HonoursRetryAfter(t *testing.T) {
+	rc := &receiver{statuses: []int{http.StatusTooManyRequests}}
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Retry-After", "10")
+		rc.ServeHTTP(rw, r)
+	}))
+	t.Cleanup(srv.Close)
+	w := NewWebhook(WebhookConfig{URL: srv.URL, MaxAttempts: 3, Backoff: time.Millisecond}, srv.Client(), memoryRetries{})
+	c := withClock(w)
+
+	_, err := w.Deliver(context.Background(), testEvents[:1])
+	require.Error(t, err)
+
+	c.t = c.t.Add(9 * time.Second)
+	n, err := w.Deliver(context.Background(), testEvents[:1])
+	require.NoError(t, err)
+	assert.Zero(t, n, "still waiting for Retry-After")
+
+	c.t = c.t.Add(time.Second)
+	n, err = w.Deliver(context.Background(), testEvents[:1])
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestWebhook_GivesUp(t *testing.T) {
+	t.Run("after max attempts", func(t *testing.T) {
+		rc := &receiver{statuses: []int{http.StatusNoContent, 500, 500, 500}}
+		w := newTestWebhook(t, rc, memoryRetries{})
+		c := withClock(w)
+
+		n, err := w.Deliver(context.Background(), testEvents)
+		require.Error(t, err)
+		assert.Equal(t, 1, n, "the first event was delivered")
+
+		var permanent *outbox.PermanentError
+		for attempt := 2; attempt <= 3; attempt++ {
+			c.t = c.t.Add(time.Minute)
+			n, err = w.Deliver(context.Background(), testEvents[1:])
+			assert.Zero(t, n)
+			require.Error(t, err)
+			assert.Equal(t, attempt == 3, errors.As(err, &permanent), "attempt %d", attempt)
+		}
+		assert.Len(t, rc.requests, 4)
+	})
+
+	t.Run("on a client error", func(t *testing.T) {
+		rc := &receiver{statuses: []int{http.StatusBadRequest}}
+		w := newTestWebhook(t, rc, memoryRetries{})
+
+		n, err := w.Deliver(context.Background(), testEvents)
+		var permanent *outbox.PermanentError
+		require.ErrorAs(t, err, &permanent)
+		assert.Equal(t, 0, n)
+		assert.Len(t, rc.requests, 1, "not retried")
+	})
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{}' | openssl dgst -sha256 -hmac key
+	assert.Equal(t,
+		"sha256=9d713ed406bb7076d4123f0dc2c39d2df5c654ed4b0cd56b52c8b4c940bd63ae",
+		Sign("key", "1700000000", []byte("{}")))
+}
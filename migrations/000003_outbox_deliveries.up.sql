@@ -0,0 +1,6 @@
+CREATE TABLE outbox_deliveries (
+    outbox_id BIGINT NOT NULL REFERENCES outbox(id) ON DELETE CASCADE,
+    sink VARCHAR(64) NOT NULL,
+    delivered_at BIGINT NOT NULL,
+    PRIMARY KEY (outbox_id, sink)
+);
@@ -0,0 +1,12 @@
+-- Tell listeners about every new outbox row; the notification is delivered
+-- when the inserting transaction commits.
+CREATE FUNCTION notify_outbox_insert() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('outbox_events', NEW.id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER outbox_notify
+    AFTER INSERT ON outbox
+    FOR EACH ROW EXECUTE FUNCTION notify_outbox_insert();
@@ -0,0 +1 @@
+ALTER TABLE companies DROP COLUMN IF EXISTS updated_at;
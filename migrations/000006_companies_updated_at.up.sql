@@ -0,0 +1,3 @@
+-- When each company was last written; the HTTP API serves it as
+-- Last-Modified. Existing rows get the time of the migration.
+ALTER TABLE companies ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS companies_notify ON companies;
+DROP FUNCTION IF EXISTS notify_company_change();
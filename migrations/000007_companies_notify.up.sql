@@ -0,0 +1,17 @@
+-- Tell listeners which company was created, changed or deleted, so replicas
+-- can drop it from their caches; the notification is delivered when the
+-- writing transaction commits.
+CREATE FUNCTION notify_company_change() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('company_changes', OLD.id::text);
+        RETURN OLD;
+    END IF;
+    PERFORM pg_notify('company_changes', NEW.id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER companies_notify
+    AFTER INSERT OR UPDATE OR DELETE ON companies
+    FOR EACH ROW EXECUTE FUNCTION notify_company_change();
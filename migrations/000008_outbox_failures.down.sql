@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_outbox_deliveries_failed;
+ALTER TABLE outbox_deliveries DROP COLUMN IF EXISTS error;
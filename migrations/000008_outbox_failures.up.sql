@@ -0,0 +1,6 @@
+-- Why a sink gave up on an event it can never deliver, e.g. a payload its
+-- schema rejects; NULL for an event that was delivered. Either way the sink
+-- is done with the event.
+ALTER TABLE outbox_deliveries ADD COLUMN error TEXT;
+
+CREATE INDEX idx_outbox_deliveries_failed ON outbox_deliveries(sink, outbox_id) WHERE error IS NOT NULL;
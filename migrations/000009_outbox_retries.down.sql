@@ -0,0 +1,4 @@
+DELETE FROM outbox_deliveries WHERE delivered_at IS NULL;
+ALTER TABLE outbox_deliveries DROP COLUMN IF EXISTS next_attempt_at;
+ALTER TABLE outbox_deliveries DROP COLUMN IF EXISTS attempts;
+ALTER TABLE outbox_deliveries ALTER COLUMN delivered_at SET NOT NULL;
@@ -0,0 +1,6 @@
+-- A sink that retries an event on later runs, like the webhook sink, keeps
+-- the attempts it made here; delivered_at stays NULL until it is done with
+-- the event. next_attempt_at is in unix seconds, rounded up.
+ALTER TABLE outbox_deliveries ALTER COLUMN delivered_at DROP NOT NULL;
+ALTER TABLE outbox_deliveries ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE outbox_deliveries ADD COLUMN next_attempt_at BIGINT;
@@ -0,0 +1,8 @@
+// Package migrations embeds the SQL schema migrations so the binary can apply
+// them itself (see the `migrate` subcommand and MIGRATE_ON_START).
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
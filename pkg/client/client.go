@@ -0,0 +1,113 @@
+// Package client is a Go client for the company API. It wraps the client
+// generated from api/openapi.yaml with what every consumer would otherwise
+// write itself: tokens fetched from /api/v1/auth/token and refreshed before
+// they expire, retries with backoff on 429 and 5xx, idempotent creates, and
+// errors that can be matched with errors.Is.
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+)
+
+// RetryPolicy controls how requests that failed with 429, a 5xx or a network
+// error are retried. Only requests that are safe to repeat are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the number of tries, the first included; 1 turns
+	// retries off
+	MaxAttempts int
+	// BaseDelay is the longest wait before the first retry; it doubles with
+	// every attempt up to MaxDelay, and a random part of it is waited
+	BaseDelay time.Duration
+	// MaxDelay caps the wait, a Retry-After from the server included
+	MaxDelay time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+type options struct {
+	httpClient *http.Client
+	userID     string
+	password   string
+	token      string
+	retry      RetryPolicy
+}
+
+type Option func(*options)
+
+// WithHTTPClient sends requests with c instead of http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) { o.httpClient = c }
+}
+
+// WithCredentials has the client fetch its own tokens, and fetch a new one
+// when the current one is about to expire or is rejected.
+func WithCredentials(userID, password string) Option {
+	return func(o *options) {
+		o.userID = userID
+		o.password = password
+	}
+}
+
+// WithToken sends a token obtained elsewhere. It is not refreshed.
+func WithToken(token string) Option {
+	return func(o *options) { o.token = token }
+}
+
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(o *options) { o.retry = p }
+}
+
+// Client calls the company API. It is safe for concurrent use.
+type Client struct {
+	api *oapi.ClientWithResponses
+}
+
+// New returns a client of the service at baseURL, e.g. "http://localhost:8080".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	o := options{httpClient: http.DefaultClient, retry: DefaultRetryPolicy}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.retry.MaxAttempts < 1 {
+		return nil, fmt.Errorf("retry policy needs at least 1 attempt, got %d", o.retry.MaxAttempts)
+	}
+	if o.token != "" && o.userID != "" {
+		return nil, fmt.Errorf("a client takes either a token or credentials")
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	d := &doer{client: o.httpClient, retry: o.retry}
+	switch {
+	case o.token != "":
+		d.tokens = &tokenSource{token: o.token}
+	case o.userID != "":
+		// Tokens are fetched through a doer of their own, which does not
+		// need a token and so cannot wait on itself
+		auth, err := oapi.NewClientWithResponses(baseURL, oapi.WithHTTPClient(&doer{client: o.httpClient, retry: o.retry}))
+		if err != nil {
+			return nil, err
+		}
+		d.tokens = &tokenSource{api: auth, userID: o.userID, password: o.password}
+	}
+
+	api, err := oapi.NewClientWithResponses(baseURL, oapi.WithHTTPClient(d))
+	if err != nil {
+		return nil, err
+	}
+	return &Client{api: api}, nil
+}
+
+// API returns the generated client, for the operations this package has no
+// method for. Its requests get the same tokens and retries.
+func (c *Client) API() *oapi.ClientWithResponses {
+	return c.api
+}
@@ -0,0 +1,280 @@
+//go:build unit
+
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dubininme/xm-assessment/api"
+	deliveryHttp "github.com/dubininme/xm-assessment/internal/delivery/http"
+	"github.com/dubininme/xm-assessment/internal/delivery/http/handler"
+	"github.com/dubininme/xm-assessment/internal/delivery/http/middleware"
+	"github.com/dubininme/xm-assessment/internal/domain/company"
+	"github.com/dubininme/xm-assessment/internal/infra/auth"
+	"github.com/dubininme/xm-assessment/internal/infra/memory"
+	"github.com/dubininme/xm-assessment/pkg/client"
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const password = "demo-password-123"
+
+var fastRetries = client.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+// server runs the real router over memory storage; wrap, if set, sits in
+// front of it.
+type server struct {
+	*httptest.Server
+	jwt    *auth.JWTService
+	tokens atomic.Int32
+}
+
+func newServer(t *testing.T, wrap func(http.Handler) http.Handler) *server {
+	t.Helper()
+	store := memory.NewStore()
+	service := company.NewCompanyService(memory.NewCompanyRepo(store), memory.NewEventsPublisher(store), memory.NewTxManager(store))
+	jwtService := auth.NewJWTService("test-secret")
+	validator, err := middleware.NewValidator(api.Spec, middleware.ValidatorOptions{MaxBodyBytes: 1 << 20, ValidateResponses: true})
+	require.NoError(t, err)
+
+	router := deliveryHttp.NewRouter(handler.NewCompanyHandler(service, ""), handler.NewHealthHandler(), handler.NewAuthHandler(jwtService),
+		nil, nil, nil, handler.NewTransferHandler(t.Context(), service, 1<<20, 64<<10, time.Minute),
+		nil, middleware.NewAuthMiddleware(jwtService), validator)
+
+	s := &server{jwt: jwtService}
+	var h http.Handler = router
+	if wrap != nil {
+		h = wrap(h)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/auth/token" {
+			s.tokens.Add(1)
+		}
+		h.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newClient(t *testing.T, s *server, opts ...client.Option) *client.Client {
+	t.Helper()
+	opts = append([]client.Option{client.WithCredentials("tester", password), client.WithRetryPolicy(fastRetries)}, opts...)
+	c, err := client.New(s.URL, opts...)
+	require.NoError(t, err)
+	return c
+}
+
+func acme() oapi.CreateCompanyRequest {
+	return oapi.CreateCompanyRequest{Name: "Acme", EmployeesCount: 10, Registered: true, Type: oapi.Corporations}
+}
+
+func TestClient_Companies(t *testing.T) {
+	s := newServer(t, nil)
+	c := newClient(t, s)
+	ctx := t.Context()
+
+	created, err := c.CreateCompany(ctx, acme())
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, created.Id)
+	assert.NotEmpty(t, created.ETag)
+
+	got, err := c.GetCompany(ctx, created.Id)
+	require.NoError(t, err)
+	assert.Equal(t, created.Company, got.Company)
+
+	renamed := acme()
+	renamed.Name = "Acme Ltd"
+	replaced, err := c.ReplaceCompany(ctx, created.Id, renamed, client.WithIfMatch(got.ETag))
+	require.NoError(t, err)
+	assert.Equal(t, "Acme Ltd", replaced.Name)
+
+	_, err = c.ReplaceCompany(ctx, created.Id, acme(), client.WithIfMatch(got.ETag))
+	require.ErrorIs(t, err, client.ErrPreconditionFailed, "the company changed since it was read")
+
+	employees := 42
+	updated, err := c.UpdateCompany(ctx, created.Id, oapi.UpdateCompanyRequest{EmployeesCount: &employees})
+	require.NoError(t, err)
+	assert.Equal(t, 42, updated.EmployeesCount)
+
+	require.NoError(t, c.DeleteCompany(ctx, created.Id))
+	_, err = c.GetCompany(ctx, created.Id)
+	require.ErrorIs(t, err, client.ErrNotFound)
+
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, oapi.ErrorCodeNotFound, apiErr.Code)
+
+	assert.EqualValues(t, 1, s.tokens.Load(), "the token is fetched once and reused")
+}
+
+func TestClient_TypedErrors(t *testing.T) {
+	s := newServer(t, nil)
+	c := newClient(t, s)
+
+	invalid := acme()
+	invalid.Type = "Guild"
+	_, err := c.CreateCompany(t.Context(), invalid)
+	require.ErrorIs(t, err, client.ErrBadRequest)
+
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.NotEmpty(t, apiErr.Details, "validation problems are listed")
+
+	_, err = c.CreateCompany(t.Context(), acme())
+	require.NoError(t, err)
+	_, err = c.CreateCompany(t.Context(), acme())
+	require.ErrorIs(t, err, client.ErrConflict, "names are unique")
+
+	_, err = newClient(t, s, client.WithCredentials("tester", "wrong")).CreateCompany(t.Context(), acme())
+	require.ErrorIs(t, err, client.ErrUnauthorized)
+}
+
+func TestClient_CreateCompanyIsIdempotent(t *testing.T) {
+	s := newServer(t, nil)
+	c := newClient(t, s)
+	key := uuid.New()
+
+	first, err := c.CreateCompany(t.Context(), acme(), client.WithIdempotencyKey(key))
+	require.NoError(t, err)
+	assert.Equal(t, key, first.Id)
+
+	again, err := c.CreateCompany(t.Context(), acme(), client.WithIdempotencyKey(key))
+	require.NoError(t, err)
+	assert.Equal(t, first.Company, again.Company, "the same request with the same key returns the first company")
+
+	other := acme()
+	other.Name = "Other"
+	_, err = c.CreateCompany(t.Context(), other, client.WithIdempotencyKey(key))
+	require.ErrorIs(t, err, client.ErrPreconditionFailed, "a key is not reused for another company")
+}
+
+func TestClient_RetriesLostCreate(t *testing.T) {
+	// The first create takes effect, but its response is lost to a 502
+	var lost atomic.Bool
+	s := newServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPut && lost.CompareAndSwap(false, true) {
+				next.ServeHTTP(httptest.NewRecorder(), r)
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	c := newClient(t, s)
+
+	created, err := c.CreateCompany(t.Context(), acme())
+	require.NoError(t, err)
+	assert.True(t, lost.Load())
+
+	got, err := c.GetCompany(t.Context(), created.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "Acme", got.Name)
+
+	_, err = c.CreateCompany(t.Context(), acme())
+	require.ErrorIs(t, err, client.ErrConflict, "the retry did not create a second company")
+}
+
+func TestClient_Retries(t *testing.T) {
+	var failures atomic.Int32
+	var attempts atomic.Int32
+	s := newServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, "/api/v1/companies") {
+				attempts.Add(1)
+				if failures.Add(-1) >= 0 {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	c := newClient(t, s)
+	created, err := c.CreateCompany(t.Context(), acme())
+	require.NoError(t, err)
+
+	t.Run("transient failures", func(t *testing.T) {
+		failures.Store(2)
+		attempts.Store(0)
+		_, err := c.GetCompany(t.Context(), created.Id)
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, attempts.Load())
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		failures.Store(5)
+		attempts.Store(0)
+		_, err := c.GetCompany(t.Context(), created.Id)
+		require.ErrorIs(t, err, client.ErrUnavailable)
+		assert.EqualValues(t, fastRetries.MaxAttempts, attempts.Load())
+	})
+
+	t.Run("unsafe requests are not retried", func(t *testing.T) {
+		failures.Store(1)
+		attempts.Store(0)
+		resp, err := c.API().BatchCompaniesWithResponse(t.Context(), oapi.CompanyBatchRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode())
+		assert.EqualValues(t, 1, attempts.Load())
+	})
+}
+
+func TestClient_StopsWithContext(t *testing.T) {
+	s := newServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "10")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+	})
+	c, err := client.New(s.URL, client.WithToken("unused"),
+		client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond, MaxDelay: time.Minute}))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = c.GetCompany(ctx, uuid.New())
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second, "the wait for Retry-After is cut short")
+}
+
+func TestClient_RefreshesRejectedToken(t *testing.T) {
+	s := newServer(t, nil)
+	c := newClient(t, s)
+
+	_, err := c.CreateCompany(t.Context(), acme())
+	require.NoError(t, err)
+	require.EqualValues(t, 1, s.tokens.Load())
+
+	// Tokens signed with the old key are no longer accepted
+	s.jwt.SetSecret("rotated-secret")
+
+	other := acme()
+	other.Name = "Other"
+	_, err = c.CreateCompany(t.Context(), other)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, s.tokens.Load())
+}
+
+func TestNew(t *testing.T) {
+	_, err := client.New("http://localhost", client.WithToken("t"), client.WithCredentials("u", "p"))
+	require.Error(t, err)
+
+	_, err = client.New("http://localhost", client.WithRetryPolicy(client.RetryPolicy{}))
+	require.Error(t, err)
+
+	assert.False(t, errors.Is(&client.Error{StatusCode: http.StatusInternalServerError}, client.ErrUnavailable))
+}
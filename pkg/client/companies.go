@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+	"github.com/google/uuid"
+)
+
+// Company is a company as the server returned it, with its version.
+type Company struct {
+	oapi.Company
+	// ETag is the version, for WithIfMatch
+	ETag string
+}
+
+func newCompany(c *oapi.Company, resp *http.Response) *Company {
+	return &Company{Company: *c, ETag: resp.Header.Get("ETag")}
+}
+
+type callOptions struct {
+	idempotencyKey uuid.UUID
+	ifMatch        string
+}
+
+// CallOption tunes a single call.
+type CallOption func(*callOptions)
+
+// WithIdempotencyKey makes CreateCompany use key as the ID of the company.
+// Calling it again with the same key and request, even from another process,
+// returns the company created the first time instead of a second one.
+func WithIdempotencyKey(key uuid.UUID) CallOption {
+	return func(o *callOptions) { o.idempotencyKey = key }
+}
+
+// WithIfMatch makes ReplaceCompany fail with ErrPreconditionFailed unless the
+// company is still at version etag, e.g. Company.ETag; "*" only replaces a
+// company that exists.
+func WithIfMatch(etag string) CallOption {
+	return func(o *callOptions) { o.ifMatch = etag }
+}
+
+func newCallOptions(opts []CallOption) callOptions {
+	var o callOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func (c *Client) GetCompany(ctx context.Context, id uuid.UUID) (*Company, error) {
+	resp, err := c.api.GetCompanyWithResponse(ctx, id, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.JSON200 == nil {
+		return nil, newError(resp.HTTPResponse, resp.Body)
+	}
+	return newCompany(resp.JSON200, resp.HTTPResponse), nil
+}
+
+// CreateCompany creates a company. The ID is chosen here, or given with
+// WithIdempotencyKey, and the company is created with PUT and If-None-Match,
+// so a create that is retried after a lost response is not made twice: the
+// retry finds the company and returns it, as long as it matches req.
+func (c *Client) CreateCompany(ctx context.Context, req oapi.CreateCompanyRequest, opts ...CallOption) (*Company, error) {
+	o := newCallOptions(opts)
+	id := o.idempotencyKey
+	if id == uuid.Nil {
+		id = uuid.New()
+	}
+
+	ifNoneMatch := "*"
+	resp, err := c.api.ReplaceCompanyWithResponse(ctx, id, &oapi.ReplaceCompanyParams{IfNoneMatch: &ifNoneMatch}, req)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.JSON201 != nil:
+		return newCompany(resp.JSON201, resp.HTTPResponse), nil
+	case resp.StatusCode() != http.StatusPreconditionFailed:
+		return nil, newError(resp.HTTPResponse, resp.Body)
+	}
+
+	// The company exists: created by an earlier attempt with this key, or
+	// by another request that used it
+	existing, err := c.GetCompany(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !sameCompany(existing.Company, req) {
+		return nil, fmt.Errorf("idempotency key %s was used for another company: %w", id, newError(resp.HTTPResponse, resp.Body))
+	}
+	return existing, nil
+}
+
+// ReplaceCompany creates the company with this ID or replaces all of its
+// fields. Sending it again is safe, so it is retried.
+func (c *Client) ReplaceCompany(ctx context.Context, id uuid.UUID, req oapi.CreateCompanyRequest, opts ...CallOption) (*Company, error) {
+	o := newCallOptions(opts)
+	params := &oapi.ReplaceCompanyParams{}
+	if o.ifMatch != "" {
+		params.IfMatch = &o.ifMatch
+	}
+
+	resp, err := c.api.ReplaceCompanyWithResponse(ctx, id, params, req)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.JSON200 != nil:
+		return newCompany(resp.JSON200, resp.HTTPResponse), nil
+	case resp.JSON201 != nil:
+		return newCompany(resp.JSON201, resp.HTTPResponse), nil
+	}
+	return nil, newError(resp.HTTPResponse, resp.Body)
+}
+
+// UpdateCompany sets the fields of req that are not nil.
+func (c *Client) UpdateCompany(ctx context.Context, id uuid.UUID, req oapi.UpdateCompanyRequest) (*Company, error) {
+	// Setting fields gives the same company however often it is done, so
+	// this PATCH is retried
+	resp, err := c.api.UpdateCompanyWithResponse(retryable(ctx), id, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.JSON200 == nil {
+		return nil, newError(resp.HTTPResponse, resp.Body)
+	}
+	return newCompany(resp.JSON200, resp.HTTPResponse), nil
+}
+
+// DeleteCompany deletes a company. A delete that is retried after it took
+// effect returns ErrNotFound.
+func (c *Client) DeleteCompany(ctx context.Context, id uuid.UUID) error {
+	resp, err := c.api.DeleteCompanyWithResponse(ctx, id)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() != http.StatusNoContent {
+		return newError(resp.HTTPResponse, resp.Body)
+	}
+	return nil
+}
+
+// sameCompany reports whether c is what creating req gives.
+func sameCompany(c oapi.Company, req oapi.CreateCompanyRequest) bool {
+	return c.Name == req.Name && c.EmployeesCount == req.EmployeesCount && c.Registered == req.Registered &&
+		c.Type == req.Type && deref(c.Description) == deref(req.Description)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+)
+
+// Errors an *Error matches with errors.Is, one per oapi.ErrorCode, and
+// ErrUnavailable for a server that was still failing after the retries.
+var (
+	ErrBadRequest           = errors.New("bad request")
+	ErrUnauthorized         = errors.New("unauthorized")
+	ErrNotFound             = errors.New("not found")
+	ErrConflict             = errors.New("conflict")
+	ErrPreconditionFailed   = errors.New("precondition failed")
+	ErrNotAcceptable        = errors.New("not acceptable")
+	ErrPayloadTooLarge      = errors.New("payload too large")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrInternal             = errors.New("internal error")
+	ErrUnavailable          = errors.New("service unavailable")
+)
+
+var codeErrors = map[oapi.ErrorCode]error{
+	oapi.ErrorCodeBadRequest:           ErrBadRequest,
+	oapi.ErrorCodeUnauthorized:         ErrUnauthorized,
+	oapi.ErrorCodeNotFound:             ErrNotFound,
+	oapi.ErrorCodeConflict:             ErrConflict,
+	oapi.ErrorCodePreconditionFailed:   ErrPreconditionFailed,
+	oapi.ErrorCodeNotAcceptable:        ErrNotAcceptable,
+	oapi.ErrorCodePayloadTooLarge:      ErrPayloadTooLarge,
+	oapi.ErrorCodeUnsupportedMediaType: ErrUnsupportedMediaType,
+	oapi.ErrorCodeInternalError:        ErrInternal,
+}
+
+// Error is an error response of the API.
+type Error struct {
+	StatusCode int
+	// Code is empty when the response was not an API error, e.g. the 502 of
+	// a proxy
+	Code    oapi.ErrorCode
+	Message string
+	Details []oapi.ErrorDetail
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func (e *Error) Is(target error) bool {
+	if target == ErrUnavailable {
+		return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusBadGateway ||
+			e.StatusCode == http.StatusServiceUnavailable || e.StatusCode == http.StatusGatewayTimeout
+	}
+	return e.Code != "" && codeErrors[e.Code] == target
+}
+
+// newError reads the error of a response the caller did not expect.
+func newError(resp *http.Response, body []byte) error {
+	e := &Error{StatusCode: resp.StatusCode}
+
+	var apiErr oapi.Error
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Code != "" {
+		e.Code = apiErr.Code
+		e.Message = apiErr.Message
+		if apiErr.Details != nil {
+			e.Details = *apiErr.Details
+		}
+	}
+	return e
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dubininme/xm-assessment/pkg/gen/oapi"
+)
+
+// refreshBefore is how long before it expires a token is replaced, so that
+// it does not expire on the way to the server.
+const refreshBefore = time.Minute
+
+// tokenSource hands out the token of a client. With credentials it fetches
+// tokens itself; without, it only has the token it was given.
+type tokenSource struct {
+	api      *oapi.ClientWithResponses
+	userID   string
+	password string
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func (s *tokenSource) refreshable() bool {
+	return s.api != nil
+}
+
+// Token returns a token that is valid for a while yet, fetching one if
+// needed. Concurrent callers wait for a single fetch.
+func (s *tokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.refreshable() || s.token != "" && (s.expiresAt.IsZero() || time.Until(s.expiresAt) > refreshBefore) {
+		return s.token, nil
+	}
+
+	// Fetching a token changes nothing, so it is retried like a GET
+	resp, err := s.api.GenerateTokenWithResponse(retryable(ctx), oapi.GenerateTokenJSONRequestBody{
+		UserId:   s.userID,
+		Password: s.password,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error fetching token: %w", err)
+	}
+	if resp.JSON200 == nil {
+		return "", fmt.Errorf("error fetching token: %w", newError(resp.HTTPResponse, resp.Body))
+	}
+
+	s.token = resp.JSON200.Token
+	s.expiresAt = tokenExpiry(s.token)
+	return s.token, nil
+}
+
+// invalidate drops token if it is still the current one, so that the next
+// call to Token fetches a new one.
+func (s *tokenSource) invalidate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == token && s.refreshable() {
+		s.token = ""
+	}
+}
+
+// tokenExpiry reads the exp claim of a JWT without verifying it; the client
+// only uses it to know when to refresh. A token it cannot read is refreshed
+// when the server rejects it.
+func tokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
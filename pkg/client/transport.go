@@ -0,0 +1,148 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type retryableKey struct{}
+
+// retryable marks the request made with ctx as safe to repeat although its
+// method is not, e.g. a PATCH that only sets fields.
+func retryable(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryableKey{}, true)
+}
+
+// replayable reports whether the body of req can be sent again.
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func canRetry(req *http.Request) bool {
+	if !replayable(req) {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	marked, _ := req.Context().Value(retryableKey{}).(bool)
+	return marked
+}
+
+// doer is the oapi.HttpRequestDoer of a Client: it authenticates requests and
+// retries them.
+type doer struct {
+	client *http.Client
+	tokens *tokenSource
+	retry  RetryPolicy
+}
+
+func (d *doer) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	retry := canRetry(req)
+	refreshed := false
+	backoff := d.retry.BaseDelay
+
+	for attempt := 1; ; attempt++ {
+		token, err := d.authorize(req)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := d.client.Do(req)
+		switch {
+		case err == nil && resp.StatusCode == http.StatusUnauthorized && token != "" && d.tokens.refreshable() && !refreshed && replayable(req):
+			// The token was revoked or signed with a retired key; a new one
+			// is worth one more try, which does not count as a retry
+			d.tokens.invalidate(token)
+			refreshed = true
+			attempt--
+			drain(resp)
+			if req, err = rewind(req); err != nil {
+				return nil, err
+			}
+			continue
+		case err != nil && ctx.Err() != nil:
+			return nil, err
+		case err == nil && !retryableStatus(resp.StatusCode):
+			return resp, nil
+		}
+
+		if !retry || attempt >= d.retry.MaxAttempts {
+			return resp, err
+		}
+
+		// Full jitter spreads out the retries of clients that failed together
+		wait := time.Duration(0)
+		if backoff > 0 {
+			wait = time.Duration(rand.Int64N(int64(backoff))) // #nosec G404 -- jitter does not need crypto randomness
+		}
+		if resp != nil {
+			wait = max(wait, retryAfter(resp))
+			drain(resp)
+		}
+		if d.retry.MaxDelay > 0 {
+			wait = min(wait, d.retry.MaxDelay)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Join(err, ctx.Err())
+		case <-time.After(wait):
+		}
+
+		backoff = min(backoff*2, max(d.retry.MaxDelay, d.retry.BaseDelay))
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// authorize sets the bearer token of the client on req and returns it.
+func (d *doer) authorize(req *http.Request) (string, error) {
+	if d.tokens == nil {
+		return "", nil
+	}
+	token, err := d.tokens.Token(req.Context())
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return token, nil
+}
+
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// retryAfter reads the delay a 429 or 503 asked for, in seconds.
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+	return time.Duration(seconds) * time.Second
+}
+
+// drain reads what is left of a response that is not returned, so that its
+// connection can be reused.
+func drain(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+}
+
+// rewind returns a copy of req with its body from the start.
+func rewind(req *http.Request) (*http.Request, error) {
+	next := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		next.Body = body
+	}
+	return next, nil
+}
@@ -1,12 +1,46 @@
 // Package oapi provides primitives to interact with the openapi HTTP API.
 //
-// Code generated by github.com/oapi-codegen/oapi-codegen/v2 version v2.4.1 DO NOT EDIT.
+// Code generated by github.com/oapi-codegen/oapi-codegen/v2 version v2.5.1 DO NOT EDIT.
 package oapi
 
 import (
+	"encoding/json"
+	"time"
+
 	openapi_types "github.com/oapi-codegen/runtime/types"
 )
 
+const (
+	BearerAuthScopes = "bearerAuth.Scopes"
+)
+
+// Defines values for CompanyBatchAction.
+const (
+	CompanyBatchActionCreate CompanyBatchAction = "create"
+	CompanyBatchActionDelete CompanyBatchAction = "delete"
+	CompanyBatchActionUpdate CompanyBatchAction = "update"
+)
+
+// Defines values for CompanyBatchMode.
+const (
+	CompanyBatchModeAllOrNothing CompanyBatchMode = "all_or_nothing"
+	CompanyBatchModeBestEffort   CompanyBatchMode = "best_effort"
+)
+
+// Defines values for CompanyBatchStatus.
+const (
+	CompanyBatchStatusAborted   CompanyBatchStatus = "aborted"
+	CompanyBatchStatusFailed    CompanyBatchStatus = "failed"
+	CompanyBatchStatusSucceeded CompanyBatchStatus = "succeeded"
+)
+
+// Defines values for CompanyImportJobStatus.
+const (
+	CompanyImportJobStatusFailed    CompanyImportJobStatus = "failed"
+	CompanyImportJobStatusRunning   CompanyImportJobStatus = "running"
+	CompanyImportJobStatusSucceeded CompanyImportJobStatus = "succeeded"
+)
+
 // Defines values for CompanyType.
 const (
 	Cooperative        CompanyType = "Cooperative"
@@ -17,11 +51,69 @@ const (
 
 // Defines values for ErrorCode.
 const (
-	ErrorCodeBadRequest    ErrorCode = "bad_request"
-	ErrorCodeConflict      ErrorCode = "conflict"
-	ErrorCodeInternalError ErrorCode = "internal_error"
-	ErrorCodeNotFound      ErrorCode = "not_found"
-	ErrorCodeUnauthorized  ErrorCode = "unauthorized"
+	ErrorCodeBadRequest           ErrorCode = "bad_request"
+	ErrorCodeConflict             ErrorCode = "conflict"
+	ErrorCodeInternalError        ErrorCode = "internal_error"
+	ErrorCodeNotAcceptable        ErrorCode = "not_acceptable"
+	ErrorCodeNotFound             ErrorCode = "not_found"
+	ErrorCodePayloadTooLarge      ErrorCode = "payload_too_large"
+	ErrorCodePreconditionFailed   ErrorCode = "precondition_failed"
+	ErrorCodeUnauthorized         ErrorCode = "unauthorized"
+	ErrorCodeUnsupportedMediaType ErrorCode = "unsupported_media_type"
+)
+
+// Defines values for ErrorDetailLocation.
+const (
+	ErrorDetailLocationBody   ErrorDetailLocation = "body"
+	ErrorDetailLocationHeader ErrorDetailLocation = "header"
+	ErrorDetailLocationPath   ErrorDetailLocation = "path"
+	ErrorDetailLocationQuery  ErrorDetailLocation = "query"
+)
+
+// Defines values for HealthStatus.
+const (
+	HealthStatusOk          HealthStatus = "ok"
+	HealthStatusUnavailable HealthStatus = "unavailable"
+)
+
+// Defines values for JSONPatchOperationOp.
+const (
+	JSONPatchOperationOpAdd     JSONPatchOperationOp = "add"
+	JSONPatchOperationOpCopy    JSONPatchOperationOp = "copy"
+	JSONPatchOperationOpMove    JSONPatchOperationOp = "move"
+	JSONPatchOperationOpRemove  JSONPatchOperationOp = "remove"
+	JSONPatchOperationOpReplace JSONPatchOperationOp = "replace"
+	JSONPatchOperationOpTest    JSONPatchOperationOp = "test"
+)
+
+// Defines values for OutboxJobKind.
+const (
+	OutboxJobKindReplay   OutboxJobKind = "replay"
+	OutboxJobKindSnapshot OutboxJobKind = "snapshot"
+)
+
+// Defines values for OutboxJobStatus.
+const (
+	OutboxJobStatusFailed    OutboxJobStatus = "failed"
+	OutboxJobStatusRunning   OutboxJobStatus = "running"
+	OutboxJobStatusSucceeded OutboxJobStatus = "succeeded"
+)
+
+// Defines values for WebhookDeliveryStatus.
+const (
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed"
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "succeeded"
+)
+
+// Defines values for WebhookEventType.
+const (
+	WebhookEventTypeCompanyCreated      WebhookEventType = "CompanyCreated"
+	WebhookEventTypeCompanyDeleted      WebhookEventType = "CompanyDeleted"
+	WebhookEventTypeCompanyRegistered   WebhookEventType = "CompanyRegistered"
+	WebhookEventTypeCompanySnapshot     WebhookEventType = "CompanySnapshot"
+	WebhookEventTypeCompanyUnregistered WebhookEventType = "CompanyUnregistered"
+	WebhookEventTypeCompanyUpdated      WebhookEventType = "CompanyUpdated"
 )
 
 // Company defines model for Company.
@@ -34,6 +126,125 @@ type Company struct {
 	Type           CompanyType        `json:"type"`
 }
 
+// CompanyBatchAction defines model for CompanyBatchAction.
+type CompanyBatchAction string
+
+// CompanyBatchMode defines model for CompanyBatchMode.
+type CompanyBatchMode string
+
+// CompanyBatchOperation defines model for CompanyBatchOperation.
+type CompanyBatchOperation struct {
+	Action CompanyBatchAction    `json:"action"`
+	Create *CreateCompanyRequest `json:"create,omitempty"`
+
+	// Id Company to update or delete
+	Id     *openapi_types.UUID   `json:"id,omitempty"`
+	Update *UpdateCompanyRequest `json:"update,omitempty"`
+}
+
+// CompanyBatchRequest defines model for CompanyBatchRequest.
+type CompanyBatchRequest struct {
+	Mode       *CompanyBatchMode       `json:"mode,omitempty"`
+	Operations []CompanyBatchOperation `json:"operations"`
+}
+
+// CompanyBatchResponse defines model for CompanyBatchResponse.
+type CompanyBatchResponse struct {
+	// Failed Failed and aborted operations
+	Failed    int                  `json:"failed"`
+	Mode      CompanyBatchMode     `json:"mode"`
+	Results   []CompanyBatchResult `json:"results"`
+	Succeeded int                  `json:"succeeded"`
+}
+
+// CompanyBatchResult defines model for CompanyBatchResult.
+type CompanyBatchResult struct {
+	Action  CompanyBatchAction `json:"action"`
+	Company *Company           `json:"company,omitempty"`
+	Error   *Error             `json:"error,omitempty"`
+
+	// Id Company the operation applied to; set for successful creates
+	Id *openapi_types.UUID `json:"id,omitempty"`
+
+	// Index Position of the operation in the request
+	Index  int                `json:"index"`
+	Status CompanyBatchStatus `json:"status"`
+}
+
+// CompanyBatchStatus defines model for CompanyBatchStatus.
+type CompanyBatchStatus string
+
+// CompanyEvent defines model for CompanyEvent.
+type CompanyEvent struct {
+	// AggregateId Company ID
+	AggregateId openapi_types.UUID `json:"aggregate_id"`
+
+	// CreatedAt Unix seconds
+	CreatedAt int64  `json:"created_at"`
+	EventType string `json:"event_type"`
+
+	// Id Outbox ID; send it back as Last-Event-ID to resume
+	Id int64 `json:"id"`
+
+	// Payload Event payload as published to Kafka, see README
+	Payload json.RawMessage `json:"payload"`
+}
+
+// CompanyImportJob defines model for CompanyImportJob.
+type CompanyImportJob struct {
+	// Error Why the import stopped before the end of the upload
+	Error      *string                `json:"error,omitempty"`
+	FinishedAt *time.Time             `json:"finished_at,omitempty"`
+	Id         openapi_types.UUID     `json:"id"`
+	Report     CompanyImportReport    `json:"report"`
+	StartedAt  time.Time              `json:"started_at"`
+	Status     CompanyImportJobStatus `json:"status"`
+}
+
+// CompanyImportJobStatus defines model for CompanyImportJobStatus.
+type CompanyImportJobStatus string
+
+// CompanyImportReport defines model for CompanyImportReport.
+type CompanyImportReport struct {
+	Created int `json:"created"`
+
+	// Errors The first 1000 failed rows
+	Errors []CompanyImportRowError `json:"errors"`
+	Failed int                     `json:"failed"`
+
+	// Rows Rows read so far
+	Rows      int `json:"rows"`
+	Unchanged int `json:"unchanged"`
+	Updated   int `json:"updated"`
+}
+
+// CompanyImportRowError defines model for CompanyImportRowError.
+type CompanyImportRowError struct {
+	Error Error `json:"error"`
+
+	// Line Line of the row in the upload, counting the CSV header
+	Line int     `json:"line"`
+	Name *string `json:"name,omitempty"`
+}
+
+// CompanyList defines model for CompanyList.
+type CompanyList struct {
+	Items []Company `json:"items"`
+
+	// NextCursor Pass as `after` to get the next page; absent on the last page
+	NextCursor *openapi_types.UUID `json:"next_cursor,omitempty"`
+}
+
+// CompanyMergePatch RFC 7396 merge patch of a company
+type CompanyMergePatch struct {
+	// Description null clears the description
+	Description    *string      `json:"description"`
+	EmployeesCount *int         `json:"employees_count,omitempty"`
+	Name           *string      `json:"name,omitempty"`
+	Registered     *bool        `json:"registered,omitempty"`
+	Type           *CompanyType `json:"type,omitempty"`
+}
+
 // CompanyType defines model for CompanyType.
 type CompanyType string
 
@@ -46,15 +257,132 @@ type CreateCompanyRequest struct {
 	Type           CompanyType `json:"type"`
 }
 
+// CreateWebhookRequest defines model for CreateWebhookRequest.
+type CreateWebhookRequest struct {
+	EventTypes *[]WebhookEventType `json:"event_types,omitempty"`
+
+	// Secret Signing key; it is never returned
+	Secret string `json:"secret"`
+
+	// Url Must not point to a loopback, private or link-local address, literally or once resolved, unless WEBHOOK_DELIVERY_ALLOWED_NETWORKS lists its network
+	Url string `json:"url"`
+}
+
 // Error defines model for Error.
 type Error struct {
-	Code    ErrorCode `json:"code"`
-	Message string    `json:"message"`
+	Code ErrorCode `json:"code"`
+
+	// Details What is wrong with each part of an invalid request
+	Details *[]ErrorDetail `json:"details,omitempty"`
+	Message string         `json:"message"`
 }
 
 // ErrorCode defines model for ErrorCode.
 type ErrorCode string
 
+// ErrorDetail defines model for ErrorDetail.
+type ErrorDetail struct {
+	// Field JSON pointer into the body, or the parameter name
+	Field    *string             `json:"field,omitempty"`
+	Location ErrorDetailLocation `json:"location"`
+	Message  string              `json:"message"`
+}
+
+// ErrorDetailLocation defines model for ErrorDetail.Location.
+type ErrorDetailLocation string
+
+// HealthCheck defines model for HealthCheck.
+type HealthCheck struct {
+	Error  *string                 `json:"error,omitempty"`
+	Stats  *map[string]interface{} `json:"stats,omitempty"`
+	Status HealthStatus            `json:"status"`
+}
+
+// HealthResponse defines model for HealthResponse.
+type HealthResponse struct {
+	Checks map[string]HealthCheck `json:"checks"`
+	Status HealthStatus           `json:"status"`
+}
+
+// HealthStatus defines model for HealthStatus.
+type HealthStatus string
+
+// JSONPatch RFC 6902 JSON Patch
+type JSONPatch = []JSONPatchOperation
+
+// JSONPatchOperation defines model for JSONPatchOperation.
+type JSONPatchOperation struct {
+	// From Source of `move` and `copy`
+	From *string              `json:"from,omitempty"`
+	Op   JSONPatchOperationOp `json:"op"`
+
+	// Path JSON pointer to a member, e.g. `/description`
+	Path string `json:"path"`
+
+	// Value Required by `add`, `replace` and `test`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// JSONPatchOperationOp defines model for JSONPatchOperation.Op.
+type JSONPatchOperationOp string
+
+// OutboxJob defines model for OutboxJob.
+type OutboxJob struct {
+	Error      *string            `json:"error,omitempty"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+	Id         openapi_types.UUID `json:"id"`
+	Kind       OutboxJobKind      `json:"kind"`
+
+	// LastId Last outbox ID or company ID published
+	LastId    *string         `json:"last_id,omitempty"`
+	Published int64           `json:"published"`
+	StartedAt time.Time       `json:"started_at"`
+	Status    OutboxJobStatus `json:"status"`
+	Total     int64           `json:"total"`
+}
+
+// OutboxJobKind defines model for OutboxJob.Kind.
+type OutboxJobKind string
+
+// OutboxJobStatus defines model for OutboxJobStatus.
+type OutboxJobStatus string
+
+// OutboxReplayRequest defines model for OutboxReplayRequest.
+type OutboxReplayRequest struct {
+	AggregateId *openapi_types.UUID `json:"aggregate_id,omitempty"`
+	BatchSize   *int                `json:"batch_size,omitempty"`
+	EventType   *string             `json:"event_type,omitempty"`
+
+	// From Earliest event creation time, inclusive
+	From *time.Time `json:"from,omitempty"`
+
+	// FromId Lowest outbox ID, inclusive
+	FromId *int64 `json:"from_id,omitempty"`
+
+	// Rate Maximum messages per second; unlimited when omitted
+	Rate *float32 `json:"rate,omitempty"`
+
+	// To Latest event creation time, inclusive
+	To *time.Time `json:"to,omitempty"`
+
+	// ToId Highest outbox ID, inclusive
+	ToId *int64 `json:"to_id,omitempty"`
+
+	// Topic Publish to this topic instead of the configured one
+	Topic *string `json:"topic,omitempty"`
+}
+
+// OutboxSnapshotRequest defines model for OutboxSnapshotRequest.
+type OutboxSnapshotRequest struct {
+	BatchSize *int `json:"batch_size,omitempty"`
+
+	// Rate Maximum messages per second; unlimited when omitted
+	Rate *float32 `json:"rate,omitempty"`
+
+	// Topic Publish to this topic instead of the configured one
+	Topic *string `json:"topic,omitempty"`
+}
+
 // UpdateCompanyRequest defines model for UpdateCompanyRequest.
 type UpdateCompanyRequest struct {
 	Description    *string      `json:"description,omitempty"`
@@ -64,23 +392,159 @@ type UpdateCompanyRequest struct {
 	Type           *CompanyType `json:"type,omitempty"`
 }
 
+// UpdateWebhookRequest defines model for UpdateWebhookRequest.
+type UpdateWebhookRequest struct {
+	Active     *bool               `json:"active,omitempty"`
+	EventTypes *[]WebhookEventType `json:"event_types,omitempty"`
+	Secret     *string             `json:"secret,omitempty"`
+
+	// Url Must not point to a loopback, private or link-local address, literally or once resolved, unless WEBHOOK_DELIVERY_ALLOWED_NETWORKS lists its network
+	Url *string `json:"url,omitempty"`
+}
+
+// Webhook defines model for Webhook.
+type Webhook struct {
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// EventTypes Events delivered; empty means all
+	EventTypes []WebhookEventType `json:"event_types"`
+	Id         openapi_types.UUID `json:"id"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+	Url        string             `json:"url"`
+}
+
+// WebhookDelivery defines model for WebhookDelivery.
+type WebhookDelivery struct {
+	Attempts    int        `json:"attempts"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+
+	// Error Error of the last failed attempt
+	Error     *string `json:"error,omitempty"`
+	EventType string  `json:"event_type"`
+	Id        int64   `json:"id"`
+
+	// NextAttemptAt Set while the delivery is pending
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	OutboxId      int64      `json:"outbox_id"`
+
+	// ResponseStatus HTTP status of the last attempt, if a response was received
+	ResponseStatus *int                  `json:"response_status,omitempty"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	WebhookId      openapi_types.UUID    `json:"webhook_id"`
+}
+
+// WebhookDeliveryList defines model for WebhookDeliveryList.
+type WebhookDeliveryList struct {
+	Items []WebhookDelivery `json:"items"`
+}
+
+// WebhookDeliveryStatus defines model for WebhookDeliveryStatus.
+type WebhookDeliveryStatus string
+
+// WebhookEventType defines model for WebhookEventType.
+type WebhookEventType string
+
+// WebhookList defines model for WebhookList.
+type WebhookList struct {
+	Items []Webhook `json:"items"`
+}
+
+// IfNoneMatch defines model for IfNoneMatch.
+type IfNoneMatch = string
+
 // BadRequest defines model for BadRequest.
 type BadRequest = Error
 
 // Conflict defines model for Conflict.
 type Conflict = Error
 
+// NotAcceptable defines model for NotAcceptable.
+type NotAcceptable = Error
+
 // NotFound defines model for NotFound.
 type NotFound = Error
 
+// PayloadTooLarge defines model for PayloadTooLarge.
+type PayloadTooLarge = Error
+
+// PreconditionFailed defines model for PreconditionFailed.
+type PreconditionFailed = Error
+
 // Unauthorized defines model for Unauthorized.
 type Unauthorized = Error
 
+// UnsupportedMediaType defines model for UnsupportedMediaType.
+type UnsupportedMediaType = Error
+
 // GenerateTokenJSONBody defines parameters for GenerateToken.
 type GenerateTokenJSONBody struct {
-	UserId string `json:"user_id"`
+	Password string `json:"password"`
+	UserId   string `json:"user_id"`
+}
+
+// ListCompaniesParams defines parameters for ListCompanies.
+type ListCompaniesParams struct {
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// After Only companies with a greater ID; `next_cursor` of the previous page
+	After *openapi_types.UUID `form:"after,omitempty" json:"after,omitempty"`
+
+	// Name Only companies whose name contains this, ignoring case
+	Name       *string      `form:"name,omitempty" json:"name,omitempty"`
+	Type       *CompanyType `form:"type,omitempty" json:"type,omitempty"`
+	Registered *bool        `form:"registered,omitempty" json:"registered,omitempty"`
+
+	// IfNoneMatch ETags of cached copies, compared weakly, or `*`
+	IfNoneMatch *IfNoneMatch `json:"If-None-Match,omitempty"`
 }
 
+// StreamCompanyEventsParams defines parameters for StreamCompanyEvents.
+type StreamCompanyEventsParams struct {
+	// CompanyId Only events of these companies
+	CompanyId *[]openapi_types.UUID `form:"company_id,omitempty" json:"company_id,omitempty"`
+
+	// EventType Only events of these types
+	EventType *[]WebhookEventType `form:"event_type,omitempty" json:"event_type,omitempty"`
+
+	// LastEventId Same as Last-Event-ID, for clients that cannot set headers
+	LastEventId *int64  `form:"last_event_id,omitempty" json:"last_event_id,omitempty"`
+	LastEventID *string `json:"Last-Event-ID,omitempty"`
+}
+
+// ImportCompaniesParams defines parameters for ImportCompanies.
+type ImportCompaniesParams struct {
+	// Async Import in the background whatever the upload size
+	Async *bool `form:"async,omitempty" json:"async,omitempty"`
+}
+
+// GetCompanyParams defines parameters for GetCompany.
+type GetCompanyParams struct {
+	// IfNoneMatch ETags of cached copies, compared weakly, or `*`
+	IfNoneMatch *IfNoneMatch `json:"If-None-Match,omitempty"`
+
+	// IfModifiedSince An HTTP date; ignored when If-None-Match is sent or it is not a valid date
+	IfModifiedSince *string `json:"If-Modified-Since,omitempty"`
+}
+
+// ReplaceCompanyParams defines parameters for ReplaceCompany.
+type ReplaceCompanyParams struct {
+	IfMatch     *string `json:"If-Match,omitempty"`
+	IfNoneMatch *string `json:"If-None-Match,omitempty"`
+}
+
+// ListWebhookDeliveriesParams defines parameters for ListWebhookDeliveries.
+type ListWebhookDeliveriesParams struct {
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// ReplayOutboxJSONRequestBody defines body for ReplayOutbox for application/json ContentType.
+type ReplayOutboxJSONRequestBody = OutboxReplayRequest
+
+// SnapshotCompaniesJSONRequestBody defines body for SnapshotCompanies for application/json ContentType.
+type SnapshotCompaniesJSONRequestBody = OutboxSnapshotRequest
+
 // GenerateTokenJSONRequestBody defines body for GenerateToken for application/json ContentType.
 type GenerateTokenJSONRequestBody GenerateTokenJSONBody
 
@@ -89,3 +553,21 @@ type CreateCompanyJSONRequestBody = CreateCompanyRequest
 
 // UpdateCompanyJSONRequestBody defines body for UpdateCompany for application/json ContentType.
 type UpdateCompanyJSONRequestBody = UpdateCompanyRequest
+
+// UpdateCompanyApplicationJSONPatchPlusJSONRequestBody defines body for UpdateCompany for application/json-patch+json ContentType.
+type UpdateCompanyApplicationJSONPatchPlusJSONRequestBody = JSONPatch
+
+// UpdateCompanyApplicationMergePatchPlusJSONRequestBody defines body for UpdateCompany for application/merge-patch+json ContentType.
+type UpdateCompanyApplicationMergePatchPlusJSONRequestBody = CompanyMergePatch
+
+// ReplaceCompanyJSONRequestBody defines body for ReplaceCompany for application/json ContentType.
+type ReplaceCompanyJSONRequestBody = CreateCompanyRequest
+
+// BatchCompaniesJSONRequestBody defines body for BatchCompanies for application/json ContentType.
+type BatchCompaniesJSONRequestBody = CompanyBatchRequest
+
+// CreateWebhookJSONRequestBody defines body for CreateWebhook for application/json ContentType.
+type CreateWebhookJSONRequestBody = CreateWebhookRequest
+
+// UpdateWebhookJSONRequestBody defines body for UpdateWebhook for application/json ContentType.
+type UpdateWebhookJSONRequestBody = UpdateWebhookRequest
@@ -0,0 +1,113 @@
+// Package jobs runs work in the background and keeps its state for lookup by
+// ID, for APIs that accept a request with 202 and are polled for the result.
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dubininme/xm-assessment/pkg/logger"
+	"github.com/google/uuid"
+)
+
+type Status string
+
+const (
+	Running   Status = "running"
+	Succeeded Status = "succeeded"
+	Failed    Status = "failed"
+)
+
+// Job is a point-in-time view of a background run reporting progress of
+// type P.
+type Job[P any] struct {
+	ID         string
+	Kind       string
+	Status     Status
+	Progress   P
+	Error      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Func does the work of a job, reporting progress as it goes.
+type Func[P any] func(ctx context.Context, onProgress func(P)) (P, error)
+
+// Registry runs jobs and keeps them in memory, so a job is only known to the
+// process, and so the replica, that started it. A finished job can be looked
+// up for ttl, after which it is forgotten. Jobs stop when the context passed
+// to NewRegistry is cancelled.
+type Registry[P any] struct {
+	ctx context.Context
+	ttl time.Duration
+	now func() time.Time
+
+	mu   sync.Mutex
+	jobs map[string]*Job[P]
+}
+
+func NewRegistry[P any](ctx context.Context, ttl time.Duration) *Registry[P] {
+	return &Registry[P]{ctx: ctx, ttl: ttl, now: time.Now, jobs: make(map[string]*Job[P])}
+}
+
+// Start runs fn in a new goroutine and returns the job as it started.
+func (r *Registry[P]) Start(kind string, fn Func[P]) Job[P] {
+	job := &Job[P]{
+		ID:        uuid.NewString(),
+		Kind:      kind,
+		Status:    Running,
+		StartedAt: r.now(),
+	}
+
+	r.mu.Lock()
+	r.prune(job.StartedAt)
+	r.jobs[job.ID] = job
+	started := *job
+	r.mu.Unlock()
+
+	go func() {
+		ctx := logger.WithLogger(r.ctx, logger.FromContext(r.ctx).With("job_id", job.ID))
+
+		progress, err := fn(ctx, func(p P) {
+			r.mu.Lock()
+			job.Progress = p
+			r.mu.Unlock()
+		})
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		job.Progress = progress
+		job.FinishedAt = r.now()
+		job.Status = Succeeded
+		if err != nil {
+			job.Status = Failed
+			job.Error = err.Error()
+			logger.FromContext(ctx).Error("background job failed", "kind", kind, "error", err)
+		}
+	}()
+
+	return started
+}
+
+// Get returns the current state of the job with the given ID.
+func (r *Registry[P]) Get(id string) (Job[P], bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return Job[P]{}, false
+	}
+	return *job, true
+}
+
+// prune forgets jobs finished more than ttl ago; r.mu must be held.
+func (r *Registry[P]) prune(now time.Time) {
+	for id, job := range r.jobs {
+		if !job.FinishedAt.IsZero() && now.Sub(job.FinishedAt) > r.ttl {
+			delete(r.jobs, id)
+		}
+	}
+}
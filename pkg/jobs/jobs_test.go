@@ -0,0 +1,113 @@
+//go:build unit
+
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// progress is what the test jobs report.
+type progress struct {
+	Done, Total int
+}
+
+func waitForJob(t *testing.T, jobs *Registry[progress], id string) Job[progress] {
+	t.Helper()
+
+	var job Job[progress]
+	require.Eventually(t, func() bool {
+		job, _ = jobs.Get(id)
+		return job.Status != Running
+	}, time.Second, 5*time.Millisecond)
+	return job
+}
+
+func TestRegistry_ReportsProgressAndResult(t *testing.T) {
+	jobs := NewRegistry[progress](context.Background(), time.Hour)
+	release := make(chan struct{})
+
+	started := jobs.Start("replay", func(ctx context.Context, onProgress func(progress)) (progress, error) {
+		onProgress(progress{Done: 1, Total: 2})
+		<-release
+		return progress{Done: 2, Total: 2}, nil
+	})
+	assert.Equal(t, Running, started.Status)
+	assert.Equal(t, "replay", started.Kind)
+
+	require.Eventually(t, func() bool {
+		job, _ := jobs.Get(started.ID)
+		return job.Progress.Done == 1
+	}, time.Second, 5*time.Millisecond)
+
+	close(release)
+	job := waitForJob(t, jobs, started.ID)
+	assert.Equal(t, Succeeded, job.Status)
+	assert.Equal(t, progress{Done: 2, Total: 2}, job.Progress)
+	assert.False(t, job.FinishedAt.IsZero())
+
+	_, ok := jobs.Get("missing")
+	assert.False(t, ok)
+}
+
+func TestRegistry_Failure(t *testing.T) {
+	jobs := NewRegistry[progress](context.Background(), time.Hour)
+
+	started := jobs.Start("snapshot", func(context.Context, func(progress)) (progress, error) {
+		return progress{Done: 1, Total: 3}, errors.New("kafka down")
+	})
+
+	job := waitForJob(t, jobs, started.ID)
+	assert.Equal(t, Failed, job.Status)
+	assert.Equal(t, "kafka down", job.Error)
+	assert.Equal(t, 1, job.Progress.Done)
+}
+
+func TestRegistry_StopWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	jobs := NewRegistry[progress](ctx, time.Hour)
+
+	started := jobs.Start("replay", func(ctx context.Context, _ func(progress)) (progress, error) {
+		<-ctx.Done()
+		return progress{}, ctx.Err()
+	})
+	cancel()
+
+	job := waitForJob(t, jobs, started.ID)
+	assert.Equal(t, Failed, job.Status)
+}
+
+func TestRegistry_ForgetsFinishedJobsAfterTTL(t *testing.T) {
+	jobs := NewRegistry[progress](context.Background(), time.Hour)
+	var now atomic.Int64
+	now.Store(1700000000)
+	jobs.now = func() time.Time { return time.Unix(now.Load(), 0) }
+
+	done := func(context.Context, func(progress)) (progress, error) { return progress{}, nil }
+	release := make(chan struct{})
+	finished := jobs.Start("import", done)
+	running := jobs.Start("import", func(context.Context, func(progress)) (progress, error) {
+		<-release
+		return progress{}, nil
+	})
+	t.Cleanup(func() { close(release) })
+	waitForJob(t, jobs, finished.ID)
+
+	now.Add(int64(time.Hour / time.Second))
+	jobs.Start("import", done)
+	_, ok := jobs.Get(finished.ID)
+	assert.True(t, ok, "kept for the whole TTL")
+
+	now.Add(1)
+	jobs.Start("import", done)
+	_, ok = jobs.Get(finished.ID)
+	assert.False(t, ok, "forgotten once the TTL has passed")
+	_, ok = jobs.Get(running.ID)
+	assert.True(t, ok, "running jobs are never forgotten")
+}